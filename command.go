@@ -0,0 +1,504 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"ddns-go/config"
+	"ddns-go/dns"
+	"ddns-go/util"
+	"ddns-go/web"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// runCommand 运行子命令, 返回退出码
+func runCommand(command string) int {
+	switch command {
+	case "validate":
+		return validateConfig()
+	case "profile":
+		return profileCommand(flag.Args())
+	case "import-ddclient":
+		return importDdclient(flag.Args())
+	case "config":
+		return configCommand(flag.Args())
+	case "acme":
+		return acmeCommand(flag.Args())
+	case "healthcheck":
+		return healthcheck()
+	case "resetpassword":
+		return resetPassword(flag.Args(), os.Stdin)
+	case "status":
+		return statusCommand(flag.Args())
+	default:
+		fmt.Fprintf(os.Stderr, "不支持的命令 %s, 支持: validate, profile, import-ddclient, config, acme, healthcheck, resetpassword, status\n", command)
+		return 2
+	}
+}
+
+// 更新一次后等待通知及更新后的命令完成的最长时间
+const onceWaitTimeout = 2 * time.Minute
+
+// updateOnce 检测并更新一次, 输出每个域名的结果. 有失败或未获取到IP时返回1
+func updateOnce() int {
+	err := dns.RunManual("", "")
+	// 退出前等待通知、更新后的命令等完成, 并发送追踪数据
+	ctx, cancel := context.WithTimeout(context.Background(), onceWaitTimeout)
+	defer cancel()
+	if dns.Wait(ctx) != nil {
+		fmt.Fprintln(os.Stderr, "等待通知及更新后的命令超时")
+	}
+	util.FlushTrace(ctx)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if conf, err := config.GetConfigCache(); err == nil && dns.IsDryRun(&conf) {
+		// 试运行时不更新域名状态, 计划的修改已在日志中输出
+		return 0
+	}
+
+	domains := dns.GetStatus().Domains
+	if len(domains) == 0 {
+		fmt.Fprintln(os.Stderr, "未配置域名或未启用IPv4/IPv6")
+		return 1
+	}
+	code := 0
+	for _, ds := range domains {
+		result := ds.LastResult
+		switch result {
+		case config.UpdatedSuccess, string(config.UpdatedNothing):
+		case "":
+			result = "未获取到IP"
+			code = 1
+		default:
+			code = 1
+		}
+		fmt.Printf("%s %s %s: %s\n", ds.RecordType, ds.Domain, ds.Value, result)
+	}
+	return code
+}
+
+// profileCommand 管理配置方案
+// ddns-go profile 列出配置方案, ddns-go profile use/create/delete <名称>
+func profileCommand(args []string) int {
+	if len(args) == 0 {
+		current := util.GetProfile()
+		for _, name := range util.GetProfiles() {
+			if name == current {
+				fmt.Println("*", name)
+			} else {
+				fmt.Println(" ", name)
+			}
+		}
+		return 0
+	}
+
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "用法: ddns-go profile [use|create|delete <名称>]")
+		return 2
+	}
+	var err error
+	switch args[0] {
+	case "use":
+		err = util.SetProfile(args[1])
+	case "create":
+		err = util.CreateProfile(args[1])
+	case "delete":
+		err = util.DeleteProfile(args[1])
+	default:
+		fmt.Fprintln(os.Stderr, "用法: ddns-go profile [use|create|delete <名称>]")
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println("当前配置方案:", util.GetProfile(), util.GetConfigFilePath())
+	return 0
+}
+
+// validateConfig 校验配置文件, 有问题时返回非0, 可用于部署前的CI检查
+func validateConfig() int {
+	configFilePath := util.GetConfigFilePath()
+	byt, err := config.ReadConfigFile(configFilePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "读取配置文件失败:", err)
+		return 1
+	}
+
+	conf, err := config.ParseConfig(configFilePath, byt)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "解析配置文件失败:", err)
+		return 1
+	}
+
+	errs := dns.Validate(&conf, *checkAuth)
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "配置文件 %s 校验失败, 共%d个问题\n", configFilePath, len(errs))
+		return 1
+	}
+	fmt.Printf("配置文件 %s 校验通过\n", configFilePath)
+	return 0
+}
+
+// importDdclient 从ddclient.conf导入DNS服务商和域名, 需替换当前的配置时要加上 -overwrite
+// ddns-go import-ddclient [-overwrite] /etc/ddclient/ddclient.conf
+func importDdclient(args []string) int {
+	overwrite := len(args) > 0 && args[0] == "-overwrite"
+	if overwrite {
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "用法: ddns-go import-ddclient [-overwrite] /etc/ddclient/ddclient.conf")
+		return 2
+	}
+	byt, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "读取ddclient配置失败:", err)
+		return 1
+	}
+
+	conf, replaced, warnings, err := config.ImportDdclient(byt)
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "转换ddclient配置失败:", err)
+		return 1
+	}
+	for _, r := range replaced {
+		fmt.Fprintln(os.Stderr, "将替换:", r)
+	}
+	if len(replaced) > 0 && !overwrite {
+		fmt.Fprintln(os.Stderr, "未导入, 确认替换以上配置请加上 -overwrite")
+		return 1
+	}
+	if err = conf.SaveConfig(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+const configCommandUsage = `用法:
+  ddns-go config get [配置项]                      查看配置, 不带配置项时隐藏密钥和密码
+  ddns-go config set <配置项> <值>                 修改配置, 列表用逗号分隔。如: set dns.name cloudflare
+  ddns-go config add-domain <ipv4|ipv6> <域名>     添加域名
+  ddns-go config remove-domain <ipv4|ipv6> <域名>  删除域名
+  ddns-go config disable-domain <ipv4|ipv6> <域名> 停用域名, 不删除
+  ddns-go config enable-domain <ipv4|ipv6> <域名>  启用域名`
+
+// configCommand 不使用网页查看及修改配置
+func configCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, configCommandUsage)
+		return 2
+	}
+
+	conf, err := config.GetConfigCache()
+	if args[0] == "get" {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "读取配置文件失败:", err)
+			return 1
+		}
+		return configGet(&conf, args[1:])
+	}
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, "读取配置文件失败:", err)
+		return 1
+	}
+
+	switch {
+	case args[0] == "set" && len(args) == 3:
+		err = conf.SetValue(args[1], args[2])
+	case args[0] == "add-domain" && len(args) == 3:
+		err = conf.AddDomain(args[1], args[2])
+	case args[0] == "remove-domain" && len(args) == 3:
+		err = conf.RemoveDomain(args[1], args[2])
+	case args[0] == "disable-domain" && len(args) == 3:
+		err = conf.EnableDomain(args[1], args[2], false)
+	case args[0] == "enable-domain" && len(args) == 3:
+		err = conf.EnableDomain(args[1], args[2], true)
+	default:
+		fmt.Fprintln(os.Stderr, configCommandUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err = conf.SaveConfig(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// configGet 输出配置或指定的配置项
+func configGet(conf *config.Config, args []string) int {
+	if len(args) == 0 {
+		byt, _ := yaml.Marshal(conf.Redacted())
+		fmt.Print(string(byt))
+		return 0
+	}
+
+	value, err := conf.GetValue(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	switch v := value.(type) {
+	case string, bool, int:
+		fmt.Println(v)
+	default:
+		byt, _ := yaml.Marshal(v)
+		fmt.Print(string(byt))
+	}
+	return 0
+}
+
+const acmeCommandUsage = `用法:
+  ddns-go acme present <域名> <验证值>  添加ACME DNS-01验证的TXT记录 _acme-challenge.<域名>
+  ddns-go acme cleanup <域名> <验证值>  删除添加的TXT记录
+  不带域名及验证值时读取certbot的环境变量 CERTBOT_DOMAIN, CERTBOT_VALIDATION`
+
+// acmeCommand 供certbot/lego/acme.sh等申请证书时调用, 使用配置的DNS服务商添加或删除TXT记录
+func acmeCommand(args []string) int {
+	if len(args) == 1 {
+		args = append(args, os.Getenv("CERTBOT_DOMAIN"), os.Getenv("CERTBOT_VALIDATION"))
+	}
+	if len(args) != 3 || args[1] == "" || args[2] == "" {
+		fmt.Fprintln(os.Stderr, acmeCommandUsage)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var err error
+	switch args[0] {
+	case "present":
+		err = dns.ACMEPresent(ctx, args[1], args[2])
+	case "cleanup":
+		err = dns.ACMECleanup(ctx, args[1], args[2])
+	default:
+		fmt.Fprintln(os.Stderr, acmeCommandUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+const resetPasswordUsage = `用法:
+  ddns-go resetpassword                 清除网页的用户名和密码, 之后无需登录
+  ddns-go resetpassword <用户名>        修改用户名和密码, 密码从标准输入读取
+  ddns-go resetpassword <用户名> <密码> 修改用户名和密码`
+
+// resetPassword 忘记密码时在终端中修改或清除网页的用户名和密码, 运行中的ddns-go自动使用新的配置
+func resetPassword(args []string, stdin io.Reader) int {
+	if len(args) > 2 {
+		fmt.Fprintln(os.Stderr, resetPasswordUsage)
+		return 2
+	}
+
+	conf, err := config.GetConfigCache()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "读取配置文件失败:", err)
+		return 1
+	}
+
+	username, password := "", ""
+	if len(args) > 0 {
+		username = strings.TrimSpace(args[0])
+		if username == "" {
+			fmt.Fprintln(os.Stderr, "用户名不能为空")
+			return 2
+		}
+		if len(args) == 2 {
+			password = args[1]
+		} else {
+			// 不在命令行中输入, 避免保存在shell的历史记录中
+			fmt.Print("请输入新密码: ")
+			line, err := bufio.NewReader(stdin).ReadString('\n')
+			if err != nil && line == "" {
+				fmt.Fprintln(os.Stderr, "读取密码失败:", err)
+				return 1
+			}
+			password = strings.TrimRight(line, "\r\n")
+		}
+		if password == "" {
+			fmt.Fprintln(os.Stderr, "密码不能为空")
+			return 2
+		}
+	}
+
+	conf.Username = username
+	conf.Password = password
+	if err = conf.SaveConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, "保存配置文件失败:", err)
+		return 1
+	}
+	if username == "" {
+		fmt.Println("已清除网页的用户名和密码, 请尽快登录网页重新设置")
+		if !conf.NotAllowWanAccess {
+			fmt.Println("注意: 未禁止从公网访问, 未设置密码时任何人都可以修改配置")
+		}
+	} else {
+		fmt.Printf("已修改网页的用户名为 %s 及密码\n", username)
+	}
+	return 0
+}
+
+// healthcheck 请求本机运行中的ddns-go的 /status, 用于Docker的HEALTHCHECK
+// 网页服务可访问且最后一次检查没有域名更新失败时返回0, 否则返回1
+func healthcheck() int {
+	resp, err := localRequest("/status")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "访问ddns-go失败:", err)
+		return 1
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return 0
+	case http.StatusServiceUnavailable:
+		fmt.Fprintln(os.Stderr, "有域名更新失败")
+	default:
+		fmt.Fprintln(os.Stderr, "访问ddns-go失败:", resp.Status)
+	}
+	return 1
+}
+
+// localRequest 请求本机运行中的ddns-go, 监听地址与 -l 相同, 使用配置文件中的用户名和密码
+func localRequest(path string) (*http.Response, error) {
+	addr := util.SplitListenAddrs(*listen)[0]
+	client := &http.Client{Timeout: 10 * time.Second}
+	url := "http://127.0.0.1" + path
+	if util.IsUnixSocketAddr(addr) {
+		socketPath := strings.TrimPrefix(addr, util.UnixSocketPrefix)
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		}
+	} else {
+		// 监听所有地址时访问本机
+		tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+		host := "127.0.0.1"
+		if tcpAddr.IP != nil && !tcpAddr.IP.IsUnspecified() {
+			host = tcpAddr.IP.String()
+		}
+		url = "http://" + net.JoinHostPort(host, fmt.Sprint(tcpAddr.Port)) + path
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if conf, err := config.GetConfigCache(); err == nil && (conf.Username != "" || conf.Password != "") {
+		req.SetBasicAuth(conf.Username, conf.Password)
+	}
+	return client.Do(req)
+}
+
+// statusCommand 输出运行中的ddns-go获取到的IP及每个域名的状态, 参数为json时输出JSON
+// 所有域名最后一次检查都未失败时返回0
+func statusCommand(args []string) int {
+	if len(args) > 1 || (len(args) == 1 && args[0] != "json") {
+		fmt.Fprintln(os.Stderr, "用法: ddns-go status [json]")
+		return 2
+	}
+	resp, err := localRequest("/status")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "访问ddns-go失败:", err)
+		return 1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		fmt.Fprintln(os.Stderr, "访问ddns-go失败:", resp.Status)
+		return 1
+	}
+	byt, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "读取状态失败:", err)
+		return 1
+	}
+	var status web.StatusResponse
+	if err = json.Unmarshal(byt, &status); err != nil {
+		fmt.Fprintln(os.Stderr, "解析状态失败:", err)
+		return 1
+	}
+
+	if len(args) == 1 {
+		var out bytes.Buffer
+		json.Indent(&out, byt, "", "  ")
+		fmt.Println(out.String())
+	} else {
+		printStatus(os.Stdout, status)
+	}
+	if !status.OK {
+		return 1
+	}
+	return 0
+}
+
+// printStatus 以表格输出运行状态
+func printStatus(w io.Writer, status web.StatusResponse) {
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return util.FormatTime(t)
+	}
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+
+	fmt.Fprintf(w, "版本: %s, 已运行 %s\n", status.Version, time.Duration(status.Uptime)*time.Second)
+	fmt.Fprintf(w, "DNS服务商: %s\n", orDash(status.Provider))
+	withInfo := func(ipAddr string, info config.IPInfo) string {
+		if info.String() == "" {
+			return orDash(ipAddr)
+		}
+		return ipAddr + " (" + info.String() + ")"
+	}
+	fmt.Fprintf(w, "IPv4: %s\n", withInfo(status.Ipv4Addr, status.Ipv4Info))
+	fmt.Fprintf(w, "IPv6: %s\n", withInfo(status.Ipv6Addr, status.Ipv6Info))
+	fmt.Fprintf(w, "最后检查: %s, 下次检查: %s\n", formatTime(status.LastRun), formatTime(status.NextRun))
+	if status.PausedUntil.After(time.Now()) {
+		fmt.Fprintf(w, "DNS服务商连续失败%d次, 暂停定时更新到: %s\n", status.PausedFailures, formatTime(status.PausedUntil))
+	}
+	if status.LastError != "" {
+		fmt.Fprintf(w, "最后的错误: %s %s\n", formatTime(status.LastErrorTime), status.LastError)
+	}
+	if len(status.Domains) == 0 {
+		fmt.Fprintln(w, "还没有域名的状态")
+		return
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "类型\t域名\tIP\t结果\t最后更新\t连续失败\t错误")
+	for _, ds := range status.Domains {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", ds.RecordType, ds.Domain, orDash(ds.Value), orDash(ds.LastResult),
+			formatTime(ds.LastUpdate), ds.FailCount, orDash(ds.LastError))
+	}
+	tw.Flush()
+}
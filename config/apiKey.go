@@ -0,0 +1,101 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+)
+
+// API密钥权限
+const (
+	// APIKeyScopeRead 只读
+	APIKeyScopeRead = "read"
+	// APIKeyScopeUpdate 只读及触发更新
+	APIKeyScopeUpdate = "update"
+	// APIKeyScopeFull 全部权限
+	APIKeyScopeFull = "full"
+)
+
+// API密钥前缀, 方便识别
+const apiKeyPrefix = "ddns_"
+
+// 权限等级
+var apiKeyScopeLevels = map[string]int{
+	APIKeyScopeRead:   1,
+	APIKeyScopeUpdate: 2,
+	APIKeyScopeFull:   3,
+}
+
+// APIKey API密钥, 只保存密钥的sha256
+type APIKey struct {
+	ID        string
+	Name      string
+	KeyHash   string
+	Scope     string
+	CreatedAt time.Time
+}
+
+// IsValidAPIKeyScope 是否为支持的权限
+func IsValidAPIKeyScope(scope string) bool {
+	_, ok := apiKeyScopeLevels[scope]
+	return ok
+}
+
+// Allow 是否拥有scope权限
+func (key APIKey) Allow(scope string) bool {
+	return apiKeyScopeLevels[key.Scope] >= apiKeyScopeLevels[scope]
+}
+
+// NewAPIKey 生成API密钥, 返回的key只在创建时可见
+func NewAPIKey(name string, scope string) (apiKey APIKey, key string, err error) {
+	byt := make([]byte, 20)
+	if _, err = rand.Read(byt); err != nil {
+		return
+	}
+	key = apiKeyPrefix + hex.EncodeToString(byt)
+
+	id := make([]byte, 4)
+	if _, err = rand.Read(id); err != nil {
+		return
+	}
+
+	apiKey = APIKey{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		KeyHash:   hashAPIKey(key),
+		Scope:     scope,
+		CreatedAt: time.Now(),
+	}
+	return
+}
+
+// FindAPIKey 查找API密钥
+func (conf *Config) FindAPIKey(key string) (apiKey APIKey, ok bool) {
+	hash := hashAPIKey(key)
+	for _, k := range conf.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(k.KeyHash), []byte(hash)) == 1 {
+			return k, true
+		}
+	}
+	return
+}
+
+// RemoveAPIKey 删除API密钥
+func (conf *Config) RemoveAPIKey(id string) bool {
+	for i, k := range conf.APIKeys {
+		if k.ID == id {
+			// 不修改原来的数组, 缓存中的配置及修改前的配置保持不变
+			keys := append([]APIKey{}, conf.APIKeys[:i]...)
+			conf.APIKeys = append(keys, conf.APIKeys[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
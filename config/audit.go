@@ -0,0 +1,140 @@
+package config
+
+import (
+	"ddns-go/util"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 审计日志的类型
+const (
+	AuditTypeConfig = "config"
+	AuditTypeLogin  = "login"
+)
+
+// 审计日志中隐藏的配置项, 只记录是否修改
+var auditSecretFields = map[string]bool{
+	"dns.id":                     true,
+	"dns.secret":                 true,
+	"dns.webhookurl":             true,
+	"dns.webhookrequestbody":     true,
+	"user.password":              true,
+	"webhook.webhookurl":         true,
+	"webhook.webhookrequestbody": true,
+}
+
+// AuditChange 修改的配置项
+type AuditChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// AuditRecord 审计日志, 记录配置的修改及登录
+type AuditRecord struct {
+	Time time.Time
+	// config/login
+	Type string
+	// 登录帐号或API密钥名称
+	User string
+	IP   string
+	// 操作, 如 /save
+	Action  string
+	Success bool
+	Changes []AuditChange `json:",omitempty"`
+}
+
+// AddAuditRecord 记录审计日志
+func AddAuditRecord(record AuditRecord) {
+	record.Time = time.Now()
+	if err := historyStore().Add(historyAudit, util.StoreRecord{Time: record.Time, Value: record}); err != nil {
+		log.Println("保存审计日志失败", err)
+	}
+}
+
+// GetAuditRecords 获得最近limit条审计日志, 最新的在前
+func GetAuditRecords(limit int) (records []AuditRecord) {
+	listHistory(historyAudit, func(value []byte) bool {
+		var r AuditRecord
+		if json.Unmarshal(value, &r) == nil {
+			records = append(records, r)
+		}
+		return len(records) < limit
+	})
+	return
+}
+
+// DiffConfig 比较修改前后的配置, 配置项为YAML中的路径, 如 dns.name. 密钥等只显示为******
+func DiffConfig(old Config, new Config) (changes []AuditChange) {
+	oldValues, newValues := map[string]string{}, map[string]string{}
+	if m, err := old.toMap(); err == nil {
+		flattenConfig("", m, oldValues)
+	}
+	if m, err := new.toMap(); err == nil {
+		flattenConfig("", m, newValues)
+	}
+
+	fields := []string{}
+	for field := range oldValues {
+		fields = append(fields, field)
+	}
+	for field := range newValues {
+		if _, ok := oldValues[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		oldValue, newValue := oldValues[field], newValues[field]
+		if oldValue == newValue || field == "version" {
+			continue
+		}
+		if auditSecretFields[field] {
+			oldValue, newValue = redactAuditValue(oldValue), redactAuditValue(newValue)
+		}
+		if field == "proxy" || field == "dns.proxy" || field == "mqtturl" {
+			oldValue, newValue = redactProxy(oldValue), redactProxy(newValue)
+		}
+		changes = append(changes, AuditChange{Field: field, Old: oldValue, New: newValue})
+	}
+	return
+}
+
+// flattenConfig 展开为 路径: 值, 列表用逗号分隔, API密钥只记录名称
+func flattenConfig(prefix string, m map[interface{}]interface{}, values map[string]string) {
+	for k, v := range m {
+		key := prefix + fmt.Sprint(k)
+		switch value := v.(type) {
+		case map[interface{}]interface{}:
+			flattenConfig(key+".", value, values)
+		case []interface{}:
+			items := []string{}
+			for _, item := range value {
+				if apiKey, ok := item.(map[interface{}]interface{}); ok {
+					item = apiKey["name"]
+				}
+				if s := strings.TrimSpace(fmt.Sprint(item)); s != "" {
+					items = append(items, s)
+				}
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+}
+
+// redactAuditValue 有值时隐藏
+func redactAuditValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "******"
+}
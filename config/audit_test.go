@@ -0,0 +1,38 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestDiffConfig 比较配置, 隐藏密钥
+func TestDiffConfig(t *testing.T) {
+	var old Config
+	old.DNS.Name = "alidns"
+	old.DNS.Secret = "secret1"
+	old.Ipv4.Domains = []string{"a.example.com"}
+	old.APIKeys = []APIKey{{ID: "1", Name: "home", KeyHash: "hash"}}
+
+	new := old
+	new.DNS.Name = "cloudflare"
+	new.DNS.Secret = "secret2"
+	new.Ipv4.Domains = []string{"a.example.com", "b.example.com"}
+	new.Password = "password"
+	new.APIKeys = nil
+
+	changes := DiffConfig(old, new)
+	expected := []AuditChange{
+		{Field: "apikeys", Old: "home", New: ""},
+		{Field: "dns.name", Old: "alidns", New: "cloudflare"},
+		{Field: "dns.secret", Old: "******", New: "******"},
+		{Field: "ipv4.domains", Old: "a.example.com", New: "a.example.com,b.example.com"},
+		{Field: "user.password", Old: "", New: "******"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("比较配置失败: %v", changes)
+	}
+
+	if changes = DiffConfig(old, old); len(changes) != 0 {
+		t.Errorf("配置未修改时不应有变化: %v", changes)
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"ddns-go/util"
+	"fmt"
+	"log"
+)
+
+// 网卡或路由器推送的IPv4为运营商级NAT时的处理
+const (
+	// CGNATWarn 仍然更新, 在日志中提示. 默认
+	CGNATWarn = ""
+	// CGNATSkip 跳过更新
+	CGNATSkip = "skip"
+	// CGNATFallback 改为使用通过接口获取的公网IP
+	CGNATFallback = "fallback"
+	// CGNATIgnore 不检查, 仍然更新
+	CGNATIgnore = "ignore"
+)
+
+// checkCGNAT 通过网卡获取或由路由器推送的IPv4为运营商级NAT时, 按配置提示、跳过更新或改用通过接口获取的IP
+func (conf *Config) checkCGNAT(ctx context.Context, ipAddr string) string {
+	if ipAddr == "" || conf.Ipv4.CGNAT == CGNATIgnore {
+		return ipAddr
+	}
+
+	reason, publicIP := "", ""
+	if util.IsCGNAT(ipAddr) {
+		reason = "运营商级NAT(100.64.0.0/10)的地址"
+	} else if conf.Ipv4.CGNATCheckPublic {
+		if publicIP = conf.getPublicIpv4(ctx); publicIP != "" && publicIP != ipAddr {
+			reason = fmt.Sprintf("与通过接口获取的公网IP %s 不同, 可能为运营商级NAT的地址", publicIP)
+		}
+	}
+	if reason == "" {
+		return ipAddr
+	}
+
+	if conf.Ipv4.CGNAT == CGNATFallback {
+		if publicIP == "" {
+			publicIP = conf.getPublicIpv4(ctx)
+		}
+		if publicIP != "" && !util.IsCGNAT(publicIP) {
+			log.Printf("IPv4 %s 为%s, 改为使用通过接口获取的 %s\n", ipAddr, reason, publicIP)
+			return publicIP
+		}
+		log.Printf("IPv4 %s 为%s, 通过接口也未能获取公网IPv4, 将不会更新\n", ipAddr, reason)
+		return ""
+	}
+	if conf.Ipv4.CGNAT == CGNATSkip {
+		log.Printf("IPv4 %s 为%s, 外网无法访问, 将不会更新. 可在IPv4中修改运营商级NAT的处理方式\n", ipAddr, reason)
+		return ""
+	}
+	log.Printf("IPv4 %s 为%s, 外网可能无法访问, 仍然更新. 可在IPv4中改为跳过更新或通过接口获取\n", ipAddr, reason)
+	return ipAddr
+}
+
+// getPublicIpv4 通过填写的接口获取公网IPv4, 未填写时自动选择接口
+func (conf *Config) getPublicIpv4(ctx context.Context) string {
+	if SplitIPURLs(conf.Ipv4.URL) != nil {
+		return getURLIP(ctx, "IPv4", conf.Ipv4.URL, Ipv4Reg, "")
+	}
+	return getAutoIP(ctx, "IPv4", Ipv4Reg, "")
+}
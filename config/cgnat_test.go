@@ -0,0 +1,44 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCheckCGNAT 运营商级NAT的地址按配置跳过或改用接口获取的IP
+func TestCheckCGNAT(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1.2.3.4"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		cgnat       string
+		checkPublic bool
+		ip          string
+		want        string
+	}{
+		{CGNATWarn, false, "100.64.1.2", "100.64.1.2"},
+		{CGNATSkip, false, "100.64.1.2", ""},
+		{CGNATSkip, false, "1.2.3.4", "1.2.3.4"},
+		{CGNATFallback, false, "100.100.1.2", "1.2.3.4"},
+		{CGNATIgnore, false, "100.64.1.2", "100.64.1.2"},
+		// 与公网IP不同
+		{CGNATSkip, false, "5.6.7.8", "5.6.7.8"},
+		{CGNATSkip, true, "5.6.7.8", ""},
+		{CGNATWarn, true, "5.6.7.8", "5.6.7.8"},
+		{CGNATSkip, true, "1.2.3.4", "1.2.3.4"},
+		{CGNATFallback, true, "5.6.7.8", "1.2.3.4"},
+	}
+	for _, tt := range tests {
+		conf := &Config{}
+		conf.Ipv4.URL = server.URL
+		conf.Ipv4.CGNAT = tt.cgnat
+		conf.Ipv4.CGNATCheckPublic = tt.checkPublic
+		if got := conf.checkCGNAT(context.Background(), tt.ip); got != tt.want {
+			t.Errorf("%q %v %s 返回 %q, 应为 %q", tt.cgnat, tt.checkPublic, tt.ip, got, tt.want)
+		}
+	}
+}
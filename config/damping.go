@@ -0,0 +1,153 @@
+package config
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// 检测IP来回变化的时间范围
+	flapWindow = 30 * time.Minute
+	// 时间范围内检测到的IP变化达到次数时, 认为在来回变化
+	flapChanges = 3
+)
+
+// ipChange 检测到的IP变化
+type ipChange struct {
+	ip   string
+	time time.Time
+}
+
+// damper 更新间隔及来回变化的状态, 使用相同获取IP方式的记录共用
+type damper struct {
+	// 最后一次用于更新的IP及时间
+	ip        string
+	changedAt time.Time
+	// 最后一次检测到的IP及时间范围内的变化
+	detected string
+	changes  []ipChange
+	flapping bool
+	// 最后一次暂缓更新的IP, 开始暂缓时发送通知, 发送后清除notify
+	held   string
+	notify bool
+}
+
+var dampers = map[string]*damper{}
+var dampersLock sync.Mutex
+
+type dampingReadOnlyKey struct{}
+
+// WithoutDampingUpdate 试运行及重试时使用, 只按之前的状态判断, 不记录检测到的IP
+func WithoutDampingUpdate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dampingReadOnlyKey{}, true)
+}
+
+func dampingReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(dampingReadOnlyKey{}).(bool)
+	return readOnly
+}
+
+// dampKey 共用获取IP方式的记录为IPv4/IPv6, 单独设置了获取IP方式的记录加上获取方式
+func dampKey(ipType string, source DomainSource) string {
+	if source.IsZero() {
+		return ipType
+	}
+	getType := source.getType()
+	return ipType + " " + getType + " " + ipSource(getType, source.URL, source.NetInterface+source.WireGuard)
+}
+
+// dampIP 根据最小更新间隔及来回变化检测, 获得本次用于更新的IP. 不满足时保持上次的IP.
+// damped为开始暂缓更新且还未发送通知
+func (conf *Config) dampIP(ctx context.Context, key string, ip string, now time.Time) (result string, damped bool) {
+	dampersLock.Lock()
+	defer dampersLock.Unlock()
+
+	readOnly := dampingReadOnly(ctx)
+	d, ok := dampers[key]
+	if !ok {
+		if !readOnly {
+			dampers[key] = &damper{ip: ip, changedAt: now, detected: ip}
+		}
+		return ip, false
+	}
+	if readOnly {
+		c := *d
+		c.changes = append([]ipChange(nil), d.changes...)
+		d = &c
+	}
+	defer func() {
+		damped = d.notify
+	}()
+
+	if ip != d.detected {
+		d.changes = append(d.changes, ipChange{ip: ip, time: now})
+		d.detected = ip
+	}
+	for len(d.changes) > 0 && now.Sub(d.changes[0].time) > flapWindow {
+		d.changes = d.changes[1:]
+	}
+
+	if ip == d.ip {
+		if !d.flapping {
+			d.held = ""
+		}
+		return ip, false
+	}
+
+	if conf.FlapDetection && d.isFlapping(ip) {
+		if !d.flapping {
+			log.Printf("%s %d分钟内变化了%d次, 在多个地址间来回变化, 保持 %s, 请检查获取IP的方式\n", key, int(flapWindow.Minutes()), len(d.changes), d.ip)
+		}
+		d.flapping = true
+		d.hold(ip)
+		return d.ip, false
+	}
+	if d.flapping {
+		log.Printf("%s 已稳定, 更新为 %s\n", key, ip)
+		d.flapping = false
+	}
+
+	interval := time.Duration(conf.MinUpdateInterval) * time.Second
+	if interval > 0 && now.Sub(d.changedAt) < interval {
+		log.Printf("%s 距上次更新不足%d秒, 暂不更新为 %s\n", key, conf.MinUpdateInterval, ip)
+		d.hold(ip)
+		return d.ip, false
+	}
+
+	d.ip = ip
+	d.changedAt = now
+	d.held = ""
+	return ip, false
+}
+
+// hold 暂缓更新为ip, 来回变化时只在开始时通知一次
+func (d *damper) hold(ip string) {
+	if d.held == "" || (!d.flapping && d.held != ip) {
+		d.notify = true
+	}
+	d.held = ip
+}
+
+// clearDamped 已发送暂缓更新的通知
+func clearDamped() {
+	dampersLock.Lock()
+	defer dampersLock.Unlock()
+	for _, d := range dampers {
+		d.notify = false
+	}
+}
+
+// isFlapping 时间范围内变化的次数达到且IP在之前出现过
+func (d *damper) isFlapping(ip string) bool {
+	if len(d.changes) < flapChanges {
+		return false
+	}
+	for _, change := range d.changes[:len(d.changes)-1] {
+		if change.ip == ip {
+			return true
+		}
+	}
+	return false
+}
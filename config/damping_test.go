@@ -0,0 +1,69 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestDampIPInterval 测试最小更新间隔
+func TestDampIPInterval(t *testing.T) {
+	conf := &Config{MinUpdateInterval: 600}
+	ctx := context.Background()
+	now := time.Now()
+	if ip, _ := conf.dampIP(ctx, "test-interval", "1.1.1.1", now); ip != "1.1.1.1" {
+		t.Fatal(ip)
+	}
+	ip, damped := conf.dampIP(ctx, "test-interval", "2.2.2.2", now.Add(time.Minute))
+	if ip != "1.1.1.1" {
+		t.Errorf("距上次更新不足最小间隔时应保持之前的IP: %s", ip)
+	}
+	if !damped {
+		t.Error("开始暂缓更新时应发送通知")
+	}
+	clearDamped()
+	if _, damped := conf.dampIP(ctx, "test-interval", "2.2.2.2", now.Add(2*time.Minute)); damped {
+		t.Error("同一IP只应通知一次")
+	}
+	if ip, _ := conf.dampIP(ctx, "test-interval", "2.2.2.2", now.Add(11*time.Minute)); ip != "2.2.2.2" {
+		t.Errorf("超过最小间隔后应更新: %s", ip)
+	}
+}
+
+// TestDampIPFlapping 测试IP来回变化
+func TestDampIPFlapping(t *testing.T) {
+	conf := &Config{FlapDetection: true}
+	ctx := context.Background()
+	now := time.Now()
+	expected := []string{"1.1.1.1", "2.2.2.2", "1.1.1.1", "1.1.1.1", "1.1.1.1"}
+	for i, ip := range []string{"1.1.1.1", "2.2.2.2", "1.1.1.1", "2.2.2.2", "2.2.2.2"} {
+		if result, _ := conf.dampIP(ctx, "test-flapping", ip, now.Add(time.Duration(i)*time.Minute)); result != expected[i] {
+			t.Fatalf("第%d次应为 %s, 实际为 %s", i+1, expected[i], result)
+		}
+	}
+
+	// 稳定后更新
+	if ip, _ := conf.dampIP(ctx, "test-flapping", "2.2.2.2", now.Add(time.Hour)); ip != "2.2.2.2" {
+		t.Errorf("稳定后应更新: %s", ip)
+	}
+}
+
+// TestDampIPReadOnly 测试试运行及重试时不记录检测到的IP
+func TestDampIPReadOnly(t *testing.T) {
+	conf := &Config{MinUpdateInterval: 600}
+	readOnly := WithoutDampingUpdate(context.Background())
+	now := time.Now()
+	if ip, _ := conf.dampIP(readOnly, "test-readonly", "1.1.1.1", now); ip != "1.1.1.1" {
+		t.Fatal(ip)
+	}
+	conf.dampIP(context.Background(), "test-readonly", "2.2.2.2", now)
+	if ip, _ := conf.dampIP(readOnly, "test-readonly", "3.3.3.3", now.Add(time.Minute)); ip != "2.2.2.2" {
+		t.Errorf("不足最小间隔时应保持之前的IP: %s", ip)
+	}
+	if ip, _ := conf.dampIP(context.Background(), "test-readonly", "2.2.2.2", now.Add(2*time.Minute)); ip != "2.2.2.2" {
+		t.Errorf("只读时检测到的IP不应记录: %s", ip)
+	}
+	if d := dampers["test-readonly"]; d.held != "" || d.notify || len(d.changes) != 0 {
+		t.Errorf("只读时不应修改状态: %+v", d)
+	}
+}
@@ -0,0 +1,255 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ddclient的 key=value, 值可以用引号包含
+var ddclientParamReg = regexp.MustCompile(`([A-Za-z][\w-]*)\s*=\s*('[^']*'|"[^"]*"|[^\s,]*)`)
+
+// ddclient中use=web时, web的常用简称
+var ddclientWebs = map[string]string{
+	"dyndns":     "http://checkip.dyndns.org/",
+	"ipify-ipv4": "https://api.ipify.org/",
+	"ipify-ipv6": "https://api6.ipify.org/",
+}
+
+// IsDdclientFile 是否为ddclient的配置文件, 如 ddclient.conf
+func IsDdclientFile(fileName string) bool {
+	return strings.EqualFold(filepath.Ext(fileName), ".conf")
+}
+
+// ParseDdclient 将ddclient.conf转换为配置, 只支持一个DNS服务商, 不能转换的内容在warnings中返回
+// 支持的协议: cloudflare, dyndns2(转换为Callback)
+func ParseDdclient(byt []byte) (conf Config, warnings []string, err error) {
+	conf.Ipv4.GetType = "url"
+	conf.Ipv4.URL = "https://myip.ipip.net"
+	conf.Ipv6.GetType = "url"
+	conf.Ipv6.URL = "https://api-ipv6.ip.sb/ip"
+	conf.NotAllowWanAccess = true
+
+	globals := map[string]string{}
+	for _, line := range ddclientLines(byt) {
+		params := map[string]string{}
+		for k, v := range globals {
+			params[k] = v
+		}
+		for _, match := range ddclientParamReg.FindAllStringSubmatch(line, -1) {
+			params[strings.ToLower(match[1])] = strings.Trim(match[2], `'"`)
+		}
+
+		hosts := strings.FieldsFunc(ddclientParamReg.ReplaceAllString(line, ""), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+
+		// 没有域名的为全局设置, 对之后的域名生效
+		if len(hosts) == 0 {
+			globals = params
+			continue
+		}
+
+		dnsConf, ok, warning := ddclientDNS(params)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("%s, 已忽略域名 %s", warning, strings.Join(hosts, ",")))
+			continue
+		}
+		if conf.DNS.Name != "" && conf.DNS != dnsConf {
+			warnings = append(warnings, fmt.Sprintf("只支持一个DNS服务商, 已忽略域名 %s", strings.Join(hosts, ",")))
+			continue
+		}
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+		conf.DNS = dnsConf
+
+		ddclientIP(&conf, params)
+		if params["usev6"] != "" {
+			conf.Ipv6.Enable = true
+			conf.Ipv6.Domains = append(conf.Ipv6.Domains, hosts...)
+		}
+		if params["usev6"] == "" || params["use"] != "" {
+			conf.Ipv4.Enable = true
+			conf.Ipv4.Domains = append(conf.Ipv4.Domains, hosts...)
+		}
+		if params["ttl"] != "" {
+			conf.TTL = params["ttl"]
+		}
+	}
+
+	if conf.DNS.Name == "" {
+		return conf, warnings, fmt.Errorf("未找到支持的DNS服务商及域名")
+	}
+	if daemon := globals["daemon"]; daemon != "" {
+		warnings = append(warnings, fmt.Sprintf("ddclient的同步间隔为%s, 请使用启动参数 -f 设置", daemon))
+	}
+	return
+}
+
+// ddclientLines 去掉注释, 合并以\结尾的行
+func ddclientLines(byt []byte) (lines []string) {
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(byt))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i == 0 || (i > 0 && (line[i-1] == ' ' || line[i-1] == '\t')) {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if strings.HasSuffix(line, "\\") {
+			current += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		current += line
+		if strings.TrimSpace(current) != "" {
+			lines = append(lines, current)
+		}
+		current = ""
+	}
+	if strings.TrimSpace(current) != "" {
+		lines = append(lines, current)
+	}
+	return
+}
+
+// ddclientDNS 转换ddclient的协议
+func ddclientDNS(params map[string]string) (dnsConf DNSConfig, ok bool, warning string) {
+	protocol := params["protocol"]
+	switch protocol {
+	case "cloudflare":
+		dnsConf.Name = "cloudflare"
+		dnsConf.Secret = params["password"]
+		if login := params["login"]; login != "" && login != "token" {
+			warning = "Cloudflare只支持API令牌, 请在网页中重新填写Token"
+		}
+		return dnsConf, true, warning
+	case "dyndns2", "":
+		server := params["server"]
+		if server == "" {
+			server = "members.dyndns.org"
+		}
+		scheme := "https"
+		if params["ssl"] == "no" {
+			scheme = "http"
+		}
+		u := url.URL{
+			Scheme:   scheme,
+			User:     url.UserPassword(params["login"], params["password"]),
+			Host:     server,
+			Path:     "/nic/update",
+			RawQuery: "hostname=#{domain}&myip=#{ip}",
+		}
+		dnsConf.Name = "callback"
+		dnsConf.ID = u.String()
+		return dnsConf, true, ""
+	}
+	return dnsConf, false, fmt.Sprintf("不支持ddclient的协议 %s", protocol)
+}
+
+// ddclientIP 转换ddclient获取IP的方式
+func ddclientIP(conf *Config, params map[string]string) {
+	switch params["use"] {
+	case "if":
+		conf.Ipv4.GetType = "netInterface"
+		conf.Ipv4.NetInterface = params["if"]
+	case "web":
+		if web := params["web"]; web != "" {
+			conf.Ipv4.URL = ddclientWebURL(web)
+		}
+	}
+	switch params["usev6"] {
+	case "if", "ifv6":
+		conf.Ipv6.GetType = "netInterface"
+		conf.Ipv6.NetInterface = params["ifv6"]
+		if conf.Ipv6.NetInterface == "" {
+			conf.Ipv6.NetInterface = params["if"]
+		}
+	case "webv6":
+		if web := params["webv6"]; web != "" {
+			conf.Ipv6.URL = ddclientWebURL(web)
+		}
+	}
+}
+
+// ddclientWebURL 转换web的简称或不带协议的地址
+func ddclientWebURL(web string) string {
+	if u, ok := ddclientWebs[web]; ok {
+		return u
+	}
+	if strings.HasPrefix(web, "http://") || strings.HasPrefix(web, "https://") {
+		return web
+	}
+	return "https://" + web
+}
+
+// ImportDdclient 转换ddclient.conf并合并到当前配置, 域名添加到当前的域名中, 保留登录用户、Webhook等其它配置
+// DNS服务商、获取IP的方式或TTL与当前的不同时需替换, replaced为将被替换的内容, 调用方确认后才能保存
+func ImportDdclient(byt []byte) (conf Config, replaced []string, warnings []string, err error) {
+	ddclientConf, warnings, err := ParseDdclient(byt)
+	if err != nil {
+		return
+	}
+
+	conf, err = GetConfigCache()
+	if err != nil {
+		// 没有配置文件
+		return ddclientConf, nil, warnings, nil
+	}
+
+	if conf.DNS.Name != "" && conf.DNS.Name != ddclientConf.DNS.Name {
+		replaced = append(replaced, fmt.Sprintf("DNS服务商 %s 替换为 %s, 并删除当前的域名", conf.DNS.Name, ddclientConf.DNS.Name))
+		conf.Ipv4.Domains, conf.Ipv6.Domains = nil, nil
+	} else if conf.DNS.ID != ddclientConf.DNS.ID || conf.DNS.Secret != ddclientConf.DNS.Secret {
+		replaced = append(replaced, fmt.Sprintf("DNS服务商 %s 的ID及Secret", conf.DNS.Name))
+	}
+	conf.DNS.Name, conf.DNS.ID, conf.DNS.Secret = ddclientConf.DNS.Name, ddclientConf.DNS.ID, ddclientConf.DNS.Secret
+
+	if v4 := ddclientConf.Ipv4; v4.Enable {
+		if conf.Ipv4.Enable && (conf.Ipv4.GetType != v4.GetType || conf.Ipv4.URL != v4.URL || conf.Ipv4.NetInterface != v4.NetInterface) {
+			replaced = append(replaced, fmt.Sprintf("IPv4获取IP的方式 %s 替换为 %s",
+				ipSource(conf.Ipv4.GetType, conf.Ipv4.URL, conf.Ipv4.NetInterface), ipSource(v4.GetType, v4.URL, v4.NetInterface)))
+		}
+		conf.Ipv4.Enable = true
+		conf.Ipv4.GetType, conf.Ipv4.URL, conf.Ipv4.NetInterface = v4.GetType, v4.URL, v4.NetInterface
+		conf.Ipv4.Domains = mergeDomains(conf.Ipv4.Domains, v4.Domains)
+	}
+	if v6 := ddclientConf.Ipv6; v6.Enable {
+		if conf.Ipv6.Enable && (conf.Ipv6.GetType != v6.GetType || conf.Ipv6.URL != v6.URL || conf.Ipv6.NetInterface != v6.NetInterface) {
+			replaced = append(replaced, fmt.Sprintf("IPv6获取IP的方式 %s 替换为 %s",
+				ipSource(conf.Ipv6.GetType, conf.Ipv6.URL, conf.Ipv6.NetInterface), ipSource(v6.GetType, v6.URL, v6.NetInterface)))
+		}
+		conf.Ipv6.Enable = true
+		conf.Ipv6.GetType, conf.Ipv6.URL, conf.Ipv6.NetInterface = v6.GetType, v6.URL, v6.NetInterface
+		conf.Ipv6.Domains = mergeDomains(conf.Ipv6.Domains, v6.Domains)
+	}
+
+	if ddclientConf.TTL != "" {
+		if conf.TTL != "" && conf.TTL != ddclientConf.TTL {
+			replaced = append(replaced, fmt.Sprintf("TTL %s 替换为 %s", conf.TTL, ddclientConf.TTL))
+		}
+		conf.TTL = ddclientConf.TTL
+	}
+	return
+}
+
+// mergeDomains 添加当前没有的域名
+func mergeDomains(domains []string, added []string) []string {
+	for _, domain := range added {
+		exists := false
+		for _, d := range domains {
+			if d == domain {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"ddns-go/util"
+)
+
+// SplitIPURLs 获取IP的接口, 多个以逗号分隔
+func SplitIPURLs(s string) (urls []string) {
+	for _, u := range strings.Split(s, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return
+}
+
+// raceIP 同时从多个来源获取IP, 返回最先获取到的, 之后取消其它请求. 全部失败时返回空
+func raceIP(ctx context.Context, sources []string, fetch func(ctx context.Context, source string) string) string {
+	if len(sources) == 1 {
+		return fetch(ctx, sources[0])
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan string, len(sources))
+	for _, source := range sources {
+		go func(source string) {
+			defer func() {
+				if err := recover(); err != nil {
+					log.Printf("从 %s 获取IP时发生异常: %v\n", source, err)
+					results <- ""
+				}
+			}()
+			results <- fetch(ctx, source)
+		}(source)
+	}
+	for range sources {
+		if result := <-results; result != "" {
+			return result
+		}
+	}
+	return ""
+}
+
+// getURLIP 同时请求填写的接口, 使用最先返回IP的
+func getURLIP(ctx context.Context, ipType string, urls string, reg string, expression string) string {
+	comp := regexp.MustCompile(reg)
+	return raceIP(ctx, SplitIPURLs(urls), func(ctx context.Context, url string) string {
+		return fetchURLIP(ctx, ipType, url, comp, expression)
+	})
+}
+
+// fetchURLIP 从接口获取IP. 因其它接口已返回而取消时不输出日志
+func fetchURLIP(ctx context.Context, ipType string, url string, comp *regexp.Regexp, expression string) string {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		log.Printf("获取%s的URL不正确: %s\n", ipType, url)
+		return ""
+	}
+	client := util.HTTPClient()
+	resp, err := client.Do(req)
+	if ctx.Err() != nil {
+		return ""
+	}
+	if err != nil {
+		if ipType == "IPv6" {
+			log.Println(fmt.Sprintf("连接失败! <a target='blank' href='%s'>点击查看接口能否返回IPv6地址</a>, 官方说明:<a target='blank' href='%s'>点击访问</a> ", url, "https://github.com/jeessy2/ddns-go#使用ipv6"))
+		} else {
+			log.Println(fmt.Sprintf("连接失败! <a target='blank' href='%s'>点击查看接口能否返回IPv4地址</a>,", url))
+		}
+		return ""
+	}
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if ctx.Err() != nil {
+		return ""
+	}
+	if err != nil {
+		log.Printf("读取%s结果失败! 查询URL: %s\n", ipType, url)
+		return ""
+	}
+	return selectIP(expression, uniqueStrings(comp.FindAllString(string(body), -1)))
+}
+
+// detectIPs 同时获取IPv4及IPv6, 只获取enable为true的
+func detectIPs(ctx context.Context, ipv4Enable bool, ipv6Enable bool, getIpv4 func(ctx context.Context) string, getIpv6 func(ctx context.Context) string) (ipv4Addr string, ipv6Addr string) {
+	var wg sync.WaitGroup
+	detect := func(result *string, get func(ctx context.Context) string) {
+		defer wg.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				log.Printf("获取IP时发生异常: %v\n", err)
+			}
+		}()
+		*result = get(ctx)
+	}
+	if ipv4Enable {
+		wg.Add(1)
+		go detect(&ipv4Addr, getIpv4)
+	}
+	if ipv6Enable {
+		wg.Add(1)
+		go detect(&ipv6Addr, getIpv6)
+	}
+	wg.Wait()
+	return
+}
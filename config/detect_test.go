@@ -0,0 +1,61 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestGetURLIP 多个接口同时请求, 使用最先返回IP的
+func TestGetURLIP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			select {
+			case <-time.After(5 * time.Second):
+			case <-r.Context().Done():
+			}
+			w.Write([]byte("5.6.7.8"))
+		case "/down":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte("1.2.3.4"))
+		}
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	ip := getURLIP(context.Background(), "IPv4", server.URL+"/slow, "+server.URL+"/down,"+server.URL+"/ok", Ipv4Reg, "")
+	if ip != "1.2.3.4" {
+		t.Fatalf("获取的IP %q 不正确", ip)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("不应等待较慢的接口")
+	}
+	if urls := SplitIPURLs(" https://a , ,https://b"); len(urls) != 2 || urls[1] != "https://b" {
+		t.Errorf("拆分的接口不正确: %v", urls)
+	}
+}
+
+// TestDetectIPs 同时获取IPv4及IPv6
+func TestDetectIPs(t *testing.T) {
+	get := func(ip string) func(ctx context.Context) string {
+		return func(ctx context.Context) string {
+			time.Sleep(200 * time.Millisecond)
+			return ip
+		}
+	}
+	start := time.Now()
+	ipv4Addr, ipv6Addr := detectIPs(context.Background(), true, true, get("1.2.3.4"), get("::1"))
+	if ipv4Addr != "1.2.3.4" || ipv6Addr != "::1" {
+		t.Fatalf("获取的IP不正确: %s %s", ipv4Addr, ipv6Addr)
+	}
+	if time.Since(start) >= 400*time.Millisecond {
+		t.Error("IPv4及IPv6应同时获取")
+	}
+	if ipv4Addr, _ = detectIPs(context.Background(), false, true, get("1.2.3.4"), get("::1")); ipv4Addr != "" {
+		t.Error("未启用时不应获取")
+	}
+}
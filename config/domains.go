@@ -1,10 +1,25 @@
 package config
 
 import (
+	"context"
+	"ddns-go/util"
 	"log"
 	"strings"
+	"time"
 )
 
+// DisabledDomainPrefix 域名前加#时为停用, 不更新, 也不需要删除
+const DisabledDomainPrefix = "#"
+
+// ParseDomainLine 解析一行域名, 返回去掉#后的域名及是否启用
+func ParseDomainLine(line string) (domain string, enabled bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, DisabledDomainPrefix) {
+		return strings.TrimSpace(strings.TrimPrefix(line, DisabledDomainPrefix)), false
+	}
+	return line, true
+}
+
 // 固定的主域名
 var staticMainDomains = []string{"com.cn", "org.cn", "net.cn", "ac.cn"}
 
@@ -18,6 +33,15 @@ type Domains struct {
 	Ipv4Domains []*Domain
 	Ipv6Addr    string
 	Ipv6Domains []*Domain
+	// 获取到的IP的归属, 填写了GeoIPURL时查询
+	Ipv4Info IPInfo
+	Ipv6Info IPInfo
+	// ASN与之前的IP不同, 可能已切换到备用线路
+	ASNChanged bool
+	// 因最小更新间隔或来回变化开始暂缓更新IP
+	Damped bool
+	// DNS服务商连续失败, 本次开始暂停定时更新时为暂停到的时间
+	PausedUntil time.Time
 }
 
 // Domain 域名实体
@@ -25,6 +49,12 @@ type Domain struct {
 	DomainName   string
 	SubDomain    string
 	UpdateStatus updateStatusType // 更新状态
+	FailCount    int              // 连续失败的次数
+	Recovered    bool             // 之前连续失败, 本次恢复正常
+	OldIP        string           // 更新前的IP, 用于通知
+	Error        string           // 失败的原因, 用于通知
+	Source       DomainSource     // 单独的获取IP方式, 为空时使用IPv4/IPv6的
+	IP           string           // 使用单独的获取IP方式时获取到的IP
 }
 
 func (d Domain) String() string {
@@ -51,51 +81,171 @@ func (d Domain) GetSubDomain() string {
 	return "@"
 }
 
-// GetNewIp 接口/网卡获得ip并校验用户输入的域名
-func (domains *Domains) GetNewIp(conf *Config) {
+// GetNewIp 接口/网卡获得ip并校验用户输入的域名, ctx取消时停止请求接口
+func (domains *Domains) GetNewIp(ctx context.Context, conf *Config) {
 	domains.Ipv4Domains = checkParseDomains(conf.Ipv4.Domains)
 	domains.Ipv6Domains = checkParseDomains(conf.Ipv6.Domains)
 
+	// 同时获取IPv4及IPv6, 所有域名都单独设置了获取IP方式时不需要获取
+	ipv4Enable := conf.Ipv4.Enable && hasSharedSource(domains.Ipv4Domains)
+	ipv6Enable := conf.Ipv6.Enable && hasSharedSource(domains.Ipv6Domains)
+	ipv4Addr, ipv6Addr := detectIPs(ctx, ipv4Enable, ipv6Enable, func(ctx context.Context) string {
+		ctx, span := util.StartSpan(ctx, "detect IPv4")
+		defer span.End()
+		span.SetAttribute("ip.gettype", conf.Ipv4.GetType)
+		ipv4Addr := conf.GetIpv4Addr(ctx)
+		span.SetAttribute("ip.address", ipv4Addr)
+		if ipv4Addr == "" {
+			span.SetError("未能获取IPv4地址")
+		}
+		return ipv4Addr
+	}, func(ctx context.Context) string {
+		ctx, span := util.StartSpan(ctx, "detect IPv6")
+		defer span.End()
+		span.SetAttribute("ip.gettype", conf.Ipv6.GetType)
+		ipv6Addr := conf.GetIpv6Addr(ctx)
+		span.SetAttribute("ip.address", ipv6Addr)
+		if ipv6Addr == "" {
+			span.SetError("未能获取IPv6地址")
+		}
+		return ipv6Addr
+	})
+	detectedIPv4, detectedIPv6 := ipv4Addr, ipv6Addr
+	ipv4Addr, ipv4Allowed := conf.checkIPRanges("IPv4", ipv4Addr)
+	ipv6Addr, ipv6Allowed := conf.checkIPRanges("IPv6", ipv6Addr)
+
 	// IPv4
-	if conf.Ipv4.Enable && len(domains.Ipv4Domains) > 0 {
-		ipv4Addr := conf.GetIpv4Addr()
-		if ipv4Addr != "" {
-			domains.Ipv4Addr = ipv4Addr
+	if ipv4Enable {
+		source := ipSource(conf.Ipv4.GetType, conf.Ipv4.URL, conf.Ipv4.NetInterface)
+		AddDetectRecord("IPv4", detectedIPv4, conf.Ipv4.GetType, source, notAllowedReason(ipv4Allowed))
+		if !ipv4Allowed {
+			// 获取到了IP, 不计入获取IP失败的次数
+			domain := firstSharedSource(domains.Ipv4Domains)
+			domain.UpdateStatus = UpdatedFailed
+			domain.Error = ipNotAllowed
+		} else if ipv4Addr != "" {
 			getIPv4FailTimes = 0
+			AddIPHistory("IPv4", ipv4Addr, conf.Ipv4.GetType, source)
+			var damped bool
+			domains.Ipv4Addr, damped = conf.dampIP(ctx, "IPv4", ipv4Addr, time.Now())
+			domains.Damped = domains.Damped || damped
 		} else {
 			// 启用IPv4 & 未获取到IP & 填写了域名 & 失败刚好3次，防止偶尔的网络连接失败，并且只发一次
 			getIPv4FailTimes++
 			if getIPv4FailTimes == 3 {
-				domains.Ipv4Domains[0].UpdateStatus = UpdatedFailed
+				domain := firstSharedSource(domains.Ipv4Domains)
+				domain.UpdateStatus = UpdatedFailed
+				domain.Error = "未能获取IPv4地址"
 			}
 			log.Println("未能获取IPv4地址, 将不会更新")
 		}
 	}
 
 	// IPv6
-	if conf.Ipv6.Enable && len(domains.Ipv6Domains) > 0 {
-		ipv6Addr := conf.GetIpv6Addr()
-		if ipv6Addr != "" {
-			domains.Ipv6Addr = ipv6Addr
+	if ipv6Enable {
+		source := ipSource(conf.Ipv6.GetType, conf.Ipv6.URL, conf.Ipv6.NetInterface)
+		AddDetectRecord("IPv6", detectedIPv6, conf.Ipv6.GetType, source, notAllowedReason(ipv6Allowed))
+		if !ipv6Allowed {
+			// 获取到了IP, 不计入获取IP失败的次数
+			domain := firstSharedSource(domains.Ipv6Domains)
+			domain.UpdateStatus = UpdatedFailed
+			domain.Error = ipNotAllowed
+		} else if ipv6Addr != "" {
 			getIPv6FailTimes = 0
+			AddIPHistory("IPv6", ipv6Addr, conf.Ipv6.GetType, source)
+			var damped bool
+			domains.Ipv6Addr, damped = conf.dampIP(ctx, "IPv6", ipv6Addr, time.Now())
+			domains.Damped = domains.Damped || damped
 		} else {
 			// 启用IPv6 & 未获取到IP & 填写了域名 & 失败刚好3次，防止偶尔的网络连接失败，并且只发一次
 			getIPv6FailTimes++
 			if getIPv6FailTimes == 3 {
-				domains.Ipv6Domains[0].UpdateStatus = UpdatedFailed
+				domain := firstSharedSource(domains.Ipv6Domains)
+				domain.UpdateStatus = UpdatedFailed
+				domain.Error = "未能获取IPv6地址"
 			}
 			log.Println("未能获取IPv6地址, 将不会更新")
 		}
 	}
 
+	if conf.Ipv4.Enable && conf.getDomainsSourceIP(ctx, "IPv4", domains.Ipv4Domains) {
+		domains.Damped = true
+	}
+	if conf.Ipv6.Enable && conf.getDomainsSourceIP(ctx, "IPv6", domains.Ipv6Domains) {
+		domains.Damped = true
+	}
+
+	var v4Changed, v6Changed bool
+	domains.Ipv4Info, v4Changed = conf.lookupIPInfo(ctx, "IPv4", domains.Ipv4Addr)
+	domains.Ipv6Info, v6Changed = conf.lookupIPInfo(ctx, "IPv6", domains.Ipv6Addr)
+	domains.ASNChanged = v4Changed || v6Changed
+}
+
+// notAllowedReason 获取到的IP不在允许的范围内时的原因
+func notAllowedReason(allowed bool) string {
+	if allowed {
+		return ""
+	}
+	return ipNotAllowed
+}
+
+// hasSharedSource 是否有使用IPv4/IPv6的获取IP方式的域名
+func hasSharedSource(domains []*Domain) bool {
+	return firstSharedSource(domains) != nil
+}
+
+// firstSharedSource 第一个使用IPv4/IPv6的获取IP方式的域名
+func firstSharedSource(domains []*Domain) *Domain {
+	for _, domain := range domains {
+		if domain.Source.IsZero() {
+			return domain
+		}
+	}
+	return nil
+}
+
+// ipSource 获取IP的来源, 接口URL或网卡名
+func ipSource(getType string, url string, netInterface string) string {
+	switch getType {
+	case "netInterface", GetTypeWireGuard:
+		return netInterface
+	case GetTypeDynDNS2:
+		return "/nic/update"
+	case GetTypeAuto:
+		return "auto"
+	case GetTypeTailscale:
+		return "tailscale"
+	}
+	return url
+}
+
+// FailedDomains 已启用的域名, 更新状态均为失败. 用于更新时发生异常
+func FailedDomains(conf *Config) (domains Domains) {
+	if conf.Ipv4.Enable {
+		domains.Ipv4Domains = checkParseDomains(conf.Ipv4.Domains)
+	}
+	if conf.Ipv6.Enable {
+		domains.Ipv6Domains = checkParseDomains(conf.Ipv6.Domains)
+	}
+	for _, domain := range append(append([]*Domain{}, domains.Ipv4Domains...), domains.Ipv6Domains...) {
+		domain.UpdateStatus = UpdatedFailed
+	}
+	return
 }
 
 // checkParseDomains 校验并解析用户输入的域名
 func checkParseDomains(domainArr []string) (domains []*Domain) {
 	for _, domainStr := range domainArr {
-		domainStr = strings.TrimSpace(domainStr)
-		if domainStr != "" {
+		domainStr, enabled := ParseDomainLine(domainStr)
+		domainStr, params := SplitDomainParams(domainStr)
+		if domainStr != "" && enabled {
 			domain := &Domain{}
+			source, err := parseDomainSource(params)
+			if err != nil {
+				log.Println(domainStr, err)
+				continue
+			}
+			domain.Source = source
 			sp := strings.Split(domainStr, ".")
 			length := len(sp)
 			if length <= 1 {
@@ -125,6 +275,26 @@ func checkParseDomains(domainArr []string) (domains []*Domain) {
 	return
 }
 
+// ParseDomain 解析域名的主域名及子域名, 不正确时返回nil
+func ParseDomain(domainStr string) *Domain {
+	domains := checkParseDomains([]string{domainStr})
+	if len(domains) == 0 {
+		return nil
+	}
+	return domains[0]
+}
+
+// IPFor 域名要更新的IP, 单独设置了获取IP方式时为其获取到的IP
+func (domains *Domains) IPFor(recordType string, domain *Domain) string {
+	if !domain.Source.IsZero() {
+		return domain.IP
+	}
+	if recordType == "AAAA" {
+		return domains.Ipv6Addr
+	}
+	return domains.Ipv4Addr
+}
+
 // GetNewIpResult 获得GetNewIp结果
 func (domains *Domains) GetNewIpResult(recordType string) (ipAddr string, retDomains []*Domain) {
 	if recordType == "AAAA" {
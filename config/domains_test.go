@@ -1,6 +1,12 @@
 package config
 
 import (
+	"context"
+	"ddns-go/util"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -23,3 +29,64 @@ func TestParseDomainArr(t *testing.T) {
 	}
 
 }
+
+// TestParseDisabledDomain 停用的域名不更新
+func TestParseDisabledDomain(t *testing.T) {
+	parsedDomains := checkParseDomains([]string{"a.mydomain.com", " # b.mydomain.com", "#c.mydomain.com"})
+	if len(parsedDomains) != 1 || parsedDomains[0].String() != "a.mydomain.com" {
+		t.Errorf("停用的域名应被忽略: %v", parsedDomains)
+	}
+	if domain, enabled := ParseDomainLine(" # b.mydomain.com"); domain != "b.mydomain.com" || enabled {
+		t.Errorf("解析停用的域名失败: %s %v", domain, enabled)
+	}
+}
+
+// TestParseDomainSource 域名单独的获取IP方式
+func TestParseDomainSource(t *testing.T) {
+	parsedDomains := checkParseDomains([]string{"lan.mydomain.com?netInterface=eth0", "b.mydomain.com?url=https%3A%2F%2Fexample.com%2Fip%3Fv%3D4", "c.mydomain.com?ttl=1"})
+	if len(parsedDomains) != 2 {
+		t.Fatalf("不支持的参数应被忽略: %v", parsedDomains)
+	}
+	if parsedDomains[0].String() != "lan.mydomain.com" || parsedDomains[0].Source.NetInterface != "eth0" {
+		t.Errorf("解析网卡失败: %+v", parsedDomains[0])
+	}
+	if parsedDomains[1].Source.URL != "https://example.com/ip?v=4" {
+		t.Errorf("解析接口失败: %+v", parsedDomains[1])
+	}
+	if source, err := parseDomainSource("tailscale"); err != nil || !source.Tailscale || source.getType() != GetTypeTailscale {
+		t.Errorf("解析Tailscale失败: %+v %v", source, err)
+	}
+	if source, err := parseDomainSource("wireguard=wg0"); err != nil || source.WireGuard != "wg0" || source.String() != "wireguard=wg0" {
+		t.Errorf("解析WireGuard失败: %+v %v", source, err)
+	}
+	for _, params := range []string{"netInterface=eth0&url=https://example.com", "tailscale&wireguard=wg0", "url=ftp://example.com", "netInterface=", "wireguard="} {
+		if _, err := parseDomainSource(params); err == nil {
+			t.Errorf("%s 应解析失败", params)
+		}
+	}
+}
+
+// TestDomainsSourceFailed 单独设置的获取IP方式失败时域名为失败
+func TestDomainsSourceFailed(t *testing.T) {
+	os.Setenv(util.ConfigFilePathENV, filepath.Join(t.TempDir(), "config.yaml"))
+	defer os.Unsetenv(util.ConfigFilePathENV)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("10.0.0.1"))
+	}))
+	defer server.Close()
+
+	conf := &Config{AllowIPRanges: []string{"1.0.0.0/8"}}
+	lines := []string{"lan.example.com?netInterface=ddns-go-test0", "vpn.example.com?url=" + server.URL}
+	for i := 1; i <= 3; i++ {
+		domains := checkParseDomains(lines)
+		conf.getDomainsSourceIP(context.Background(), "IPv4", domains)
+		if lan := domains[0]; (lan.UpdateStatus == UpdatedFailed) != (i == 3) {
+			t.Errorf("第%d次获取IP失败, 状态为 %s", i, lan.UpdateStatus)
+		} else if i == 3 && lan.Error != "未能获取IPv4地址" {
+			t.Errorf("失败的原因不正确: %s", lan.Error)
+		}
+		if vpn := domains[1]; vpn.UpdateStatus != UpdatedFailed || vpn.Error != ipNotAllowed || vpn.IP != "" {
+			t.Errorf("IP不在允许的范围内时应为失败: %+v", vpn)
+		}
+	}
+}
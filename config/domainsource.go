@@ -0,0 +1,198 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DomainSource 域名单独的获取IP方式, 在域名后填写, 如 lan.example.com?netInterface=eth0.
+// 为空时使用IPv4/IPv6的获取IP方式
+type DomainSource struct {
+	// 从网卡获取
+	NetInterface string
+	// 从接口获取, 多个以逗号分隔
+	URL string
+	// 使用Tailscale的IP
+	Tailscale bool
+	// 使用WireGuard网卡的IP
+	WireGuard string
+}
+
+// IsZero 是否未设置
+func (s DomainSource) IsZero() bool {
+	return s == DomainSource{}
+}
+
+func (s DomainSource) String() string {
+	if s.NetInterface != "" {
+		return "netInterface=" + s.NetInterface
+	}
+	if s.URL != "" {
+		return "url=" + s.URL
+	}
+	if s.Tailscale {
+		return "tailscale"
+	}
+	if s.WireGuard != "" {
+		return "wireguard=" + s.WireGuard
+	}
+	return ""
+}
+
+// getType 对应的获取IP方式
+func (s DomainSource) getType() string {
+	switch {
+	case s.NetInterface != "":
+		return "netInterface"
+	case s.Tailscale:
+		return GetTypeTailscale
+	case s.WireGuard != "":
+		return GetTypeWireGuard
+	}
+	return "url"
+}
+
+// SplitDomainParams 分割域名及?后的参数
+func SplitDomainParams(line string) (domain string, params string) {
+	if i := strings.Index(line, "?"); i >= 0 {
+		return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+	}
+	return strings.TrimSpace(line), ""
+}
+
+// parseDomainSource 解析域名后的参数, 支持 netInterface=eth0、url=https://... (需对&等编码)、tailscale 或 wireguard=wg0
+func parseDomainSource(params string) (source DomainSource, err error) {
+	if params == "" {
+		return
+	}
+	values, err := url.ParseQuery(params)
+	if err != nil {
+		return source, fmt.Errorf("域名的参数 %s 不正确", params)
+	}
+	for key := range values {
+		switch key {
+		case "netInterface", "url", "tailscale", "wireguard":
+		default:
+			return source, fmt.Errorf("不支持域名的参数 %s, 支持: netInterface, url, tailscale, wireguard", key)
+		}
+	}
+	if len(values) > 1 {
+		return source, fmt.Errorf("域名的参数 %s 中 netInterface、url、tailscale 及 wireguard 只能填写一个", params)
+	}
+	source.NetInterface = strings.TrimSpace(values.Get("netInterface"))
+	source.URL = strings.TrimSpace(values.Get("url"))
+	_, source.Tailscale = values["tailscale"]
+	source.WireGuard = strings.TrimSpace(values.Get("wireguard"))
+	for _, u := range SplitIPURLs(source.URL) {
+		if !isHTTPURL(u) {
+			return DomainSource{}, fmt.Errorf("域名的参数中获取IP的接口 %s 不正确", u)
+		}
+	}
+	if source.IsZero() {
+		return source, fmt.Errorf("域名的参数 %s 不正确", params)
+	}
+	return source, nil
+}
+
+// getSourceIP 使用域名单独的获取IP方式获取IP
+func (conf *Config) getSourceIP(ctx context.Context, ipType string, source DomainSource) string {
+	reg := Ipv4Reg
+	if ipType == "IPv6" {
+		reg = Ipv6Reg
+	}
+	if source.URL != "" {
+		return getURLIP(ctx, ipType, source.URL, reg, "")
+	}
+	if source.Tailscale {
+		return getTailscaleIP(ctx, ipType, "")
+	}
+	if source.WireGuard != "" {
+		return getWireGuardIP(ipType, source.WireGuard, "")
+	}
+
+	ipv4, ipv6, err := GetNetInterface()
+	if err != nil {
+		log.Printf("从网卡获得%s失败!\n", ipType)
+		return ""
+	}
+	netInterfaces := ipv4
+	if ipType == "IPv6" {
+		netInterfaces = ipv6
+	}
+	for _, netInterface := range netInterfaces {
+		if netInterface.Name == source.NetInterface && len(netInterface.Address) > 0 {
+			if ipType == "IPv4" {
+				return conf.checkCGNAT(ctx, netInterface.Address[0])
+			}
+			return netInterface.Address[0]
+		}
+	}
+	log.Printf("从网卡中获得%s失败! 网卡名: %s\n", ipType, source.NetInterface)
+	return ""
+}
+
+// 单独设置的获取IP方式获取IP失败的次数, key同dampKey
+var sourceFailTimes = struct {
+	sync.Mutex
+	m map[string]int
+}{m: make(map[string]int)}
+
+// sourceFailed 记录获取IP失败, 与IPv4/IPv6相同, 失败刚好3次时返回true, 只发一次通知
+func sourceFailed(key string, failed bool) bool {
+	sourceFailTimes.Lock()
+	defer sourceFailTimes.Unlock()
+	if !failed {
+		delete(sourceFailTimes.m, key)
+		return false
+	}
+	sourceFailTimes.m[key]++
+	return sourceFailTimes.m[key] == 3
+}
+
+// getDomainsSourceIP 获取单独设置了获取IP方式的域名的IP, 相同的方式只获取一次. 有开始暂缓更新的IP时返回true
+// 获取IP失败或IP不在允许的范围内时, 使用该方式的域名为失败
+func (conf *Config) getDomainsSourceIP(ctx context.Context, ipType string, domains []*Domain) (damped bool) {
+	ips := make(map[DomainSource]string)
+	errs := make(map[DomainSource]string)
+	for _, domain := range domains {
+		if domain.Source.IsZero() {
+			continue
+		}
+		ip, ok := ips[domain.Source]
+		if !ok {
+			key := dampKey(ipType, domain.Source)
+			detected := conf.getSourceIP(ctx, ipType, domain.Source)
+			var allowed bool
+			ip, allowed = conf.checkIPRanges(ipType, detected)
+			getType := domain.Source.getType()
+			AddDetectRecord(ipType, detected, getType, ipSource(getType, domain.Source.URL, domain.Source.NetInterface+domain.Source.WireGuard), notAllowedReason(allowed))
+			switch {
+			case !allowed:
+				// 获取到了IP, 不计入获取IP失败的次数
+				errs[domain.Source] = ipNotAllowed
+			case ip == "":
+				if sourceFailed(key, true) {
+					errs[domain.Source] = "未能获取" + ipType + "地址"
+				}
+				log.Printf("未能获取域名 %s 的%s地址, 将不会更新\n", domain, ipType)
+			default:
+				sourceFailed(key, false)
+				var held bool
+				ip, held = conf.dampIP(ctx, key, ip, time.Now())
+				damped = damped || held
+			}
+			ips[domain.Source] = ip
+		}
+		domain.IP = ip
+		if err := errs[domain.Source]; err != "" {
+			domain.UpdateStatus = UpdatedFailed
+			domain.Error = err
+		}
+	}
+	return
+}
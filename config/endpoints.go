@@ -0,0 +1,309 @@
+package config
+
+import (
+	"context"
+	"crypto/ed25519"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ddns-go/util"
+)
+
+// GetTypeAuto 获取IP方式: 从项目发布的接口列表中自动选择可用的接口
+const GetTypeAuto = "auto"
+
+// EndpointList 获取公网IP的接口列表, 由项目发布并使用ed25519签名
+type EndpointList struct {
+	// 每次发布时增加, 只使用比当前新的列表
+	Version int      `json:"version"`
+	IPv4    []string `json:"ipv4"`
+	IPv6    []string `json:"ipv6"`
+}
+
+//go:embed endpoints.json
+var defaultEndpoints []byte
+
+// 验证接口列表签名的公钥, 发布时使用维护者保管的私钥运行 sign_endpoints.go 签名 endpoints.json, 签名的base64保存为 endpoints.json.sig
+var endpointsPublicKey = mustDecodeKey("2EKe8ChVr55oUWseY2Mfm61XJsNzNUqWqGBKRgMF1mg=")
+
+// 项目发布的接口列表, 签名为 URL + .sig
+var endpointsURL = "https://raw.githubusercontent.com/gdfsnhsw/ddns-go/master/config/endpoints.json"
+
+// 检查新的接口列表的间隔
+const endpointsRefreshInterval = 24 * time.Hour
+
+// 每次获取IP同时请求的接口数
+const autoEndpointTries = 3
+
+// 接口失败后暂停使用的时间, 连续失败时翻倍
+const (
+	endpointMinBackoff = 5 * time.Minute
+	endpointMaxBackoff = 6 * time.Hour
+)
+
+// endpointHealth 接口的健康度
+type endpointHealth struct {
+	// 连续失败的次数
+	Failures int
+	// 最近请求耗时的加权平均
+	Latency time.Duration
+	// 失败后在此之前不优先使用
+	RetryAt time.Time
+}
+
+var endpoints = struct {
+	sync.Mutex
+	list        EndpointList
+	health      map[string]*endpointHealth
+	refreshOnce sync.Once
+}{health: make(map[string]*endpointHealth)}
+
+func mustDecodeKey(s string) ed25519.PublicKey {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		panic("接口列表的公钥不正确")
+	}
+	return key
+}
+
+func init() {
+	if err := json.Unmarshal(defaultEndpoints, &endpoints.list); err != nil {
+		panic(err)
+	}
+}
+
+// parseEndpointList 验证签名并解析接口列表
+func parseEndpointList(data []byte, signature []byte) (list EndpointList, err error) {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(endpointsPublicKey, data, sig) {
+		return list, errors.New("接口列表的签名不正确")
+	}
+	if err = json.Unmarshal(data, &list); err != nil {
+		return list, err
+	}
+	if len(list.IPv4) == 0 || len(list.IPv6) == 0 {
+		return list, errors.New("接口列表为空")
+	}
+	for _, u := range append(append([]string{}, list.IPv4...), list.IPv6...) {
+		if !strings.HasPrefix(u, "https://") {
+			return list, fmt.Errorf("接口 %s 需为https", u)
+		}
+	}
+	return list, nil
+}
+
+// useEndpointList 使用比当前新的接口列表, 返回是否使用
+func useEndpointList(list EndpointList) bool {
+	endpoints.Lock()
+	defer endpoints.Unlock()
+	if list.Version <= endpoints.list.Version {
+		return false
+	}
+	endpoints.list = list
+	return true
+}
+
+// GetEndpoints 当前的接口列表
+func GetEndpoints() EndpointList {
+	endpoints.Lock()
+	defer endpoints.Unlock()
+	return endpoints.list
+}
+
+// loadSavedEndpoints 读取上次下载的接口列表
+func loadSavedEndpoints() {
+	path := util.GetDataFilePath("endpoints.json")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	signature, err := ioutil.ReadFile(path + ".sig")
+	if err != nil {
+		return
+	}
+	if list, err := parseEndpointList(data, signature); err != nil {
+		log.Printf("忽略保存的接口列表 %s: %s\n", path, err)
+	} else {
+		useEndpointList(list)
+	}
+}
+
+// refreshEndpoints 下载项目发布的接口列表, 签名正确且比当前新时使用并保存
+func refreshEndpoints(ctx context.Context) error {
+	data, err := downloadEndpoints(ctx, endpointsURL)
+	if err != nil {
+		return err
+	}
+	signature, err := downloadEndpoints(ctx, endpointsURL+".sig")
+	if err != nil {
+		return err
+	}
+	list, err := parseEndpointList(data, signature)
+	if err != nil {
+		return err
+	}
+	if useEndpointList(list) {
+		log.Printf("已更新获取IP的接口列表到版本 %d\n", list.Version)
+		path := util.GetDataFilePath("endpoints.json")
+		if err := ioutil.WriteFile(path, data, 0600); err == nil {
+			ioutil.WriteFile(path+".sig", signature, 0600)
+		}
+	}
+	return nil
+}
+
+func downloadEndpoints(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := util.HTTPClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载 %s 失败: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, 64<<10))
+}
+
+// startEndpointsRefresh 第一次使用自动选择接口时开始定时下载接口列表
+func startEndpointsRefresh() {
+	endpoints.refreshOnce.Do(func() {
+		loadSavedEndpoints()
+		go func() {
+			for {
+				if err := refreshEndpoints(context.Background()); err != nil {
+					log.Println("检查获取IP的接口列表失败:", err)
+				}
+				time.Sleep(endpointsRefreshInterval)
+			}
+		}()
+	})
+}
+
+// rankedEndpoints 按健康度排序的接口: 未暂停的在前, 失败次数少、耗时短的优先
+func rankedEndpoints(urls []string, now time.Time) []string {
+	endpoints.Lock()
+	defer endpoints.Unlock()
+	ranked := append([]string{}, urls...)
+	health := func(u string) endpointHealth {
+		if h := endpoints.health[u]; h != nil {
+			return *h
+		}
+		return endpointHealth{}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		hi, hj := health(ranked[i]), health(ranked[j])
+		pausedI, pausedJ := now.Before(hi.RetryAt), now.Before(hj.RetryAt)
+		if pausedI != pausedJ {
+			return !pausedI
+		}
+		if pausedI {
+			return hi.RetryAt.Before(hj.RetryAt)
+		}
+		if hi.Failures != hj.Failures {
+			return hi.Failures < hj.Failures
+		}
+		return hi.Latency < hj.Latency
+	})
+	return ranked
+}
+
+// recordEndpoint 记录接口的结果
+func recordEndpoint(url string, latency time.Duration, ok bool, now time.Time) {
+	endpoints.Lock()
+	defer endpoints.Unlock()
+	h := endpoints.health[url]
+	if h == nil {
+		h = &endpointHealth{Latency: latency}
+		endpoints.health[url] = h
+	}
+	if ok {
+		h.Failures = 0
+		h.RetryAt = time.Time{}
+		h.Latency = (h.Latency*3 + latency) / 4
+		return
+	}
+	h.Failures++
+	backoff := endpointMinBackoff
+	for i := 1; i < h.Failures && backoff < endpointMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > endpointMaxBackoff {
+		backoff = endpointMaxBackoff
+	}
+	h.RetryAt = now.Add(backoff)
+}
+
+// PreferredEndpoint 自动选择接口时首先使用的接口, ipType为IPv4/IPv6
+func PreferredEndpoint(ipType string) string {
+	return endpointsFor(ipType)[0]
+}
+
+// endpointsFor 按健康度排序的IPv4/IPv6接口
+func endpointsFor(ipType string) []string {
+	list := GetEndpoints()
+	urls := list.IPv4
+	if ipType == "IPv6" {
+		urls = list.IPv6
+	}
+	return rankedEndpoints(urls, time.Now())
+}
+
+// getAutoIP 同时请求健康度最好的几个接口, 返回最先获取到的IP. 失败或被屏蔽的接口暂停使用
+func getAutoIP(ctx context.Context, ipType string, reg string, expression string) string {
+	startEndpointsRefresh()
+	comp := regexp.MustCompile(reg)
+	urls := endpointsFor(ipType)
+	if len(urls) > autoEndpointTries {
+		urls = urls[:autoEndpointTries]
+	}
+	return raceIP(ctx, urls, func(ctx context.Context, u string) string {
+		start := time.Now()
+		body, err := downloadIP(ctx, u)
+		// 其它接口已返回IP, 不记录
+		if ctx.Err() != nil {
+			return ""
+		}
+		result := ""
+		if err == nil {
+			result = selectIP(expression, uniqueStrings(comp.FindAllString(string(body), -1)))
+		}
+		recordEndpoint(u, time.Since(start), result != "", time.Now())
+		if result == "" {
+			log.Printf("从接口 %s 获取%s失败\n", u, ipType)
+		}
+		return result
+	})
+}
+
+func downloadIP(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := util.HTTPClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, 64<<10))
+}
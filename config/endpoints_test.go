@@ -0,0 +1,180 @@
+package config
+
+import (
+	"context"
+	"crypto/ed25519"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ddns-go/util"
+)
+
+//go:embed endpoints.json.sig
+var defaultEndpointsSig []byte
+
+// TestDefaultEndpoints 内置的接口列表签名正确
+func TestDefaultEndpoints(t *testing.T) {
+	list, err := parseEndpointList(defaultEndpoints, defaultEndpointsSig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Version != GetEndpoints().Version {
+		t.Errorf("内置的接口列表版本不正确: %d", list.Version)
+	}
+}
+
+// useTestKey 使用测试的密钥签名, 结束时恢复接口列表及健康度
+func useTestKey(t *testing.T) ed25519.PrivateKey {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey, oldList, oldHealth := endpointsPublicKey, GetEndpoints(), endpoints.health
+	endpointsPublicKey = public
+	endpoints.health = make(map[string]*endpointHealth)
+	t.Cleanup(func() {
+		endpointsPublicKey = oldKey
+		endpoints.Lock()
+		endpoints.list, endpoints.health = oldList, oldHealth
+		endpoints.Unlock()
+	})
+	return private
+}
+
+func signEndpoints(key ed25519.PrivateKey, list EndpointList) (data []byte, sig []byte) {
+	data, _ = json.Marshal(list)
+	return data, []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)))
+}
+
+// TestParseEndpointList 签名不正确或接口不是https时不使用
+func TestParseEndpointList(t *testing.T) {
+	key := useTestKey(t)
+	list := EndpointList{Version: 2, IPv4: []string{"https://v4.example.com"}, IPv6: []string{"https://v6.example.com"}}
+
+	data, sig := signEndpoints(key, list)
+	if _, err := parseEndpointList(data, sig); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseEndpointList(append(data, ' '), sig); err == nil {
+		t.Error("修改后的接口列表应校验失败")
+	}
+	if _, err := parseEndpointList(data, []byte("abc")); err == nil {
+		t.Error("签名不正确时应校验失败")
+	}
+	list.IPv4 = []string{"http://v4.example.com"}
+	if _, err := parseEndpointList(signEndpoints(key, list)); err == nil {
+		t.Error("接口不是https时应校验失败")
+	}
+}
+
+// TestRefreshEndpoints 下载比当前新的接口列表并保存, 旧的列表不使用
+func TestRefreshEndpoints(t *testing.T) {
+	key := useTestKey(t)
+	dir := t.TempDir()
+	os.Setenv(util.ConfigFilePathENV, filepath.Join(dir, "config.yaml"))
+	defer os.Unsetenv(util.ConfigFilePathENV)
+
+	current := GetEndpoints().Version
+	list := EndpointList{Version: current, IPv4: []string{"https://v4.example.com"}, IPv6: []string{"https://v6.example.com"}}
+	var data, sig []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/endpoints.json.sig" {
+			w.Write(sig)
+		} else {
+			w.Write(data)
+		}
+	}))
+	defer server.Close()
+	oldURL := endpointsURL
+	endpointsURL = server.URL + "/endpoints.json"
+	defer func() { endpointsURL = oldURL }()
+
+	data, sig = signEndpoints(key, list)
+	if err := refreshEndpoints(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if GetEndpoints().IPv4[0] == list.IPv4[0] {
+		t.Error("版本相同时不应使用")
+	}
+
+	list.Version = current + 1
+	data, sig = signEndpoints(key, list)
+	if err := refreshEndpoints(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if GetEndpoints().IPv4[0] != list.IPv4[0] {
+		t.Error("未使用新的接口列表")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.endpoints.json.sig")); err != nil {
+		t.Error("未保存新的接口列表", err)
+	}
+}
+
+// TestGetAutoIP 同时请求几个接口, 失败及未返回IP的接口暂停使用
+func TestGetAutoIP(t *testing.T) {
+	useTestKey(t)
+	endpoints.refreshOnce.Do(func() {})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/down":
+			w.WriteHeader(http.StatusForbidden)
+		case "/html":
+			w.Write([]byte("<html>blocked</html>"))
+		default:
+			// 失败的接口先返回, 记录失败后再返回IP
+			time.Sleep(50 * time.Millisecond)
+			w.Write([]byte("1.2.3.4\n"))
+		}
+	}))
+	defer server.Close()
+	endpoints.list = EndpointList{
+		Version: 100,
+		IPv4:    []string{server.URL + "/down", server.URL + "/html", server.URL + "/ok"},
+	}
+
+	if ip := getAutoIP(context.Background(), "IPv4", Ipv4Reg, ""); ip != "1.2.3.4" {
+		t.Fatalf("获取的IP %q 不正确", ip)
+	}
+	ranked := rankedEndpoints(endpoints.list.IPv4, time.Now())
+	if ranked[0] != server.URL+"/ok" {
+		t.Errorf("成功的接口应优先使用: %v", ranked)
+	}
+	if PreferredEndpoint("IPv4") != server.URL+"/ok" {
+		t.Error("首先使用的接口不正确")
+	}
+	// 暂停结束后按失败次数排序
+	ranked = rankedEndpoints(endpoints.list.IPv4, time.Now().Add(endpointMaxBackoff))
+	if ranked[0] != server.URL+"/ok" || len(ranked) != 3 {
+		t.Errorf("暂停结束后的排序不正确: %v", ranked)
+	}
+}
+
+// TestRecordEndpoint 连续失败时暂停时间翻倍, 不超过最长时间
+func TestRecordEndpoint(t *testing.T) {
+	useTestKey(t)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		recordEndpoint("https://a", time.Second, false, now)
+	}
+	if h := endpoints.health["https://a"]; h.Failures != 3 || !h.RetryAt.Equal(now.Add(4*endpointMinBackoff)) {
+		t.Errorf("暂停时间不正确: %+v", h)
+	}
+	for i := 0; i < 20; i++ {
+		recordEndpoint("https://a", time.Second, false, now)
+	}
+	if h := endpoints.health["https://a"]; !h.RetryAt.Equal(now.Add(endpointMaxBackoff)) {
+		t.Errorf("暂停时间应不超过 %s: %+v", endpointMaxBackoff, h)
+	}
+	recordEndpoint("https://a", time.Second, true, now)
+	if h := endpoints.health["https://a"]; h.Failures != 0 || !h.RetryAt.IsZero() {
+		t.Errorf("成功后应恢复: %+v", h)
+	}
+}
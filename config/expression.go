@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+)
+
+// ipExprEnv 获取IP的表达式中可使用的变量及函数, 每个候选IP计算一次
+func ipExprEnv(ip string, index int) map[string]interface{} {
+	return map[string]interface{}{
+		// 候选IP及其在候选IP中的位置
+		"ip":    ip,
+		"index": index,
+		// ip是否在网段中, 如 inCIDR(ip, "2001:db8::/48")
+		"inCIDR": func(ip string, cidr string) bool {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			return err == nil && ipNet.Contains(net.ParseIP(ip))
+		},
+		// IPv6的第n段(0-7)的值, 如 hextet(ip, 7) 为最后一段
+		"hextet": func(ip string, n int) int {
+			addr := net.ParseIP(ip).To16()
+			if addr == nil || n < 0 || n > 7 {
+				return -1
+			}
+			return int(addr[n*2])<<8 | int(addr[n*2+1])
+		},
+		// IPv4的第n段(0-3)的值
+		"octet": func(ip string, n int) int {
+			addr := net.ParseIP(ip).To4()
+			if addr == nil || n < 0 || n > 3 {
+				return -1
+			}
+			return int(addr[n])
+		},
+		// 保留ip的前bits位, 后面替换为suffix, 如 withSuffix(ip, 64, "::1") 为前缀::1
+		"withSuffix": func(ip string, bits int, suffix string) string {
+			addr, suffixAddr := net.ParseIP(ip), net.ParseIP(suffix)
+			if addr == nil || suffixAddr == nil {
+				return ""
+			}
+			size := 128
+			if addr.To4() != nil {
+				addr, suffixAddr, size = addr.To4(), suffixAddr.To4(), 32
+			}
+			if suffixAddr == nil || bits < 0 || bits > size {
+				return ""
+			}
+			mask := net.CIDRMask(bits, size)
+			result := make(net.IP, len(addr))
+			for i := range addr {
+				result[i] = addr[i]&mask[i] | suffixAddr[i]&^mask[i]
+			}
+			return result.String()
+		},
+	}
+}
+
+// CompileIPExpr 编译获取IP的表达式
+func CompileIPExpr(source string) (*vm.Program, error) {
+	program, err := expr.Compile(source, expr.Env(ipExprEnv("", 0)))
+	if err != nil {
+		return nil, fmt.Errorf("IP表达式 %s 不正确: %s", source, err)
+	}
+	return program, nil
+}
+
+// selectIP 使用表达式从候选IP中选择IP, 表达式为空时使用第一个. 每个候选IP的结果为:
+// false/nil 排除, true 保留, 数字 保留且值最小的优先, 字符串 替换为该IP
+func selectIP(source string, candidates []string) string {
+	if source == "" {
+		if len(candidates) > 0 {
+			return candidates[0]
+		}
+		return ""
+	}
+	program, err := CompileIPExpr(source)
+	if err != nil {
+		log.Println(err)
+		return ""
+	}
+
+	result := ""
+	best := math.Inf(1)
+	for i, ip := range candidates {
+		if ip == "" {
+			continue
+		}
+		out, err := expr.Run(program, ipExprEnv(ip, i))
+		if err != nil {
+			log.Printf("IP表达式计算 %s 失败: %s\n", ip, err)
+			continue
+		}
+		score := float64(i)
+		switch v := out.(type) {
+		case nil:
+			continue
+		case bool:
+			if !v {
+				continue
+			}
+		case int:
+			score = float64(v)
+		case float64:
+			score = v
+		case string:
+			if net.ParseIP(v) == nil {
+				log.Printf("IP表达式的结果 %q 不是IP\n", v)
+				continue
+			}
+			ip = v
+		default:
+			log.Printf("IP表达式的结果 %v 不支持, 需为bool、数字或IP\n", out)
+			continue
+		}
+		if score < best {
+			best, result = score, ip
+		}
+	}
+	return result
+}
+
+// uniqueStrings 去除重复的值, 保持原有顺序
+func uniqueStrings(values []string) (result []string) {
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return
+}
@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+// TestSelectIP 使用表达式从候选IP中选择或转换IP
+func TestSelectIP(t *testing.T) {
+	candidates := []string{"fd00::1", "2001:db8:1::20", "2001:db8:1::5", "2001:db9::1"}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "fd00::1"},
+		{`inCIDR(ip, "2001:db8::/32")`, "2001:db8:1::20"},
+		{`inCIDR(ip, "2001:db8:1::/48") ? hextet(ip, 7) : nil`, "2001:db8:1::5"},
+		{`index == 3 ? withSuffix(ip, 64, "::abcd") : false`, "2001:db9::abcd"},
+		{`inCIDR(ip, "10.0.0.0/8")`, ""},
+		{`"not ip"`, ""},
+	}
+	for _, tt := range tests {
+		if got := selectIP(tt.expr, candidates); got != tt.want {
+			t.Errorf("%s: 结果为 %q, 应为 %q", tt.expr, got, tt.want)
+		}
+	}
+
+	if got := selectIP(`octet(ip, 0) == 192 && octet(ip, 3) > 1`, []string{"10.0.0.1", "192.168.1.1", "192.168.1.2"}); got != "192.168.1.2" {
+		t.Errorf("IPv4的结果为 %q", got)
+	}
+	if _, err := CompileIPExpr(`unknown(ip)`); err == nil {
+		t.Error("不存在的函数应编译失败")
+	}
+}
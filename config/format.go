@@ -0,0 +1,40 @@
+package config
+
+import (
+	"bytes"
+	"ddns-go/util"
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// isJSONFile 根据扩展名判断配置文件格式, .json为JSON, 其它(.yaml/.yml等)为YAML
+func isJSONFile(path string) bool {
+	if util.IsRemoteConfigPath(path) {
+		if u, err := url.Parse(path); err == nil {
+			path = u.Path
+		}
+	}
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// unmarshalConfig 按配置文件格式反序列化, 不允许未知的字段
+func unmarshalConfig(path string, byt []byte, conf *Config) error {
+	if isJSONFile(path) {
+		decoder := json.NewDecoder(bytes.NewReader(byt))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(conf)
+	}
+	return yaml.UnmarshalStrict(byt, conf)
+}
+
+// marshalConfig 按配置文件格式序列化
+func marshalConfig(path string, conf *Config) ([]byte, error) {
+	if isJSONFile(path) {
+		return json.MarshalIndent(conf, "", "  ")
+	}
+	return yaml.Marshal(conf)
+}
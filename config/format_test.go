@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+)
+
+// TestConfigFormat 测试YAML/JSON格式的配置文件
+func TestConfigFormat(t *testing.T) {
+	conf := &Config{TTL: "600"}
+	conf.DNS.Name = "cloudflare"
+	conf.Ipv4.Domains = []string{"test.example.com"}
+
+	for _, path := range []string{"config.yaml", "config.yml", "config.json", "config.JSON"} {
+		byt, err := marshalConfig(path, conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result Config
+		if err = unmarshalConfig(path, byt, &result); err != nil {
+			t.Fatalf("%s 反序列化失败: %s", path, err)
+		}
+		if result.TTL != conf.TTL || result.DNS.Name != conf.DNS.Name || len(result.Ipv4.Domains) != 1 {
+			t.Errorf("%s 前后不一致", path)
+		}
+	}
+
+	var result Config
+	if err := unmarshalConfig("config.json", []byte(`{"Unknown": 1}`), &result); err == nil {
+		t.Error("JSON未知的字段应返回错误")
+	}
+}
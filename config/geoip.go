@@ -0,0 +1,181 @@
+package config
+
+import (
+	"context"
+	"ddns-go/util"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IPInfo IP的归属, 通过GeoIPURL查询
+type IPInfo struct {
+	// 国家代码, 如 CN
+	Country string `json:",omitempty"`
+	// 自治系统号, 如 AS4134
+	ASN string `json:",omitempty"`
+	// 运营商或组织
+	Org string `json:",omitempty"`
+}
+
+// String 如 CN AS4134 Chinanet, 未查询到时为空
+func (info IPInfo) String() string {
+	var parts []string
+	for _, s := range []string{info.Country, info.ASN, info.Org} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// 上次查询的IP及结果, key为IPv4/IPv6. IP未变化时不再查询
+var lastIPInfo = struct {
+	sync.Mutex
+	m map[string]ipInfoEntry
+}{m: make(map[string]ipInfoEntry)}
+
+type ipInfoEntry struct {
+	ip   string
+	info IPInfo
+	// ASN与之前的IP不同, 发送通知后清除
+	asnChanged bool
+}
+
+// lookupIPInfo 查询获取到的IP的归属, 未填写GeoIPURL或查询失败时返回空. ASN与之前的IP不同时asnChanged为true
+func (conf *Config) lookupIPInfo(ctx context.Context, ipType string, ipAddr string) (info IPInfo, asnChanged bool) {
+	if conf.GeoIPURL == "" || ipAddr == "" {
+		return
+	}
+	lastIPInfo.Lock()
+	last, ok := lastIPInfo.m[ipType]
+	lastIPInfo.Unlock()
+	if ok && last.ip == ipAddr {
+		return last.info, last.asnChanged
+	}
+
+	info, err := LookupIPInfo(ctx, conf.GeoIPURL, ipAddr)
+	if err != nil {
+		log.Printf("查询%s %s 的归属失败: %s\n", ipType, ipAddr, err)
+		return
+	}
+	entry := ipInfoEntry{ip: ipAddr, info: info}
+	if ok && last.info.ASN != "" && info.ASN != "" && last.info.ASN != info.ASN {
+		entry.asnChanged = true
+		log.Printf("%s的ASN由 %s 变为 %s, 可能已切换到备用线路\n", ipType, last.info, info)
+	}
+	lastIPInfo.Lock()
+	lastIPInfo.m[ipType] = entry
+	lastIPInfo.Unlock()
+	return info, entry.asnChanged
+}
+
+// clearASNChanged 已发送ASN变化的通知
+func clearASNChanged() {
+	lastIPInfo.Lock()
+	defer lastIPInfo.Unlock()
+	for ipType, entry := range lastIPInfo.m {
+		entry.asnChanged = false
+		lastIPInfo.m[ipType] = entry
+	}
+}
+
+// CheckGeoIPURL 校验IP归属查询接口, 需为http(s)
+func CheckGeoIPURL(rawURL string) error {
+	u, err := url.Parse(strings.ReplaceAll(rawURL, "{ip}", "1.2.3.4"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("IP归属查询接口 %s 不正确, 如 https://ipapi.co/{ip}/json/", rawURL)
+	}
+	return nil
+}
+
+// LookupIPInfo 查询IP的归属. rawURL中的{ip}替换为IP, 未包含时加在最后
+func LookupIPInfo(ctx context.Context, rawURL string, ipAddr string) (info IPInfo, err error) {
+	if strings.Contains(rawURL, "{ip}") {
+		rawURL = strings.ReplaceAll(rawURL, "{ip}", url.PathEscape(ipAddr))
+	} else {
+		rawURL += url.PathEscape(ipAddr)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return
+	}
+	resp, err := util.HTTPClient().Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		return info, fmt.Errorf("返回状态码 %d", resp.StatusCode)
+	}
+	return parseIPInfo(body)
+}
+
+// 以ASN开头的组织, 如 AS4134 Chinanet
+var asnPrefixReg = regexp.MustCompile(`(?i)^AS(\d+)\s*(.*)$`)
+
+// parseIPInfo 解析常见接口返回的JSON, 如 ipapi.co, ip-api.com, ipinfo.io, ipwho.is
+func parseIPInfo(body []byte) (info IPInfo, err error) {
+	var m map[string]interface{}
+	if err = json.Unmarshal(body, &m); err != nil {
+		return info, fmt.Errorf("返回的不是JSON: %s", err)
+	}
+	// ipwho.is 的ASN在connection中
+	for _, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			for k, v := range nested {
+				if _, exists := m[k]; !exists {
+					m[k] = v
+				}
+			}
+		}
+	}
+	get := func(keys ...string) string {
+		for _, key := range keys {
+			switch v := m[key].(type) {
+			case string:
+				if v != "" {
+					return v
+				}
+			case float64:
+				return strconv.FormatInt(int64(v), 10)
+			}
+		}
+		return ""
+	}
+
+	info.Country = get("country_code", "countryCode", "country")
+	if asn := get("asn"); asn != "" {
+		info.ASN = "AS" + strings.TrimPrefix(strings.ToUpper(asn), "AS")
+	}
+	for _, key := range []string{"org", "as", "isp"} {
+		s := get(key)
+		if s == "" {
+			continue
+		}
+		if match := asnPrefixReg.FindStringSubmatch(s); match != nil {
+			if info.ASN == "" {
+				info.ASN = "AS" + match[1]
+			}
+			s = match[2]
+		}
+		if info.Org == "" {
+			info.Org = s
+		}
+	}
+	if info.Country == "" && info.ASN == "" {
+		return info, fmt.Errorf("返回结果中未包含国家或ASN: %s", body)
+	}
+	return info, nil
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestParseIPInfo 解析常见接口返回的国家及ASN
+func TestParseIPInfo(t *testing.T) {
+	data := map[string]IPInfo{
+		// ipapi.co
+		`{"ip":"1.2.3.4","country_code":"CN","asn":"AS4134","org":"Chinanet"}`: {Country: "CN", ASN: "AS4134", Org: "Chinanet"},
+		// ip-api.com
+		`{"status":"success","countryCode":"CN","country":"China","as":"AS4134 Chinanet","isp":"China Telecom"}`: {Country: "CN", ASN: "AS4134", Org: "Chinanet"},
+		// ipinfo.io
+		`{"ip":"1.2.3.4","country":"CN","org":"AS4134 CHINANET-BACKBONE"}`: {Country: "CN", ASN: "AS4134", Org: "CHINANET-BACKBONE"},
+		// ipwho.is
+		`{"ip":"1.2.3.4","country_code":"CN","connection":{"asn":4134,"org":"Chinanet","isp":"China Telecom"}}`: {Country: "CN", ASN: "AS4134", Org: "Chinanet"},
+	}
+	for body, want := range data {
+		if info, err := parseIPInfo([]byte(body)); err != nil || info != want {
+			t.Errorf("解析 %s 为 %+v, 应为 %+v: %v", body, info, want, err)
+		}
+	}
+	for _, body := range []string{`<html>`, `{"ip":"1.2.3.4"}`} {
+		if _, err := parseIPInfo([]byte(body)); err == nil {
+			t.Errorf("%s 应返回错误", body)
+		}
+	}
+}
+
+// TestLookupIPInfo IP未变化时不再查询, ASN变化时发送事件, 通知后清除
+func TestLookupIPInfo(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if strings.HasSuffix(r.URL.Path, "/5.6.7.8") {
+			w.Write([]byte(`{"countryCode":"CN","as":"AS9808 China Mobile"}`))
+			return
+		}
+		w.Write([]byte(`{"countryCode":"CN","as":"AS4134 Chinanet"}`))
+	}))
+	defer server.Close()
+	defer delete(lastIPInfo.m, "IPv4")
+
+	conf := &Config{GeoIPURL: server.URL + "/json/{ip}"}
+	if info, changed := conf.lookupIPInfo(context.Background(), "IPv4", "1.2.3.4"); info.ASN != "AS4134" || changed {
+		t.Fatalf("查询结果 %+v 不正确", info)
+	}
+	if _, changed := conf.lookupIPInfo(context.Background(), "IPv4", "1.2.3.4"); changed || requests != 1 {
+		t.Errorf("IP未变化时不应再查询, 已查询 %d 次", requests)
+	}
+
+	info, changed := conf.lookupIPInfo(context.Background(), "IPv4", "5.6.7.8")
+	if info.ASN != "AS9808" || !changed {
+		t.Fatalf("ASN变化时应发送事件: %+v", info)
+	}
+	events := getNotifyEvents(&Domains{Ipv4Addr: "5.6.7.8", Ipv4Info: info, ASNChanged: changed})
+	if len(events) != 1 || events[0] != EventASNChanged {
+		t.Errorf("事件 %v 不正确", events)
+	}
+	clearASNChanged()
+	if _, changed := conf.lookupIPInfo(context.Background(), "IPv4", "5.6.7.8"); changed {
+		t.Error("通知后不应再次发送ASN变化")
+	}
+
+	if err := CheckGeoIPURL("ftp://example.com/{ip}"); err == nil {
+		t.Error("不是http(s)的接口应返回错误")
+	}
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"ddns-go/util"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// 最多显示的记录条数
+const maxIPHistoryNum = 500
+
+// 未设置时历史记录保留的天数
+const defaultHistoryDays = 90
+
+// 历史记录中的类型
+const (
+	historyIPChanges  = "ipChanges"
+	historyDetections = "detections"
+	historyUpdates    = "updates"
+	historyAudit      = "audit"
+)
+
+// IPHistory IP变化记录
+type IPHistory struct {
+	Time time.Time
+	// IPv4/IPv6
+	Type  string
+	OldIP string
+	IP    string
+	// 获取IP类型 url/netInterface
+	GetType string
+	// 接口URL或网卡名
+	Source string
+}
+
+// DetectRecord 每次获取IP的结果, IP为空时为获取失败
+type DetectRecord struct {
+	Time    time.Time
+	Type    string
+	IP      string
+	GetType string
+	Source  string
+	// 获取到的IP不在允许的范围内时的原因
+	Error string `json:",omitempty"`
+}
+
+// UpdateRecord 每次更新域名的结果
+type UpdateRecord struct {
+	Time       time.Time
+	Provider   string
+	Domain     string
+	RecordType string
+	OldIP      string
+	IP         string
+	Result     string
+}
+
+type ipHistoryType struct {
+	// 每种类型最后的IP
+	lastIP    map[string]string
+	loaded    bool
+	lastPrune time.Time
+	Lock      sync.Mutex
+}
+
+var ipHistory = &ipHistoryType{}
+
+// historyStore 历史记录保存在配置文件同目录的history.db
+func historyStore() util.Store {
+	return util.Store{Path: util.GetDataFilePath("history.db")}
+}
+
+// AddIPHistory IP有变化时记录
+func AddIPHistory(ipType string, ip string, getType string, source string) {
+	ipHistory.Lock.Lock()
+	defer ipHistory.Lock.Unlock()
+
+	ipHistory.load()
+
+	oldIP := ipHistory.lastIP[ipType]
+	if oldIP == ip {
+		return
+	}
+
+	now := time.Now()
+	err := historyStore().Add(historyIPChanges, util.StoreRecord{Time: now, Value: IPHistory{
+		Time:    now,
+		Type:    ipType,
+		OldIP:   oldIP,
+		IP:      ip,
+		GetType: getType,
+		Source:  source,
+	}})
+	if err != nil {
+		log.Println("保存IP变化记录失败", err)
+		return
+	}
+	ipHistory.lastIP[ipType] = ip
+}
+
+// AddDetectRecord 记录获取IP的结果, reason为获取到的IP不能使用的原因
+func AddDetectRecord(ipType string, ip string, getType string, source string, reason string) {
+	now := time.Now()
+	err := historyStore().Add(historyDetections, util.StoreRecord{Time: now, Value: DetectRecord{
+		Time:    now,
+		Type:    ipType,
+		IP:      ip,
+		GetType: getType,
+		Source:  source,
+		Error:   reason,
+	}})
+	if err != nil {
+		log.Println("保存获取IP的记录失败", err)
+	}
+}
+
+// AddUpdateRecords 记录更新域名的结果, 并删除超过保留天数的历史记录
+func AddUpdateRecords(records []UpdateRecord, historyDays int) {
+	now := time.Now()
+	values := make([]util.StoreRecord, len(records))
+	for i := range records {
+		records[i].Time = now
+		values[i] = util.StoreRecord{Time: now, Value: records[i]}
+	}
+	if err := historyStore().Add(historyUpdates, values...); err != nil {
+		log.Println("保存更新记录失败", err)
+	}
+	pruneHistory(historyDays, now)
+}
+
+// pruneHistory 每小时最多一次, 删除超过保留天数的历史记录
+func pruneHistory(historyDays int, now time.Time) {
+	ipHistory.Lock.Lock()
+	defer ipHistory.Lock.Unlock()
+
+	if now.Sub(ipHistory.lastPrune) < time.Hour {
+		return
+	}
+	ipHistory.lastPrune = now
+	if historyDays <= 0 {
+		historyDays = defaultHistoryDays
+	}
+	_, err := historyStore().Prune(now.AddDate(0, 0, -historyDays), historyIPChanges, historyDetections, historyUpdates, historyAudit)
+	if err != nil {
+		log.Println("清理历史记录失败", err)
+	}
+}
+
+// GetIPHistory 获得IP变化记录, 最新的在前
+func GetIPHistory() (histories []IPHistory) {
+	listHistory(historyIPChanges, func(value []byte) bool {
+		var h IPHistory
+		if json.Unmarshal(value, &h) == nil {
+			histories = append(histories, h)
+		}
+		return len(histories) < maxIPHistoryNum
+	})
+	return
+}
+
+// GetDetectRecords 获得最近limit条获取IP的记录, 最新的在前
+func GetDetectRecords(limit int) (records []DetectRecord) {
+	listHistory(historyDetections, func(value []byte) bool {
+		var r DetectRecord
+		if json.Unmarshal(value, &r) == nil {
+			records = append(records, r)
+		}
+		return len(records) < limit
+	})
+	return
+}
+
+// GetUpdateRecords 获得最近limit条更新域名的记录, 最新的在前. domain不为空时只返回该域名的
+func GetUpdateRecords(limit int, domain string) (records []UpdateRecord) {
+	listHistory(historyUpdates, func(value []byte) bool {
+		var r UpdateRecord
+		if json.Unmarshal(value, &r) == nil && (domain == "" || r.Domain == domain) {
+			records = append(records, r)
+		}
+		return len(records) < limit
+	})
+	return
+}
+
+// listHistory 从新到旧读取, fn返回false时停止
+func listHistory(bucket string, fn func(value []byte) bool) {
+	ipHistory.Lock.Lock()
+	ipHistory.load()
+	ipHistory.Lock.Unlock()
+
+	if err := historyStore().List(bucket, fn); err != nil {
+		log.Println("读取历史记录失败", err)
+	}
+}
+
+// load 首次使用时读取每种类型最后的IP, 之前版本的history.json导入后删除
+func (h *ipHistoryType) load() {
+	if h.loaded {
+		return
+	}
+	h.loaded = true
+	h.lastIP = map[string]string{}
+
+	h.migrate()
+	historyStore().List(historyIPChanges, func(value []byte) bool {
+		var history IPHistory
+		if json.Unmarshal(value, &history) == nil {
+			if _, ok := h.lastIP[history.Type]; !ok {
+				h.lastIP[history.Type] = history.IP
+			}
+		}
+		return len(h.lastIP) < 2
+	})
+}
+
+// migrate 导入之前版本保存的history.json
+func (h *ipHistoryType) migrate() {
+	jsonPath := util.GetDataFilePath("history.json")
+	byt, err := ioutil.ReadFile(jsonPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("读取IP变化记录失败", err)
+		}
+		return
+	}
+
+	var histories []IPHistory
+	if err = json.Unmarshal(byt, &histories); err != nil {
+		log.Println("反序列化IP变化记录失败", err)
+		return
+	}
+	records := make([]util.StoreRecord, len(histories))
+	for i, history := range histories {
+		records[i] = util.StoreRecord{Time: history.Time, Value: history}
+	}
+	if err = historyStore().Add(historyIPChanges, records...); err != nil {
+		log.Println("导入IP变化记录失败", err)
+		return
+	}
+	os.Remove(jsonPath)
+}
@@ -0,0 +1,12 @@
+package config
+
+// Hooks 更新前后运行的命令, 使用 sh -c (Windows中为 cmd /C) 运行, 通过环境变量传递IP及域名
+// 可运行任意命令, 只能在配置文件中设置, 网页导入配置及gRPC SetConfig时保留当前的
+type Hooks struct {
+	// 获取IP前运行, 如启动VPN
+	Before string
+	// 有域名更新成功后运行, 如修改防火墙规则
+	Success string
+	// 有域名更新失败后运行
+	Failed string
+}
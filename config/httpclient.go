@@ -0,0 +1,37 @@
+package config
+
+import (
+	"ddns-go/util"
+	"fmt"
+	"time"
+)
+
+// HTTPClient 请求DNS服务商、获取IP及通知的超时(秒)及连接池, 为0时使用默认值. 只能在配置文件中设置
+type HTTPClient struct {
+	// 建立连接的超时, 包括TLS握手, 默认10秒
+	ConnectTimeout int
+	// 整个请求的超时, 默认30秒
+	Timeout int
+	// 每个域名保持的空闲连接数, 默认4
+	MaxIdleConns int
+	// 空闲连接保持的时间, 默认90秒
+	IdleTimeout int
+}
+
+// options 转换为util.HTTPOptions
+func (c HTTPClient) options() util.HTTPOptions {
+	return util.HTTPOptions{
+		ConnectTimeout:      time.Duration(c.ConnectTimeout) * time.Second,
+		Timeout:             time.Duration(c.Timeout) * time.Second,
+		MaxIdleConnsPerHost: c.MaxIdleConns,
+		IdleConnTimeout:     time.Duration(c.IdleTimeout) * time.Second,
+	}
+}
+
+// validate 校验, 不能为负数
+func (c HTTPClient) validate() error {
+	if c.ConnectTimeout < 0 || c.Timeout < 0 || c.MaxIdleConns < 0 || c.IdleTimeout < 0 {
+		return fmt.Errorf("httpclient的配置不能为负数: %+v", c)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"ddns-go/util"
+	"log"
+	"net"
+)
+
+// ipNotAllowed 获取到的IP不在允许的IP段或在禁止的IP段中, 与获取IP失败区分
+const ipNotAllowed = "IP不在允许的范围内"
+
+// checkIPRanges 获取的IP不在允许的IP段或在禁止的IP段中时返回空及false, 不更新到域名
+func (conf *Config) checkIPRanges(ipType string, ipAddr string) (string, bool) {
+	if ipAddr == "" || len(conf.AllowIPRanges)+len(conf.DenyIPRanges) == 0 {
+		return ipAddr, true
+	}
+	ip := net.ParseIP(ipAddr)
+	if ip == nil {
+		return ipAddr, true
+	}
+
+	// 已在保存时校验
+	deny, _ := util.ParseIPRanges(conf.DenyIPRanges)
+	for _, ipNet := range deny {
+		if ipNet.Contains(ip) {
+			log.Printf("获取的%s %s 在禁止的IP段 %s 中, 将不会更新\n", ipType, ipAddr, ipNet)
+			return "", false
+		}
+	}
+
+	allow, _ := util.ParseIPRanges(conf.AllowIPRanges)
+	sameType := false
+	for _, ipNet := range allow {
+		if (ipNet.IP.To4() != nil) != (ip.To4() != nil) {
+			continue
+		}
+		if ipNet.Contains(ip) {
+			return ipAddr, true
+		}
+		sameType = true
+	}
+	if sameType {
+		log.Printf("获取的%s %s 不在允许的IP段中, 将不会更新\n", ipType, ipAddr)
+		return "", false
+	}
+	return ipAddr, true
+}
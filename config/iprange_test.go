@@ -0,0 +1,74 @@
+package config
+
+import (
+	"context"
+	"ddns-go/util"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCheckIPRanges 不在允许的IP段或在禁止的IP段中时不更新
+func TestCheckIPRanges(t *testing.T) {
+	conf := &Config{
+		AllowIPRanges: []string{"1.2.0.0/16", "5.6.7.8"},
+		DenyIPRanges:  []string{"1.2.3.0/24", "fd00::/8"},
+	}
+	tests := map[string]string{
+		"1.2.4.5":  "1.2.4.5",
+		"5.6.7.8":  "5.6.7.8",
+		"1.2.3.4":  "",
+		"10.0.0.1": "",
+		// 没有允许的IPv6段时只检查禁止的
+		"2409::1": "2409::1",
+		"fd00::1": "",
+		"":        "",
+	}
+	for ip, want := range tests {
+		got, allowed := conf.checkIPRanges("IP", ip)
+		if got != want {
+			t.Errorf("%s 返回 %q, 应为 %q", ip, got, want)
+		}
+		if allowed != (ip == "" || want != "") {
+			t.Errorf("%s 不在允许的范围内时应返回false", ip)
+		}
+	}
+
+	if got, _ := (&Config{}).checkIPRanges("IPv4", "10.0.0.1"); got != "10.0.0.1" {
+		t.Errorf("未填写IP段时应不限制, 返回 %q", got)
+	}
+}
+
+// TestGetNewIpNotAllowed 获取到的IP不在允许的范围内时记录原因, 不计入获取IP失败的次数
+func TestGetNewIpNotAllowed(t *testing.T) {
+	os.Setenv(util.ConfigFilePathENV, filepath.Join(t.TempDir(), "config.yaml"))
+	defer os.Unsetenv(util.ConfigFilePathENV)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("10.0.0.1"))
+	}))
+	defer server.Close()
+
+	conf := &Config{AllowIPRanges: []string{"1.0.0.0/8"}}
+	conf.Ipv4.Enable = true
+	conf.Ipv4.GetType = "url"
+	conf.Ipv4.URL = server.URL
+	conf.Ipv4.Domains = []string{"www.example.com"}
+	failTimes := getIPv4FailTimes
+
+	var domains Domains
+	domains.GetNewIp(context.Background(), conf)
+	if domains.Ipv4Addr != "" {
+		t.Errorf("不在允许的范围内时不应更新: %s", domains.Ipv4Addr)
+	}
+	if domain := domains.Ipv4Domains[0]; domain.UpdateStatus != UpdatedFailed || domain.Error != ipNotAllowed {
+		t.Errorf("应记录不在允许的范围内: %+v", domain)
+	}
+	if getIPv4FailTimes != failTimes {
+		t.Error("不应计入获取IP失败的次数")
+	}
+	if records := GetDetectRecords(1); len(records) != 1 || records[0].IP != "10.0.0.1" || records[0].Error != ipNotAllowed {
+		t.Errorf("获取IP的记录不正确: %+v", records)
+	}
+}
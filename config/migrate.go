@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigVersion 当前配置文件的版本, 修改配置文件的结构时加1并在migrations中添加升级方法
+const ConfigVersion = 1
+
+// migrations 配置文件升级方法, migrations[i] 将版本i升级到i+1
+// 升级方法修改的是配置文件中原始的键值, 键不区分大小写(YAML为小写, JSON为字段名)
+var migrations = []func(raw map[interface{}]interface{}){
+	// 0 -> 1: 未区分获取IP方式的旧配置, 默认通过接口获取
+	func(raw map[interface{}]interface{}) {
+		for _, ipType := range []string{"ipv4", "ipv6"} {
+			ipConf, ok := rawGet(raw, ipType).(map[interface{}]interface{})
+			if ok && rawGet(ipConf, "gettype") == nil {
+				ipConf["gettype"] = "url"
+			}
+		}
+	},
+}
+
+// decodeConfig 反序列化配置文件, 旧版本的配置自动升级
+// 不允许未知的字段, 防止保存时丢失新版本的配置
+func decodeConfig(path string, byt []byte) (conf Config, err error) {
+	// JSON也是合法的YAML
+	var raw map[interface{}]interface{}
+	if err = yaml.Unmarshal(byt, &raw); err != nil {
+		return
+	}
+	if raw == nil {
+		raw = map[interface{}]interface{}{}
+	}
+
+	version, _ := rawGet(raw, "version").(int)
+	if version > ConfigVersion {
+		return conf, fmt.Errorf("配置文件的版本%d高于当前支持的版本%d, 请升级ddns-go", version, ConfigVersion)
+	}
+	if version < ConfigVersion {
+		for v := version; v < ConfigVersion; v++ {
+			migrations[v](raw)
+		}
+		rawSet(raw, "version", ConfigVersion)
+		if byt, err = marshalRaw(path, raw); err != nil {
+			return
+		}
+		log.Printf("配置文件已从版本%d升级到%d\n", version, ConfigVersion)
+	}
+
+	if err = unmarshalConfig(path, byt, &conf); err != nil {
+		return conf, fmt.Errorf("%s, 配置文件可能来自新版本的ddns-go", err)
+	}
+	return
+}
+
+// rawGet 获得键的值, 不区分大小写
+func rawGet(raw map[interface{}]interface{}, key string) interface{} {
+	for k, v := range raw {
+		if s, ok := k.(string); ok && strings.EqualFold(s, key) {
+			return v
+		}
+	}
+	return nil
+}
+
+// rawSet 修改键的值, 不区分大小写
+func rawSet(raw map[interface{}]interface{}, key string, value interface{}) {
+	for k := range raw {
+		if s, ok := k.(string); ok && strings.EqualFold(s, key) {
+			raw[k] = value
+			return
+		}
+	}
+	raw[key] = value
+}
+
+// marshalRaw 按配置文件格式序列化原始的键值
+func marshalRaw(path string, raw map[interface{}]interface{}) ([]byte, error) {
+	if isJSONFile(path) {
+		return json.Marshal(toStringKeys(raw))
+	}
+	return yaml.Marshal(raw)
+}
+
+// toStringKeys YAML的map转换为JSON支持的map
+func toStringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = toStringKeys(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = toStringKeys(item)
+		}
+	}
+	return value
+}
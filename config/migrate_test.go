@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+)
+
+// TestDecodeConfig 测试旧版本配置的升级
+func TestDecodeConfig(t *testing.T) {
+	for _, c := range []struct {
+		path string
+		byt  string
+	}{
+		{"config.yaml", "ipv4:\n  enable: true\n  url: https://myip.ipip.net\n"},
+		{"config.json", `{"Ipv4": {"Enable": true, "URL": "https://myip.ipip.net"}}`},
+	} {
+		conf, err := decodeConfig(c.path, []byte(c.byt))
+		if err != nil {
+			t.Fatalf("%s 升级失败: %s", c.path, err)
+		}
+		if conf.Version != ConfigVersion || conf.Ipv4.GetType != "url" || !conf.Ipv4.Enable {
+			t.Errorf("%s 升级不正确: %+v", c.path, conf)
+		}
+	}
+
+	if _, err := decodeConfig("config.yaml", []byte("version: 99\n")); err == nil {
+		t.Error("版本高于当前支持的版本时应返回错误")
+	}
+	if _, err := decodeConfig("config.yaml", []byte("version: 1\nunknown: 1\n")); err == nil {
+		t.Error("包含未知的字段时应返回错误")
+	}
+}
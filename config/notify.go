@@ -0,0 +1,201 @@
+package config
+
+import (
+	"context"
+	"ddns-go/util"
+	"fmt"
+	"log"
+)
+
+// NotifyResult 通知结果, 包含原始的响应
+type NotifyResult struct {
+	Channel    string
+	StatusCode int
+	Response   string
+	Error      string
+}
+
+// 通知事件
+const (
+	// EventIPChanged 更新了解析记录
+	EventIPChanged = "ip-changed"
+	// EventUpdateFailed 更新解析记录失败
+	EventUpdateFailed = "update-failed"
+	// EventDetectionFailed 多次获取IP失败
+	EventDetectionFailed = "detection-failed"
+	// EventRecovered 连续失败后恢复正常
+	EventRecovered = "recovered"
+	// EventASNChanged 获取到的IP的ASN变化, 如切换到了备用线路
+	EventASNChanged = "asn-changed"
+	// EventIPDamped 因最小更新间隔或来回变化暂缓更新IP
+	EventIPDamped = "ip-damped"
+	// EventProviderPaused DNS服务商连续失败, 暂停定时更新
+	EventProviderPaused = "provider-paused"
+	// EventStartup 启动
+	EventStartup = "startup"
+)
+
+// NotifyEvents 所有通知事件
+var NotifyEvents = []string{EventIPChanged, EventUpdateFailed, EventDetectionFailed, EventRecovered, EventASNChanged, EventIPDamped, EventProviderPaused, EventStartup}
+
+// 未选择通知事件时发送的事件
+var defaultNotifyEvents = []string{EventIPChanged, EventUpdateFailed, EventDetectionFailed, EventRecovered, EventASNChanged, EventIPDamped, EventProviderPaused}
+
+// 通知的级别
+const (
+	SeverityInfo  = "info"
+	SeverityError = "error"
+)
+
+// Notifier 通知渠道
+type Notifier interface {
+	// Channel 渠道名称
+	Channel() string
+	// Subscribed 是否发送该事件
+	Subscribed(event string) bool
+	// Send 发送通知. v4Status/v6Status为IPv4/IPv6的更新结果, events为本次的事件
+	Send(domains *Domains, v4Status updateStatusType, v6Status updateStatusType, events []string) NotifyResult
+}
+
+// notifierFactories 通知渠道, 根据配置创建, 未配置时返回nil. 新增渠道在此注册
+var notifierFactories = []func(conf *Config) Notifier{
+	newWebhookNotifier,
+}
+
+// GetNotifiers 获得已配置的通知渠道
+func GetNotifiers(conf *Config) (notifiers []Notifier) {
+	for _, factory := range notifierFactories {
+		if notifier := factory(conf); notifier != nil {
+			notifiers = append(notifiers, notifier)
+		}
+	}
+	return
+}
+
+// ExecNotify 发送本次更新的事件到订阅了的通知渠道
+func ExecNotify(ctx context.Context, domains *Domains, conf *Config) {
+	v4Status := getDomainsStatus(domains.Ipv4Domains)
+	v6Status := getDomainsStatus(domains.Ipv6Domains)
+	sendNotify(ctx, conf, domains, v4Status, v6Status, getNotifyEvents(domains))
+	if domains.ASNChanged {
+		clearASNChanged()
+	}
+	if domains.Damped {
+		clearDamped()
+	}
+}
+
+// NotifyStartup 发送启动事件
+func NotifyStartup(ctx context.Context, conf *Config) {
+	sendNotify(ctx, conf, &Domains{}, UpdatedNothing, UpdatedNothing, []string{EventStartup})
+}
+
+// sendNotify 发送到订阅了其中任一事件的通知渠道
+func sendNotify(ctx context.Context, conf *Config, domains *Domains, v4Status updateStatusType, v6Status updateStatusType, events []string) {
+	if len(events) == 0 {
+		return
+	}
+
+	for _, notifier := range GetNotifiers(conf) {
+		subscribed := []string{}
+		for _, event := range events {
+			if notifier.Subscribed(event) {
+				subscribed = append(subscribed, event)
+			}
+		}
+		if len(subscribed) == 0 {
+			continue
+		}
+		_, span := util.StartSpan(ctx, "notify "+notifier.Channel())
+		result := notifier.Send(domains, v4Status, v6Status, subscribed)
+		if result.Error == "" {
+			log.Printf("%s调用成功, 返回数据: %s\n", result.Channel, result.Response)
+		} else {
+			log.Printf("%s调用失败，Err：%s\n", result.Channel, result.Error)
+			span.SetError(result.Error)
+		}
+		span.End()
+	}
+}
+
+// getNotifyEvents 根据更新结果获得事件
+func getNotifyEvents(domains *Domains) (events []string) {
+	has := map[string]bool{}
+	check := func(recordType string) {
+		_, recordDomains := domains.GetNewIpResult(recordType)
+		for _, domain := range recordDomains {
+			switch {
+			case domain.UpdateStatus == UpdatedFailed && domains.IPFor(recordType, domain) == "":
+				has[EventDetectionFailed] = true
+			case domain.UpdateStatus == UpdatedFailed:
+				has[EventUpdateFailed] = true
+			case domain.UpdateStatus == UpdatedSuccess:
+				has[EventIPChanged] = true
+			}
+			if domain.Recovered {
+				has[EventRecovered] = true
+			}
+		}
+	}
+	check("A")
+	check("AAAA")
+	has[EventASNChanged] = domains.ASNChanged
+	has[EventIPDamped] = domains.Damped
+	has[EventProviderPaused] = !domains.PausedUntil.IsZero()
+
+	for _, event := range NotifyEvents {
+		if has[event] {
+			events = append(events, event)
+		}
+	}
+	return
+}
+
+// getNotifySeverity 有失败的事件时为error
+func getNotifySeverity(events []string) string {
+	for _, event := range events {
+		if event == EventUpdateFailed || event == EventDetectionFailed || event == EventProviderPaused {
+			return SeverityError
+		}
+	}
+	return SeverityInfo
+}
+
+// subscribedEvent 是否订阅了事件, 未选择时使用默认的事件
+func subscribedEvent(events []string, event string) bool {
+	if len(events) == 0 {
+		events = defaultNotifyEvents
+	}
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// TestNotify 使用模拟数据测试通知渠道
+func TestNotify(channel string, conf *Config) (result NotifyResult, err error) {
+	for _, notifier := range GetNotifiers(conf) {
+		if notifier.Channel() == channel {
+			return notifier.Send(fakeDomains(), UpdatedSuccess, UpdatedSuccess, []string{EventIPChanged}), nil
+		}
+	}
+	return result, fmt.Errorf("通知渠道 %s 未配置", channel)
+}
+
+// fakeDomains 用于测试通知的模拟数据
+func fakeDomains() *Domains {
+	var domains = make([]*Domain, 1)
+	domains[0] = &Domain{}
+	domains[0].DomainName = "example.com"
+	domains[0].SubDomain = "test"
+	domains[0].UpdateStatus = UpdatedSuccess
+
+	return &Domains{
+		Ipv4Addr:    "127.0.0.1",
+		Ipv4Domains: domains,
+		Ipv6Addr:    "::1",
+		Ipv6Domains: domains,
+	}
+}
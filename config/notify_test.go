@@ -0,0 +1,114 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestGetNotifyEvents 测试根据更新结果获得事件
+func TestGetNotifyEvents(t *testing.T) {
+	domains := &Domains{
+		Ipv4Addr: "1.1.1.1",
+		Ipv4Domains: []*Domain{
+			{DomainName: "example.com", SubDomain: "a", UpdateStatus: UpdatedSuccess, Recovered: true},
+			{DomainName: "example.com", SubDomain: "b", UpdateStatus: UpdatedFailed},
+		},
+		Ipv6Domains: []*Domain{{DomainName: "example.com", UpdateStatus: UpdatedFailed}},
+	}
+	events := getNotifyEvents(domains)
+	if !reflect.DeepEqual(events, []string{EventIPChanged, EventUpdateFailed, EventDetectionFailed, EventRecovered}) {
+		t.Errorf("事件不正确: %v", events)
+	}
+	if getNotifySeverity(events) != SeverityError || getNotifySeverity([]string{EventRecovered}) != SeverityInfo {
+		t.Error("级别不正确")
+	}
+
+	if len(getNotifyEvents(&Domains{Ipv4Addr: "1.1.1.1", Ipv4Domains: []*Domain{{UpdateStatus: UpdatedNothing}}})) != 0 {
+		t.Error("未改变时不应有事件")
+	}
+
+	paused := &Domains{Ipv4Addr: "1.1.1.1", Ipv4Domains: []*Domain{{UpdateStatus: UpdatedFailed}}, Damped: true, PausedUntil: time.Now()}
+	if events := getNotifyEvents(paused); !reflect.DeepEqual(events, []string{EventUpdateFailed, EventIPDamped, EventProviderPaused}) {
+		t.Errorf("暂缓更新及暂停定时更新的事件不正确: %v", events)
+	}
+}
+
+// TestWebhookSubscribed 测试Webhook只发送选择的事件
+func TestWebhookSubscribed(t *testing.T) {
+	webhook := &webhookNotifier{}
+	if !webhook.Subscribed(EventIPChanged) || webhook.Subscribed(EventStartup) {
+		t.Error("未选择时应发送除启动外的所有事件")
+	}
+
+	webhook.WebhookEvents = []string{EventUpdateFailed}
+	if webhook.Subscribed(EventIPChanged) || !webhook.Subscribed(EventUpdateFailed) {
+		t.Error("应只发送选择的事件")
+	}
+
+	para := replacePara(&Domains{}, "#{event} #{severity}", "", UpdatedFailed, UpdatedNothing, []string{EventUpdateFailed, EventRecovered})
+	if para != "update-failed,recovered error" {
+		t.Errorf("变量替换不正确: %s", para)
+	}
+}
+
+// TestProviderWebhook DNS服务商填写了Webhook时代替全局的Webhook
+func TestProviderWebhook(t *testing.T) {
+	conf := &Config{}
+	conf.WebhookURL = "https://example.com/global"
+	conf.WebhookEvents = []string{EventUpdateFailed}
+	webhook := newWebhookNotifier(conf).(*webhookNotifier)
+	if webhook.WebhookURL != "https://example.com/global" {
+		t.Errorf("应使用全局的Webhook: %s", webhook.WebhookURL)
+	}
+
+	conf.DNS.WebhookURL = "https://example.com/work"
+	conf.DNS.WebhookRequestBody = `{"text":"#{ipv4Addr}"}`
+	webhook = newWebhookNotifier(conf).(*webhookNotifier)
+	if webhook.WebhookURL != "https://example.com/work" || webhook.WebhookRequestBody != `{"text":"#{ipv4Addr}"}` {
+		t.Errorf("应使用DNS服务商的Webhook: %v", webhook.Webhook)
+	}
+	if !reflect.DeepEqual(webhook.WebhookEvents, []string{EventUpdateFailed}) {
+		t.Errorf("通知事件应使用全局的: %v", webhook.WebhookEvents)
+	}
+
+	conf.WebhookURL = ""
+	if newWebhookNotifier(conf) == nil {
+		t.Error("只填写了DNS服务商的Webhook时也应通知")
+	}
+}
+
+// TestRenderWebhook 测试Webhook的模板
+func TestRenderWebhook(t *testing.T) {
+	domains := &Domains{
+		Ipv4Addr: "1.1.1.1",
+		Ipv4Domains: []*Domain{
+			{DomainName: "example.com", SubDomain: "a", UpdateStatus: UpdatedSuccess, OldIP: "2.2.2.2"},
+			{DomainName: "example.com", SubDomain: "b", UpdateStatus: UpdatedFailed, Error: `Code: 1, Message: "x"`},
+		},
+	}
+	tmpl := `{"provider":"{{upper .Provider}}","old":"#{ipv4OldAddr}","error":{{json .Error}},` +
+		`"domains":[{{range $i, $d := .IPv4.Domains}}{{if $i}},{{end}}"{{$d.Name}} {{if eq $d.Result "成功"}}ok{{else}}{{$d.Error | lower}}{{end}}"{{end}}],` +
+		`"date":"{{.Time | date "2006"}}","events":"{{join .Events ","}}"}`
+	body, err := renderWebhook(tmpl, domains, "alidns", UpdatedFailed, UpdatedNothing, []string{EventIPChanged, EventUpdateFailed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"provider":"ALIDNS","old":"2.2.2.2","error":"Code: 1, Message: \"x\"",` +
+		`"domains":["a.example.com ok","b.example.com code: 1, message: "x""],` +
+		`"date":"` + time.Now().Format("2006") + `","events":"ip-changed,update-failed"}`
+	if body != want {
+		t.Errorf("模板结果不正确:\n%s\n%s", body, want)
+	}
+
+	// 不包含{{ }}时只替换变量
+	if body, _ := renderWebhook("#{provider} #{error}", domains, "alidns", UpdatedFailed, UpdatedNothing, nil); body != `alidns Code: 1, Message: "x"` {
+		t.Errorf("变量替换不正确: %s", body)
+	}
+	if _, err := renderWebhook("{{.Unknown}}", domains, "", UpdatedNothing, UpdatedNothing, nil); err == nil {
+		t.Error("不存在的变量应返回错误")
+	}
+	if _, err := parseWebhookTemplate("{{if}}"); err == nil {
+		t.Error("模板不正确时应返回错误")
+	}
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// GetTypeTailscale 获取IP方式: Tailscale分配给本机的IP, 通过tailscaled的本地接口获取
+const GetTypeTailscale = "tailscale"
+
+// GetTypeWireGuard 获取IP方式: WireGuard网卡的IP, 网卡名填写在NetInterface中. 与通过网卡获取不同, 私有地址也会使用
+const GetTypeWireGuard = "wireguard"
+
+// tailscaled本地接口的unix socket
+var tailscaleSocket = "/var/run/tailscale/tailscaled.sock"
+
+// Tailscale的网卡名, 本地接口不可用时(如Windows)从网卡获取
+var tailscaleInterfaces = []string{"tailscale0", "Tailscale"}
+
+// IsURLGetType 获取IP方式是否为通过接口获取
+func IsURLGetType(getType string) bool {
+	return getType == "url" || getType == ""
+}
+
+// getTailscaleIP 获得Tailscale的IPv4(100.64.0.0/10)或IPv6(fd7a:115c:a1e0::/48)
+func getTailscaleIP(ctx context.Context, ipType string, expression string) string {
+	ips, err := tailscaleIPs(ctx, tailscaleSocket)
+	if err != nil {
+		for _, name := range tailscaleInterfaces {
+			if ips, _ = interfaceIPs(name); len(ips) > 0 {
+				break
+			}
+		}
+		if len(ips) == 0 {
+			log.Printf("从Tailscale获得%s失败! %s\n", ipType, err)
+			return ""
+		}
+	}
+	if result := selectIP(expression, filterIPType(ipType, ips)); result != "" {
+		return result
+	}
+	log.Printf("Tailscale中没有%s地址\n", ipType)
+	return ""
+}
+
+// tailscaleIPs 从tailscaled的本地接口获得本机的IP
+func tailscaleIPs(ctx context.Context, socket string) ([]string, error) {
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	// tailscaled 只接受此Host
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://local-tailscaled.sock/localapi/v0/status?peers=false", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("本地接口返回状态码 %d", resp.StatusCode)
+	}
+	var status struct {
+		BackendState string
+		TailscaleIPs []string
+		Self         struct {
+			TailscaleIPs []string
+		}
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	if status.BackendState != "" && status.BackendState != "Running" {
+		return nil, fmt.Errorf("Tailscale未连接, 状态为 %s", status.BackendState)
+	}
+	if len(status.Self.TailscaleIPs) > 0 {
+		return status.Self.TailscaleIPs, nil
+	}
+	return status.TailscaleIPs, nil
+}
+
+// getWireGuardIP 获得WireGuard网卡的IP
+func getWireGuardIP(ipType string, name string, expression string) string {
+	ips, err := interfaceIPs(name)
+	if err != nil {
+		log.Printf("从WireGuard网卡获得%s失败! 网卡名: %s, %s\n", ipType, name, err)
+		return ""
+	}
+	if result := selectIP(expression, filterIPType(ipType, ips)); result != "" {
+		return result
+	}
+	log.Printf("WireGuard网卡 %s 中没有%s地址\n", name, ipType)
+	return ""
+}
+
+// interfaceIPs 已启用的网卡的IP, 包含私有地址, 不包含链路本地地址
+func interfaceIPs(name string) (ips []string, err error) {
+	netInterface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if netInterface.Flags&net.FlagUp == 0 {
+		return nil, fmt.Errorf("网卡 %s 未启用", name)
+	}
+	addrs, err := netInterface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range addrs {
+		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && !ipnet.IP.IsLinkLocalUnicast() {
+			ips = append(ips, ipnet.IP.String())
+		}
+	}
+	return ips, nil
+}
+
+// filterIPType 只保留IPv4或IPv6
+func filterIPType(ipType string, ips []string) (result []string) {
+	for _, s := range ips {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			continue
+		}
+		if (ip.To4() != nil) == (ipType == "IPv4") {
+			result = append(result, s)
+		}
+	}
+	return
+}
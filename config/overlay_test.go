@@ -0,0 +1,71 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestTailscaleIPs 从tailscaled的本地接口获得本机的IP
+func TestTailscaleIPs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows的tailscaled不使用unix socket")
+	}
+	dir, err := ioutil.TempDir("", "tailscale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "tailscaled.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := "Running"
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "local-tailscaled.sock" || r.URL.Path != "/localapi/v0/status" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"BackendState":"` + state + `","Self":{"TailscaleIPs":["100.101.102.103","fd7a:115c:a1e0::1"]}}`))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	ips, err := tailscaleIPs(context.Background(), socket)
+	if err != nil || len(ips) != 2 {
+		t.Fatalf("获得的IP %v 不正确: %v", ips, err)
+	}
+	if v4 := filterIPType("IPv4", ips); len(v4) != 1 || v4[0] != "100.101.102.103" {
+		t.Errorf("IPv4 %v 不正确", v4)
+	}
+	if v6 := filterIPType("IPv6", ips); len(v6) != 1 || v6[0] != "fd7a:115c:a1e0::1" {
+		t.Errorf("IPv6 %v 不正确", v6)
+	}
+
+	state = "Stopped"
+	if _, err := tailscaleIPs(context.Background(), socket); err == nil {
+		t.Error("Tailscale未连接时应返回错误")
+	}
+}
+
+// TestGetWireGuardIP 网卡不存在时返回空
+func TestGetWireGuardIP(t *testing.T) {
+	if ip := getWireGuardIP("IPv4", "ddns-go-wg-test", ""); ip != "" {
+		t.Errorf("网卡不存在时不应获得IP %s", ip)
+	}
+	errs := validateIP("IPv4", GetTypeWireGuard, "", "", []string{"a.example.com"})
+	if len(errs) != 1 {
+		t.Errorf("WireGuard未填写网卡时应校验失败: %v", errs)
+	}
+	if errs := validateIP("IPv4", GetTypeTailscale, "", "", []string{"a.example.com"}); len(errs) != 0 {
+		t.Errorf("Tailscale不需要填写网卡: %v", errs)
+	}
+}
@@ -0,0 +1,35 @@
+package config
+
+// IsProviderPaused 是否暂停了DNS服务商的更新
+func (conf Config) IsProviderPaused(provider string) bool {
+	for _, name := range conf.PausedProviders {
+		if name == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPaused 是否暂停了所有更新或DNS服务商的更新
+func (conf Config) IsPaused(provider string) bool {
+	return conf.Paused || conf.IsProviderPaused(provider)
+}
+
+// SetPaused 暂停或恢复更新, provider为空时暂停或恢复所有更新
+func (conf *Config) SetPaused(provider string, paused bool) {
+	if provider == "" {
+		conf.Paused = paused
+		return
+	}
+
+	var providers []string
+	for _, name := range conf.PausedProviders {
+		if name != provider {
+			providers = append(providers, name)
+		}
+	}
+	if paused {
+		providers = append(providers, provider)
+	}
+	conf.PausedProviders = providers
+}
@@ -0,0 +1,24 @@
+package config
+
+import "testing"
+
+// TestSetPaused 测试暂停及恢复更新
+func TestSetPaused(t *testing.T) {
+	conf := &Config{}
+	conf.SetPaused("cloudflare", true)
+	conf.SetPaused("cloudflare", true)
+	if !conf.IsPaused("cloudflare") || conf.IsPaused("alidns") || len(conf.PausedProviders) != 1 {
+		t.Fatal("暂停DNS服务商不正确")
+	}
+
+	conf.SetPaused("", true)
+	if !conf.IsPaused("alidns") {
+		t.Error("暂停所有更新后应全部暂停")
+	}
+
+	conf.SetPaused("", false)
+	conf.SetPaused("cloudflare", false)
+	if conf.IsPaused("cloudflare") || len(conf.PausedProviders) != 0 {
+		t.Error("恢复更新不正确")
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"log"
+	"strings"
+	"sync"
+)
+
+// GetTypeDynDNS2 获取IP方式: 由路由器通过DynDNS2协议(/nic/update)推送
+const GetTypeDynDNS2 = "dyndns2"
+
+// 路由器推送的IP, key为IPv4/IPv6
+var pushedIP = struct {
+	sync.Mutex
+	m map[string]string
+}{m: map[string]string{}}
+
+// SetPushedIP 保存路由器推送的IP, 返回与上次推送的是否不同
+func SetPushedIP(ipType string, ip string) (changed bool) {
+	pushedIP.Lock()
+	defer pushedIP.Unlock()
+
+	changed = pushedIP.m[ipType] != ip
+	pushedIP.m[ipType] = ip
+	return
+}
+
+// getPushedIP 获得路由器推送的IP, 重启后未推送时使用IP变化记录中最后的IP
+func getPushedIP(ipType string) string {
+	pushedIP.Lock()
+	ip := pushedIP.m[ipType]
+	pushedIP.Unlock()
+	if ip != "" {
+		return ip
+	}
+
+	ipHistory.Lock.Lock()
+	defer ipHistory.Lock.Unlock()
+	ipHistory.load()
+	if ip = ipHistory.lastIP[ipType]; ip == "" {
+		log.Printf("未收到路由器推送的%s地址, 请在路由器中配置DynDNS2: /nic/update\n", ipType)
+	}
+	return ip
+}
+
+// IsPushDomain 是否为获取IP方式为DynDNS2的域名, 不区分大小写
+func (conf *Config) IsPushDomain(hostname string) bool {
+	hostname = strings.ToLower(strings.TrimSpace(hostname))
+	check := func(enable bool, getType string, domains []string) bool {
+		if !enable || getType != GetTypeDynDNS2 {
+			return false
+		}
+		for _, line := range domains {
+			if domain, enabled := ParseDomainLine(line); enabled && strings.ToLower(domainName(domain)) == hostname {
+				return true
+			}
+		}
+		return false
+	}
+	return hostname != "" &&
+		(check(conf.Ipv4.Enable, conf.Ipv4.GetType, conf.Ipv4.Domains) ||
+			check(conf.Ipv6.Enable, conf.Ipv6.GetType, conf.Ipv6.Domains))
+}
@@ -0,0 +1,36 @@
+package config
+
+import "testing"
+
+// TestIsPushDomain 只有获取IP方式为DynDNS2的域名可推送
+func TestIsPushDomain(t *testing.T) {
+	var conf Config
+	conf.Ipv4.Enable = true
+	conf.Ipv4.GetType = GetTypeDynDNS2
+	conf.Ipv4.Domains = []string{" ddns.example.com"}
+	conf.Ipv6.Enable = true
+	conf.Ipv6.GetType = "url"
+	conf.Ipv6.Domains = []string{"v6.example.com"}
+
+	if !conf.IsPushDomain("DDNS.example.com") {
+		t.Error("ddns.example.com 应可推送")
+	}
+	for _, hostname := range []string{"v6.example.com", "other.example.com", ""} {
+		if conf.IsPushDomain(hostname) {
+			t.Errorf("%s 不应可推送", hostname)
+		}
+	}
+}
+
+// TestSetPushedIP 推送相同的IP时无变化
+func TestSetPushedIP(t *testing.T) {
+	if !SetPushedIP("IPv4", "192.0.2.1") {
+		t.Error("第一次推送应有变化")
+	}
+	if SetPushedIP("IPv4", "192.0.2.1") {
+		t.Error("推送相同的IP不应有变化")
+	}
+	if getPushedIP("IPv4") != "192.0.2.1" {
+		t.Error("获得推送的IP失败")
+	}
+}
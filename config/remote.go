@@ -0,0 +1,103 @@
+package config
+
+import (
+	"bytes"
+	"ddns-go/util"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ReadConfigFile 读取本地或远程的配置文件
+// 远程配置支持 http(s)://(如S3的公开或预签名URL), consul(s)://host:8500/key?token=, etcd(s)://host:2379/key
+func ReadConfigFile(path string) ([]byte, error) {
+	if !util.IsRemoteConfigPath(path) {
+		return ioutil.ReadFile(path)
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	client := http.Client{Timeout: 10 * time.Second}
+	switch u.Scheme {
+	case "consul", "consuls":
+		return readConsul(&client, u)
+	case "etcd", "etcds":
+		return readEtcd(&client, u)
+	}
+
+	resp, err := client.Get(path)
+	return util.GetHTTPResponseOrg(resp, u.Redacted(), err)
+}
+
+// readConsul 读取Consul KV, 使用 ?token= 作为ACL Token
+func readConsul(client *http.Client, u *url.URL) ([]byte, error) {
+	apiURL := url.URL{
+		Scheme:   schemeOf(u),
+		Host:     u.Host,
+		Path:     "/v1/kv/" + strings.TrimPrefix(u.Path, "/"),
+		RawQuery: "raw",
+	}
+	req, err := http.NewRequest("GET", apiURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := u.Query().Get("token"); token != "" {
+		req.Header.Set("X-Consul-Token", token)
+	}
+	resp, err := client.Do(req)
+	return util.GetHTTPResponseOrg(resp, apiURL.String(), err)
+}
+
+// readEtcd 通过etcd v3的HTTP接口读取
+func readEtcd(client *http.Client, u *url.URL) ([]byte, error) {
+	apiURL := url.URL{Scheme: schemeOf(u), Host: u.Host, Path: "/v3/kv/range"}
+	body, _ := json.Marshal(map[string]string{
+		"key": base64.StdEncoding.EncodeToString([]byte(u.Path)),
+	})
+	resp, err := client.Post(apiURL.String(), "application/json", bytes.NewReader(body))
+
+	var result struct {
+		Kvs []struct {
+			Value string
+		}
+	}
+	if err = util.GetHTTPResponse(resp, apiURL.String(), err, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Kvs) == 0 {
+		return nil, fmt.Errorf("etcd中不存在 %s", u.Path)
+	}
+	return base64.StdEncoding.DecodeString(result.Kvs[0].Value)
+}
+
+// schemeOf consuls/etcds使用https
+func schemeOf(u *url.URL) string {
+	if strings.HasSuffix(u.Scheme, "s") {
+		return "https"
+	}
+	return "http"
+}
+
+// watchRemoteConfig 定时读取远程配置, 内容变化时重新加载配置并调用onChange
+func watchRemoteConfig(path string, interval time.Duration, onChange func()) {
+	last, _ := ReadConfigFile(path)
+	for {
+		time.Sleep(interval)
+		byt, err := ReadConfigFile(path)
+		if err != nil || bytes.Equal(byt, last) {
+			continue
+		}
+		last = byt
+		log.Println("远程配置已修改, 重新加载配置")
+		ClearConfigCache()
+		onChange()
+	}
+}
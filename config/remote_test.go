@@ -0,0 +1,43 @@
+package config
+
+import (
+	"ddns-go/util"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestRemoteConfigRetry 读取远程配置失败时不一直缓存错误, 隔一段时间后重试
+func TestRemoteConfigRetry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ttl: \"600\"\n"))
+	}))
+	defer server.Close()
+	os.Setenv(util.ConfigFilePathENV, server.URL+"/config.yaml")
+	defer os.Unsetenv(util.ConfigFilePathENV)
+	ClearConfigCache()
+	defer ClearConfigCache()
+
+	if _, err := GetConfigCache(); err == nil {
+		t.Fatal("读取失败时应返回错误")
+	}
+	if _, err := GetConfigCache(); err == nil || requests != 1 {
+		t.Fatalf("重试间隔内应返回缓存的错误, 请求了%d次", requests)
+	}
+
+	cache.Lock.Lock()
+	cache.remoteErrTime = time.Now().Add(-remoteConfigRetryInterval)
+	cache.Lock.Unlock()
+	conf, err := GetConfigCache()
+	if err != nil || conf.TTL != "600" || requests != 2 {
+		t.Errorf("超过重试间隔后应重新读取: %v %q %d", err, conf.TTL, requests)
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// secretFilePrefix 从文件读取密钥的前缀, 如 file:///run/secrets/cf_token
+const secretFilePrefix = "file://"
+
+// 配置中的ID/Secret为空时, 从环境变量指定的文件中读取, 方便使用Docker/Kubernetes的secrets
+const (
+	DNSIDFileENV     = "DDNS_DNS_ID_FILE"
+	DNSSecretFileENV = "DDNS_DNS_SECRET_FILE"
+)
+
+// IsSecretFile 是否为密钥文件的引用
+func IsSecretFile(value string) bool {
+	return strings.HasPrefix(value, secretFilePrefix)
+}
+
+// resolveSecret 读取文件引用的密钥, 去掉首尾的空白字符
+func resolveSecret(value string, env string) (string, error) {
+	path := ""
+	if IsSecretFile(value) {
+		path = strings.TrimPrefix(value, secretFilePrefix)
+	} else if value == "" {
+		path = os.Getenv(env)
+	}
+	if path == "" {
+		return value, nil
+	}
+
+	byt, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取密钥文件 %s 失败: %s", path, err)
+	}
+	return strings.TrimSpace(string(byt)), nil
+}
+
+// Resolved 返回读取文件引用后的DNS配置, 不修改原配置
+func (dnsConf DNSConfig) Resolved() (DNSConfig, error) {
+	var err error
+	if dnsConf.ID, err = resolveSecret(dnsConf.ID, DNSIDFileENV); err != nil {
+		return dnsConf, err
+	}
+	if dnsConf.Secret, err = resolveSecret(dnsConf.Secret, DNSSecretFileENV); err != nil {
+		return dnsConf, err
+	}
+	return dnsConf, nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestResolved 测试从文件读取密钥
+func TestResolved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddns-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "secret")
+	if err = ioutil.WriteFile(path, []byte("token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv(DNSIDFileENV, path)
+	defer os.Unsetenv(DNSIDFileENV)
+
+	dnsConf, err := DNSConfig{Secret: secretFilePrefix + path}.Resolved()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dnsConf.ID != "token" || dnsConf.Secret != "token" {
+		t.Errorf("读取密钥文件失败: %+v", dnsConf)
+	}
+
+	dnsConf, _ = DNSConfig{ID: "id"}.Resolved()
+	if dnsConf.ID != "id" {
+		t.Error("已配置ID时不应使用环境变量")
+	}
+
+	if _, err = (DNSConfig{Secret: secretFilePrefix + filepath.Join(dir, "none")}).Resolved(); err == nil {
+		t.Error("密钥文件不存在时应返回错误")
+	}
+}
@@ -0,0 +1,39 @@
+//go:build ignore
+// +build ignore
+
+// 发布接口列表时签名 endpoints.json, 生成 endpoints.json.sig
+// 用法: DDNS_GO_ENDPOINTS_KEY=<base64私钥> go run config/sign_endpoints.go
+// 私钥由本仓库的维护者保管, 不提交到仓库. 对应的公钥为 endpoints.go 中的 endpointsPublicKey
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+func main() {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(os.Getenv("DDNS_GO_ENDPOINTS_KEY")))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		log.Fatal("请在环境变量 DDNS_GO_ENDPOINTS_KEY 中设置base64编码的ed25519私钥")
+	}
+	_, file, _, _ := runtime.Caller(0)
+	path := filepath.Join(filepath.Dir(file), "endpoints.json")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sig := ed25519.Sign(ed25519.PrivateKey(key), data)
+	if !ed25519.Verify(ed25519.PrivateKey(key).Public().(ed25519.PublicKey), data, sig) {
+		log.Fatal("签名失败")
+	}
+	if err := ioutil.WriteFile(path+".sig", []byte(base64.StdEncoding.EncodeToString(sig)), 0644); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("已签名", path)
+}
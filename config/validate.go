@@ -0,0 +1,221 @@
+package config
+
+import (
+	"ddns-go/util"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Validate 校验配置中的必填项及格式, 不校验DNS服务商
+func (conf *Config) Validate() (errs []error) {
+	if !conf.Ipv4.Enable && !conf.Ipv6.Enable {
+		errs = append(errs, fmt.Errorf("未启用IPv4或IPv6"))
+	}
+	if conf.Ipv4.Enable {
+		errs = append(errs, validateIP("IPv4", conf.Ipv4.GetType, conf.Ipv4.URL, conf.Ipv4.NetInterface, conf.Ipv4.Domains)...)
+		switch conf.Ipv4.CGNAT {
+		case CGNATWarn, CGNATSkip, CGNATFallback, CGNATIgnore:
+		default:
+			errs = append(errs, fmt.Errorf("运营商级NAT的处理方式 %s 不正确", conf.Ipv4.CGNAT))
+		}
+	}
+	if conf.Ipv6.Enable {
+		errs = append(errs, validateIP("IPv6", conf.Ipv6.GetType, conf.Ipv6.URL, conf.Ipv6.NetInterface, conf.Ipv6.Domains)...)
+	}
+	for _, source := range []string{conf.Ipv4.Expression, conf.Ipv6.Expression} {
+		if source == "" {
+			continue
+		}
+		if _, err := CompileIPExpr(source); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, ptr := range []string{conf.Ipv4.PTR, conf.Ipv6.PTR} {
+		if ptr == "" {
+			continue
+		}
+		if name, params := SplitDomainParams(ptr); params != "" || ParseDomain(name) == nil {
+			errs = append(errs, fmt.Errorf("PTR记录指向的域名 %s 不正确", ptr))
+		}
+	}
+
+	if conf.TTL != "" {
+		if ttl, err := strconv.Atoi(conf.TTL); err != nil || ttl < 1 {
+			errs = append(errs, fmt.Errorf("TTL %s 不正确", conf.TTL))
+		}
+	}
+	if conf.Concurrency < 0 || conf.Concurrency > MaxConcurrency {
+		errs = append(errs, fmt.Errorf("同时更新的域名数 %d 不正确, 需为0-%d", conf.Concurrency, MaxConcurrency))
+	}
+	if conf.MinUpdateInterval < 0 {
+		errs = append(errs, fmt.Errorf("最小更新间隔 %d 不正确", conf.MinUpdateInterval))
+	}
+	for _, event := range conf.WebhookEvents {
+		if _, ok := notifyEventTitles[event]; !ok {
+			errs = append(errs, fmt.Errorf("不支持的通知事件 %s", event))
+		}
+	}
+	if conf.HistoryDays < 0 {
+		errs = append(errs, fmt.Errorf("历史记录保留天数 %d 不正确", conf.HistoryDays))
+	}
+	if conf.Cron != "" {
+		if _, err := util.ParseCron(conf.Cron); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, windows := range []string{conf.UpdateWindows, conf.NoUpdateWindows} {
+		if _, err := util.ParseTimeWindows(windows); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, webhookURL := range []string{conf.WebhookURL, conf.DNS.WebhookURL} {
+		if webhookURL != "" && !isHTTPURL(webhookURL) {
+			errs = append(errs, fmt.Errorf("Webhook URL %s 不正确", webhookURL))
+		}
+	}
+	for _, text := range []string{conf.WebhookURL, conf.WebhookRequestBody, conf.DNS.WebhookURL, conf.DNS.WebhookRequestBody} {
+		if isWebhookTemplate(text) {
+			if _, err := parseWebhookTemplate(text); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err := conf.HTTPClient.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, proxy := range []string{conf.Proxy, conf.DNS.Proxy} {
+		if err := util.CheckProxy(proxy); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := util.CheckIPVersion(conf.DNS.IPVersion); err != nil {
+		errs = append(errs, err)
+	}
+	if err := util.CheckBind(conf.DNS.Bind); err != nil {
+		errs = append(errs, err)
+	}
+	if _, _, err := util.ParseRateLimit(conf.DNS.RateLimit); err != nil {
+		errs = append(errs, err)
+	}
+	for _, resolver := range []string{conf.Resolver, conf.BootstrapResolver} {
+		if resolver == "" {
+			continue
+		}
+		if _, err := util.ParseResolver(resolver); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, ranges := range [][]string{conf.AllowIPRanges, conf.DenyIPRanges} {
+		if _, err := util.ParseIPRanges(ranges); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if _, err := util.ParseHosts(conf.Hosts); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.GeoIPURL != "" {
+		if err := CheckGeoIPURL(conf.GeoIPURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if conf.MQTTURL != "" {
+		if _, err := util.ParseMQTTURL(conf.MQTTURL); err != nil {
+			errs = append(errs, err)
+		} else if conf.MQTTTopic == "" {
+			errs = append(errs, fmt.Errorf("MQTT主题不能为空"))
+		}
+	}
+	if _, err := conf.DNS.Resolved(); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.DNS.Name == "exec" {
+		if err := CheckExecPath(conf.DNS.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return
+}
+
+// CheckExecPath 校验自定义程序, 需为绝对路径的可执行文件
+func CheckExecPath(path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("自定义程序 %s 需为绝对路径", path)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("自定义程序 %s 不存在", path)
+	}
+	if !fi.Mode().IsRegular() || (runtime.GOOS != "windows" && fi.Mode()&0111 == 0) {
+		return fmt.Errorf("自定义程序 %s 不是可执行文件", path)
+	}
+	return nil
+}
+
+// validateIP 校验IPv4/IPv6的获取方式及域名
+func validateIP(ipType string, getType string, ipURL string, netInterface string, domainArr []string) (errs []error) {
+	switch getType {
+	case "netInterface", GetTypeWireGuard:
+		if netInterface == "" {
+			errs = append(errs, fmt.Errorf("%s 未选择网卡", ipType))
+		}
+	case GetTypeDynDNS2, GetTypeAuto, GetTypeTailscale:
+	case "url", "":
+		urls := SplitIPURLs(ipURL)
+		if len(urls) == 0 {
+			errs = append(errs, fmt.Errorf("%s 获取IP的接口 %s 不正确", ipType, ipURL))
+		}
+		for _, u := range urls {
+			if !isHTTPURL(u) {
+				errs = append(errs, fmt.Errorf("%s 获取IP的接口 %s 不正确", ipType, u))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s 获取IP方式 %s 不支持", ipType, getType))
+	}
+
+	count := 0
+	for _, domainStr := range domainArr {
+		// 停用的域名也需校验, 启用时不会出错
+		domainStr, _ = ParseDomainLine(domainStr)
+		domainStr, params := SplitDomainParams(domainStr)
+		if domainStr == "" {
+			continue
+		}
+		count++
+		if !isDomain(domainStr) {
+			errs = append(errs, fmt.Errorf("%s 域名 %s 不正确", ipType, domainStr))
+		}
+		if _, err := parseDomainSource(params); err != nil {
+			errs = append(errs, fmt.Errorf("%s 域名 %s: %s", ipType, domainStr, err))
+		}
+	}
+	if count == 0 {
+		errs = append(errs, fmt.Errorf("%s 未填写域名", ipType))
+	}
+	return
+}
+
+// isDomain 至少包含主域名及后缀, 如 example.com
+func isDomain(domainStr string) bool {
+	labels := strings.Split(domainStr, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// isHTTPURL 是否为http/https地址
+func isHTTPURL(str string) bool {
+	u, err := url.Parse(str)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
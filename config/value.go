@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// GetValue 获得配置项的值, key为YAML中的路径(不区分大小写), 如 dns.name, ipv4.domains
+func (conf *Config) GetValue(key string) (interface{}, error) {
+	m, err := conf.toMap()
+	if err != nil {
+		return nil, err
+	}
+	parent, name, err := findValue(m, key)
+	if err != nil {
+		return nil, err
+	}
+	return parent[name], nil
+}
+
+// SetValue 修改配置项的值, 只能修改字符串、布尔值及字符串列表(逗号分隔)
+func (conf *Config) SetValue(key string, value string) error {
+	m, err := conf.toMap()
+	if err != nil {
+		return err
+	}
+	parent, name, err := findValue(m, key)
+	if err != nil {
+		return err
+	}
+
+	switch old := parent[name].(type) {
+	case string:
+		parent[name] = value
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s 的值只能为 true/false", key)
+		}
+		parent[name] = b
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s 的值只能为整数", key)
+		}
+		parent[name] = n
+	case []interface{}:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		if len(old) > 0 {
+			if _, ok := old[0].(string); !ok {
+				return fmt.Errorf("不支持修改 %s", key)
+			}
+		}
+		parent[name] = list
+	default:
+		return fmt.Errorf("不支持修改 %s", key)
+	}
+
+	byt, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	var newConf Config
+	if err = yaml.UnmarshalStrict(byt, &newConf); err != nil {
+		return err
+	}
+	*conf = newConf
+	return nil
+}
+
+// AddDomain 添加IPv4/IPv6域名, 已存在时不重复添加
+func (conf *Config) AddDomain(ipType string, domain string) error {
+	domains, err := conf.domainsOf(ipType)
+	if err != nil {
+		return err
+	}
+	for _, d := range *domains {
+		if d, _ = ParseDomainLine(d); d == domain || domainName(d) == domain {
+			return nil
+		}
+	}
+	*domains = append(*domains, domain)
+	return nil
+}
+
+// RemoveDomain 删除IPv4/IPv6域名
+func (conf *Config) RemoveDomain(ipType string, domain string) error {
+	domains, err := conf.domainsOf(ipType)
+	if err != nil {
+		return err
+	}
+	var result []string
+	for _, d := range *domains {
+		if name, _ := ParseDomainLine(d); name != domain && domainName(name) != domain {
+			result = append(result, d)
+		}
+	}
+	if len(result) == len(*domains) {
+		return fmt.Errorf("未找到域名 %s", domain)
+	}
+	*domains = result
+	return nil
+}
+
+// EnableDomain 启用或停用IPv4/IPv6域名, 停用时在域名前加#, 不删除
+func (conf *Config) EnableDomain(ipType string, domain string, enable bool) error {
+	domains, err := conf.domainsOf(ipType)
+	if err != nil {
+		return err
+	}
+	for i, d := range *domains {
+		if name, _ := ParseDomainLine(d); name == domain || domainName(name) == domain {
+			if enable {
+				(*domains)[i] = name
+			} else {
+				(*domains)[i] = DisabledDomainPrefix + name
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("未找到域名 %s", domain)
+}
+
+// domainName 去掉参数后的域名
+func domainName(line string) string {
+	name, _ := SplitDomainParams(line)
+	return name
+}
+
+// domainsOf 获得IPv4/IPv6的域名列表
+func (conf *Config) domainsOf(ipType string) (*[]string, error) {
+	switch strings.ToLower(ipType) {
+	case "ipv4":
+		return &conf.Ipv4.Domains, nil
+	case "ipv6":
+		return &conf.Ipv6.Domains, nil
+	}
+	return nil, fmt.Errorf("类型 %s 不正确, 只能为 ipv4/ipv6", ipType)
+}
+
+// toMap 转换为YAML中的结构
+func (conf *Config) toMap() (m map[interface{}]interface{}, err error) {
+	byt, err := yaml.Marshal(conf)
+	if err != nil {
+		return
+	}
+	err = yaml.Unmarshal(byt, &m)
+	return
+}
+
+// findValue 查找配置项, 返回所在的map及名称
+func findValue(m map[interface{}]interface{}, key string) (map[interface{}]interface{}, string, error) {
+	parts := strings.Split(strings.ToLower(key), ".")
+	for i, part := range parts {
+		value, ok := m[part]
+		if !ok {
+			return nil, "", fmt.Errorf("配置项 %s 不存在", key)
+		}
+		if i == len(parts)-1 {
+			return m, part, nil
+		}
+		if m, ok = value.(map[interface{}]interface{}); !ok {
+			return nil, "", fmt.Errorf("配置项 %s 不存在", key)
+		}
+	}
+	return nil, "", fmt.Errorf("配置项 %s 不存在", key)
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+)
+
+// TestSetValue 测试修改配置项
+func TestSetValue(t *testing.T) {
+	conf := &Config{}
+	if err := conf.SetValue("dns.name", "cloudflare"); err != nil || conf.DNS.Name != "cloudflare" {
+		t.Error("修改dns.name失败", err)
+	}
+	if err := conf.SetValue("Ipv4.Enable", "true"); err != nil || !conf.Ipv4.Enable {
+		t.Error("修改ipv4.enable失败", err)
+	}
+	if err := conf.SetValue("webhook.webhookurl", "https://example.com"); err != nil || conf.WebhookURL != "https://example.com" {
+		t.Error("修改webhook.webhookurl失败", err)
+	}
+	if err := conf.SetValue("ipv4.enable", "yes"); err == nil {
+		t.Error("布尔值不正确时应返回错误")
+	}
+	if err := conf.SetValue("minupdateinterval", "600"); err != nil || conf.MinUpdateInterval != 600 {
+		t.Error("修改minupdateinterval失败", err)
+	}
+	if err := conf.SetValue("minupdateinterval", "10m"); err == nil {
+		t.Error("整数不正确时应返回错误")
+	}
+	if err := conf.SetValue("dns.none", "x"); err == nil {
+		t.Error("配置项不存在时应返回错误")
+	}
+
+	conf.AddDomain("ipv4", "a.example.com")
+	conf.AddDomain("ipv4", "a.example.com")
+	if v, _ := conf.GetValue("ipv4.domains"); len(v.([]interface{})) != 1 {
+		t.Errorf("添加域名失败: %v", v)
+	}
+	if err := conf.EnableDomain("ipv4", "a.example.com", false); err != nil || conf.Ipv4.Domains[0] != "#a.example.com" {
+		t.Errorf("停用域名失败: %v %v", conf.Ipv4.Domains, err)
+	}
+	conf.AddDomain("ipv4", "a.example.com")
+	if len(conf.Ipv4.Domains) != 1 {
+		t.Errorf("已停用的域名不应重复添加: %v", conf.Ipv4.Domains)
+	}
+	if err := conf.EnableDomain("ipv4", "a.example.com", true); err != nil || conf.Ipv4.Domains[0] != "a.example.com" {
+		t.Errorf("启用域名失败: %v %v", conf.Ipv4.Domains, err)
+	}
+	if err := conf.RemoveDomain("ipv4", "a.example.com"); err != nil || len(conf.Ipv4.Domains) != 0 {
+		t.Error("删除域名失败", err)
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"ddns-go/util"
+	"log"
+	"os"
+	"time"
+)
+
+// WatchConfigFile 定时检查配置文件是否被外部修改(如GitOps)或切换了配置方案, 变化时重新加载配置并调用onChange
+// 网页中保存的配置不会触发
+// 远程配置每分钟读取一次
+func WatchConfigFile(interval time.Duration, onChange func()) {
+	configFilePath := util.GetConfigFilePath()
+	if util.IsRemoteConfigPath(configFilePath) {
+		watchRemoteConfig(configFilePath, time.Minute, onChange)
+		return
+	}
+
+	cache.Lock.Lock()
+	if fi, err := os.Stat(configFilePath); err == nil && cache.modTime.IsZero() {
+		cache.filePath = configFilePath
+		cache.modTime = fi.ModTime()
+	}
+	cache.Lock.Unlock()
+
+	var watcher fileWatcher
+	for {
+		time.Sleep(interval)
+		util.RefreshProfile()
+		if watcher.changed(util.GetConfigFilePath()) {
+			log.Println("配置文件已修改, 重新加载配置")
+			onChange()
+		}
+	}
+}
+
+// fileWatcher 配置文件变化后等到下一次检查时不再变化才重新加载, 避免读取到正在写入的文件
+type fileWatcher struct {
+	pending bool
+	modTime time.Time
+	size    int64
+}
+
+// changed 配置文件路径或修改时间是否变化且已不再变化, 变化时清空配置缓存
+// 配置文件被删除时保留当前的配置, 重新创建后再加载
+func (w *fileWatcher) changed(configFilePath string) bool {
+	fi, err := os.Stat(configFilePath)
+	if err != nil {
+		w.pending = false
+		return false
+	}
+
+	cache.Lock.Lock()
+	defer cache.Lock.Unlock()
+
+	if configFilePath == cache.filePath && fi.ModTime().Equal(cache.modTime) {
+		w.pending = false
+		return false
+	}
+	if !w.pending || !fi.ModTime().Equal(w.modTime) || fi.Size() != w.size {
+		w.pending, w.modTime, w.size = true, fi.ModTime(), fi.Size()
+		return false
+	}
+	w.pending = false
+	cache.filePath = configFilePath
+	cache.modTime = fi.ModTime()
+	cache.ConfigSingle = nil
+	return true
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"ddns-go/util"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWatchConfigFile 测试外部修改配置文件后重新加载
+func TestWatchConfigFile(t *testing.T) {
+	configFilePath := filepath.Join(t.TempDir(), "config.yaml")
+	os.Setenv(util.ConfigFilePathENV, configFilePath)
+	defer os.Unsetenv(util.ConfigFilePathENV)
+	defer ClearConfigCache()
+
+	conf := &Config{TTL: "600"}
+	if err := conf.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now()
+	write := func(ttl string) {
+		modTime = modTime.Add(time.Second)
+		if err := ioutil.WriteFile(configFilePath, []byte("ttl: \""+ttl+"\"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(configFilePath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ttl := func() string {
+		conf, err := GetConfigCache()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conf.TTL
+	}
+
+	var watcher fileWatcher
+	if watcher.changed(configFilePath) {
+		t.Fatal("网页中保存的配置不应重新加载")
+	}
+
+	write("300")
+	if watcher.changed(configFilePath) {
+		t.Error("修改后应等到下一次检查再加载")
+	}
+	if !watcher.changed(configFilePath) || ttl() != "300" {
+		t.Errorf("修改后应重新加载, TTL: %s", ttl())
+	}
+	if watcher.changed(configFilePath) {
+		t.Error("没有修改时不应重新加载")
+	}
+
+	// 仍在写入时继续等待
+	write("120")
+	watcher.changed(configFilePath)
+	write("60")
+	if watcher.changed(configFilePath) {
+		t.Error("期间又修改了, 应继续等待")
+	}
+	if !watcher.changed(configFilePath) || ttl() != "60" {
+		t.Errorf("不再修改后应重新加载, TTL: %s", ttl())
+	}
+
+	// 删除后保留当前的配置, 重新创建后加载
+	if err := os.Remove(configFilePath); err != nil {
+		t.Fatal(err)
+	}
+	if watcher.changed(configFilePath) || ttl() != "60" {
+		t.Error("删除后应保留当前的配置")
+	}
+	write("30")
+	watcher.changed(configFilePath)
+	if !watcher.changed(configFilePath) || ttl() != "30" {
+		t.Errorf("重新创建后应重新加载, TTL: %s", ttl())
+	}
+}
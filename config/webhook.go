@@ -4,17 +4,18 @@ import (
 	"ddns-go/util"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
-	"time"
 )
 
 // Webhook Webhook
 type Webhook struct {
 	WebhookURL         string
 	WebhookRequestBody string
+	// 发送的事件, 为空时发送除启动外的所有事件
+	WebhookEvents []string
 }
 
 // updateStatusType 更新状态
@@ -29,46 +30,114 @@ const (
 	UpdatedSuccess = "成功"
 )
 
-// ExecWebhook 添加或更新IPv4/IPv6记录
-func ExecWebhook(domains *Domains, conf *Config) {
-	v4Status := getDomainsStatus(domains.Ipv4Domains)
-	v6Status := getDomainsStatus(domains.Ipv6Domains)
-
-	if conf.WebhookURL != "" && (v4Status != UpdatedNothing || v6Status != UpdatedNothing) {
-		// 成功和失败都要触发webhook
-		method := "GET"
-		postPara := ""
-		contentType := "application/x-www-form-urlencoded"
-		if conf.WebhookRequestBody != "" {
-			method = "POST"
-			postPara = replacePara(domains, conf.WebhookRequestBody, v4Status, v6Status)
-			if json.Valid([]byte(postPara)) {
-				contentType = "application/json"
-			}
-		}
-		requestURL := replacePara(domains, conf.WebhookURL, v4Status, v6Status)
-		u, err := url.Parse(requestURL)
-		if err != nil {
-			log.Println("Webhook配置中的URL不正确")
-			return
-		}
-		req, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s?%s", u.Scheme, u.Host, u.Path, u.Query().Encode()), strings.NewReader(postPara))
+// NotifyEventOption 网页中显示的通知事件
+type NotifyEventOption struct {
+	Name    string
+	Title   string
+	Checked bool
+}
+
+// 通知事件的名称
+var notifyEventTitles = map[string]string{
+	EventIPChanged:       "IP变化",
+	EventUpdateFailed:    "更新失败",
+	EventDetectionFailed: "获取IP失败",
+	EventRecovered:       "恢复正常",
+	EventASNChanged:      "ASN变化",
+	EventIPDamped:        "暂缓更新IP",
+	EventProviderPaused:  "暂停定时更新",
+	EventStartup:         "启动",
+}
+
+// WebhookEventOptions 网页中显示的Webhook通知事件
+func (webhook Webhook) WebhookEventOptions() (options []NotifyEventOption) {
+	for _, event := range NotifyEvents {
+		options = append(options, NotifyEventOption{
+			Name:    event,
+			Title:   notifyEventTitles[event],
+			Checked: subscribedEvent(webhook.WebhookEvents, event),
+		})
+	}
+	return
+}
+
+// webhookNotifier Webhook通知
+type webhookNotifier struct {
+	Webhook
+	// DNS服务商的名称, 用于#{provider}
+	provider string
+}
+
+// newWebhookNotifier 填写了URL时创建Webhook通知, DNS服务商填写了Webhook时使用DNS服务商的
+func newWebhookNotifier(conf *Config) Notifier {
+	webhook := conf.Webhook
+	if conf.DNS.WebhookURL != "" {
+		webhook.WebhookURL = conf.DNS.WebhookURL
+		webhook.WebhookRequestBody = conf.DNS.WebhookRequestBody
+	}
+	if webhook.WebhookURL == "" {
+		return nil
+	}
+	return &webhookNotifier{Webhook: webhook, provider: conf.DNS.Name}
+}
+
+// Channel 渠道名称
+func (webhook *webhookNotifier) Channel() string {
+	return "Webhook"
+}
+
+// Subscribed 是否发送该事件
+func (webhook *webhookNotifier) Subscribed(event string) bool {
+	return subscribedEvent(webhook.WebhookEvents, event)
+}
+
+// Send 调用Webhook
+func (webhook *webhookNotifier) Send(domains *Domains, v4Status updateStatusType, v6Status updateStatusType, events []string) (result NotifyResult) {
+	result.Channel = webhook.Channel()
+
+	method := "GET"
+	postPara := ""
+	contentType := "application/x-www-form-urlencoded"
+	if webhook.WebhookRequestBody != "" {
+		method = "POST"
+		var err error
+		postPara, err = renderWebhook(webhook.WebhookRequestBody, domains, webhook.provider, v4Status, v6Status, events)
 		if err != nil {
-			log.Println("创建Webhook请求异常, Err:", err)
+			result.Error = err.Error()
 			return
 		}
-		req.Header.Add("content-type", contentType)
-
-		clt := http.Client{}
-		clt.Timeout = 30 * time.Second
-		resp, err := clt.Do(req)
-		body, err := util.GetHTTPResponseOrg(resp, requestURL, err)
-		if err == nil {
-			log.Println(fmt.Sprintf("Webhook调用成功, 返回数据: %s", string(body)))
-		} else {
-			log.Println(fmt.Sprintf("Webhook调用失败，Err：%s", err))
+		if json.Valid([]byte(postPara)) {
+			contentType = "application/json"
 		}
 	}
+	requestURL, err := renderWebhook(webhook.WebhookURL, domains, webhook.provider, v4Status, v6Status, events)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		result.Error = "Webhook配置中的URL不正确"
+		return
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s?%s", u.Scheme, u.Host, u.Path, u.Query().Encode()), strings.NewReader(postPara))
+	if err != nil {
+		result.Error = fmt.Sprintf("创建Webhook请求异常, Err: %s", err)
+		return
+	}
+	req.Header.Add("content-type", contentType)
+
+	clt := util.HTTPClient(util.URLSecrets(requestURL)...)
+	resp, err := clt.Do(req)
+	if resp != nil {
+		result.StatusCode = resp.StatusCode
+	}
+	body, err := util.GetHTTPResponseOrg(resp, util.RedactURLString(requestURL), err)
+	result.Response = string(body)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return
 }
 
 // getDomainsStr 用逗号分割域名
@@ -92,14 +161,34 @@ func getDomainsStatus(domains []*Domain) updateStatusType {
 }
 
 // replacePara 替换参数
-func replacePara(domains *Domains, orgPara string, ipv4Result updateStatusType, ipv6Result updateStatusType) (newPara string) {
+func replacePara(domains *Domains, orgPara string, provider string, ipv4Result updateStatusType, ipv6Result updateStatusType, events []string) (newPara string) {
+	orgPara = strings.ReplaceAll(orgPara, "#{event}", strings.Join(events, ","))
+	orgPara = strings.ReplaceAll(orgPara, "#{severity}", getNotifySeverity(events))
+	orgPara = strings.ReplaceAll(orgPara, "#{provider}", provider)
+	orgPara = strings.ReplaceAll(orgPara, "#{error}", getDomainsError(append(append([]*Domain{}, domains.Ipv4Domains...), domains.Ipv6Domains...)))
+	pausedUntil := ""
+	if !domains.PausedUntil.IsZero() {
+		pausedUntil = util.FormatTime(domains.PausedUntil)
+	}
+	orgPara = strings.ReplaceAll(orgPara, "#{pausedUntil}", pausedUntil)
+
 	orgPara = strings.ReplaceAll(orgPara, "#{ipv4Addr}", domains.Ipv4Addr)
+	orgPara = strings.ReplaceAll(orgPara, "#{ipv4OldAddr}", getOldAddr(domains.Ipv4Domains))
 	orgPara = strings.ReplaceAll(orgPara, "#{ipv4Result}", string(ipv4Result))
 	orgPara = strings.ReplaceAll(orgPara, "#{ipv4Domains}", getDomainsStr(domains.Ipv4Domains))
+	orgPara = strings.ReplaceAll(orgPara, "#{ipv4FailCount}", strconv.Itoa(getFailCount(domains.Ipv4Domains)))
+	orgPara = strings.ReplaceAll(orgPara, "#{ipv4Country}", domains.Ipv4Info.Country)
+	orgPara = strings.ReplaceAll(orgPara, "#{ipv4ASN}", domains.Ipv4Info.ASN)
+	orgPara = strings.ReplaceAll(orgPara, "#{ipv4Org}", domains.Ipv4Info.Org)
 
 	orgPara = strings.ReplaceAll(orgPara, "#{ipv6Addr}", domains.Ipv6Addr)
+	orgPara = strings.ReplaceAll(orgPara, "#{ipv6OldAddr}", getOldAddr(domains.Ipv6Domains))
 	orgPara = strings.ReplaceAll(orgPara, "#{ipv6Result}", string(ipv6Result))
 	orgPara = strings.ReplaceAll(orgPara, "#{ipv6Domains}", getDomainsStr(domains.Ipv6Domains))
+	orgPara = strings.ReplaceAll(orgPara, "#{ipv6FailCount}", strconv.Itoa(getFailCount(domains.Ipv6Domains)))
+	orgPara = strings.ReplaceAll(orgPara, "#{ipv6Country}", domains.Ipv6Info.Country)
+	orgPara = strings.ReplaceAll(orgPara, "#{ipv6ASN}", domains.Ipv6Info.ASN)
+	orgPara = strings.ReplaceAll(orgPara, "#{ipv6Org}", domains.Ipv6Info.Org)
 
 	return orgPara
 }
@@ -116,3 +205,13 @@ func getDomainsStr(domains []*Domain) string {
 
 	return str
 }
+
+// getFailCount 获得域名中最多的连续失败次数
+func getFailCount(domains []*Domain) (count int) {
+	for _, v46 := range domains {
+		if v46.FailCount > count {
+			count = v46.FailCount
+		}
+	}
+	return
+}
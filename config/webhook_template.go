@@ -0,0 +1,149 @@
+package config
+
+import (
+	"bytes"
+	"ddns-go/util"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// webhookTemplateData Webhook中使用模板(包含{{ }})时可使用的变量
+type webhookTemplateData struct {
+	// 本次的事件
+	Events   []string
+	Severity string
+	Provider string
+	Time     time.Time
+	// 失败的原因, 多个以; 分割
+	Error string
+	IPv4  webhookIPData
+	IPv6  webhookIPData
+}
+
+// webhookIPData IPv4/IPv6的更新结果
+type webhookIPData struct {
+	Addr string
+	// 之前的IP, 第一个有记录的域名的IP
+	OldAddr   string
+	Result    string
+	FailCount int
+	Domains   []webhookDomainData
+	// IP的归属, 如 {{.IPv4.ASN}}
+	IPInfo
+}
+
+// webhookDomainData 域名的更新结果
+type webhookDomainData struct {
+	Name      string
+	OldIP     string
+	Result    string
+	FailCount int
+	Recovered bool
+	Error     string
+}
+
+// webhookFuncs Webhook模板中可使用的函数
+var webhookFuncs = template.FuncMap{
+	// JSON编码, 字符串包含引号, 如 {"text": {{json .Error}}}
+	"json": func(v interface{}) (string, error) {
+		byt, err := json.Marshal(v)
+		return string(byt), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+	// 格式化时间, 如 {{.Time | date "2006-01-02 15:04:05"}}
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	// 使用 -time-format 设置的时间格式, 如 {{datetime .Time}}
+	"datetime": util.FormatTime,
+}
+
+// isWebhookTemplate 是否使用模板
+func isWebhookTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+// parseWebhookTemplate 解析Webhook的模板
+func parseWebhookTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("webhook").Funcs(webhookFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Webhook模板不正确: %s", err)
+	}
+	return tmpl, nil
+}
+
+// newWebhookTemplateData 模板中的变量
+func newWebhookTemplateData(domains *Domains, provider string, ipv4Result updateStatusType, ipv6Result updateStatusType, events []string) webhookTemplateData {
+	return webhookTemplateData{
+		Events:   events,
+		Severity: getNotifySeverity(events),
+		Provider: provider,
+		Time:     time.Now(),
+		Error:    getDomainsError(append(append([]*Domain{}, domains.Ipv4Domains...), domains.Ipv6Domains...)),
+		IPv4:     newWebhookIPData(domains.Ipv4Addr, domains.Ipv4Info, ipv4Result, domains.Ipv4Domains),
+		IPv6:     newWebhookIPData(domains.Ipv6Addr, domains.Ipv6Info, ipv6Result, domains.Ipv6Domains),
+	}
+}
+
+func newWebhookIPData(addr string, info IPInfo, result updateStatusType, domains []*Domain) webhookIPData {
+	data := webhookIPData{
+		Addr:      addr,
+		IPInfo:    info,
+		OldAddr:   getOldAddr(domains),
+		Result:    string(result),
+		FailCount: getFailCount(domains),
+	}
+	for _, domain := range domains {
+		data.Domains = append(data.Domains, webhookDomainData{
+			Name:      domain.String(),
+			OldIP:     domain.OldIP,
+			Result:    string(domain.UpdateStatus),
+			FailCount: domain.FailCount,
+			Recovered: domain.Recovered,
+			Error:     domain.Error,
+		})
+	}
+	return data
+}
+
+// renderWebhook 包含{{ }}时先作为模板执行, 再替换#{}变量
+func renderWebhook(text string, domains *Domains, provider string, ipv4Result updateStatusType, ipv6Result updateStatusType, events []string) (string, error) {
+	if isWebhookTemplate(text) {
+		tmpl, err := parseWebhookTemplate(text)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, newWebhookTemplateData(domains, provider, ipv4Result, ipv6Result, events)); err != nil {
+			return "", fmt.Errorf("Webhook模板执行失败: %s", err)
+		}
+		text = buf.String()
+	}
+	return replacePara(domains, text, provider, ipv4Result, ipv6Result, events), nil
+}
+
+// getOldAddr 第一个有记录的域名之前的IP
+func getOldAddr(domains []*Domain) string {
+	for _, domain := range domains {
+		if domain.OldIP != "" {
+			return domain.OldIP
+		}
+	}
+	return ""
+}
+
+// getDomainsError 失败域名的原因, 相同的只保留一个
+func getDomainsError(domains []*Domain) string {
+	var errs []string
+	for _, domain := range domains {
+		if domain.UpdateStatus == UpdatedFailed && domain.Error != "" {
+			errs = append(errs, domain.Error)
+		}
+	}
+	return strings.Join(uniqueStrings(errs), "; ")
+}
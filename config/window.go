@@ -0,0 +1,64 @@
+package config
+
+import (
+	"ddns-go/util"
+	"log"
+	"time"
+)
+
+// timeWindows 解析后的允许及禁止更新的时间段
+type timeWindows struct {
+	allow util.TimeWindows
+	deny  util.TimeWindows
+}
+
+// parseWindows 读取配置时解析时间段, 不正确时在日志中提示一次并忽略
+func (conf *Config) parseWindows() {
+	conf.windows = newTimeWindows(conf, func(err error) { log.Println(err) })
+}
+
+func newTimeWindows(conf *Config, onError func(err error)) *timeWindows {
+	windows := &timeWindows{}
+	var err error
+	if windows.allow, err = util.ParseTimeWindows(conf.UpdateWindows); err != nil {
+		onError(err)
+	}
+	if windows.deny, err = util.ParseTimeWindows(conf.NoUpdateWindows); err != nil {
+		onError(err)
+	}
+	return windows
+}
+
+// timeWindows 解析后的时间段, 不是通过GetConfigCache获得的配置时解析
+func (conf *Config) timeWindows() *timeWindows {
+	if conf.windows != nil {
+		return conf.windows
+	}
+	return newTimeWindows(conf, func(err error) {})
+}
+
+// allowed t是否允许更新, 需在允许更新的时间段内且不在禁止更新的时间段内
+func (windows *timeWindows) allowed(t time.Time) bool {
+	if len(windows.allow) > 0 && !windows.allow.Contains(t) {
+		return false
+	}
+	return !windows.deny.Contains(t)
+}
+
+// UpdateAllowed t是否允许更新, 需在允许更新的时间段内且不在禁止更新的时间段内
+func (conf *Config) UpdateAllowed(t time.Time) bool {
+	return conf.timeWindows().allowed(t)
+}
+
+// NextUpdateAllowed t之后最早允许更新的时间, 精确到分钟. 一周内都不允许时返回零值
+func (conf *Config) NextUpdateAllowed(t time.Time) time.Time {
+	windows := conf.timeWindows()
+	next := t.Truncate(time.Minute)
+	for i := 0; i <= 7*24*60; i++ {
+		next = next.Add(time.Minute)
+		if windows.allowed(next) {
+			return next
+		}
+	}
+	return time.Time{}
+}
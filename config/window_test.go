@@ -0,0 +1,24 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestUpdateAllowed 读取配置时解析时间段, 之后修改配置字符串不影响已解析的
+func TestUpdateAllowed(t *testing.T) {
+	conf := &Config{UpdateWindows: "22:00-06:00", NoUpdateWindows: "sun 00:00-23:59"}
+	monday := time.Date(2024, 1, 1, 23, 0, 0, 0, time.Local)
+	if !conf.UpdateAllowed(monday) || conf.UpdateAllowed(monday.Add(-12*time.Hour)) {
+		t.Error("未解析的配置应按时间段判断")
+	}
+	if next := conf.NextUpdateAllowed(monday.Add(-12 * time.Hour)); !next.Equal(time.Date(2024, 1, 1, 22, 0, 0, 0, time.Local)) {
+		t.Errorf("下次允许更新的时间不正确: %s", next)
+	}
+
+	conf.parseWindows()
+	conf.UpdateWindows = "错误"
+	if !conf.UpdateAllowed(monday) || conf.UpdateAllowed(monday.AddDate(0, 0, 6)) {
+		t.Error("应使用读取配置时解析的时间段")
+	}
+}
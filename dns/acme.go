@@ -0,0 +1,79 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ACME DNS-01验证使用的子域名前缀
+const acmeChallengePrefix = "_acme-challenge."
+
+// txtRecorder 可添加及删除TXT记录的DNS服务商, 用于ACME DNS-01验证
+type txtRecorder interface {
+	// 添加TXT记录, 已有相同的值时不重复添加
+	addTXTRecord(ctx context.Context, dnsConf config.DNSConfig, domain *config.Domain, value string) error
+	// 删除值为value的TXT记录, 不删除其它的值
+	deleteTXTRecord(ctx context.Context, dnsConf config.DNSConfig, domain *config.Domain, value string) error
+}
+
+// ACMEPresent 使用配置的DNS服务商添加ACME DNS-01验证的TXT记录
+// domain为要申请证书的域名或 _acme-challenge 开头的完整域名, 可以.结尾, 通配符证书可带 *.
+func ACMEPresent(ctx context.Context, domain string, value string) error {
+	return acmeTXT(ctx, domain, value, true)
+}
+
+// ACMECleanup 删除ACMEPresent添加的TXT记录
+func ACMECleanup(ctx context.Context, domain string, value string) error {
+	return acmeTXT(ctx, domain, value, false)
+}
+
+func acmeTXT(ctx context.Context, domain string, value string, add bool) error {
+	conf, err := config.GetConfigCache()
+	if err != nil {
+		return err
+	}
+	dnsConf, err := conf.DNS.Resolved()
+	if err != nil {
+		return err
+	}
+	recorder, ok := NewDNS(conf.DNS.Name).(txtRecorder)
+	if !ok {
+		return fmt.Errorf("DNS服务商 %s 不支持添加TXT记录", conf.DNS.Name)
+	}
+
+	fqdn := acmeChallengeDomain(domain)
+	parsed := config.ParseDomain(fqdn)
+	if parsed == nil || value == "" {
+		return fmt.Errorf("域名 %s 或验证值不正确", domain)
+	}
+	if add {
+		if err = recorder.addTXTRecord(ctx, dnsConf, parsed, value); err != nil {
+			return fmt.Errorf("添加TXT记录 %s 失败: %s", fqdn, strings.TrimSpace(err.Error()))
+		}
+		log.Printf("已添加TXT记录 %s: %s\n", fqdn, value)
+		return nil
+	}
+	if err = recorder.deleteTXTRecord(ctx, dnsConf, parsed, value); err != nil {
+		return fmt.Errorf("删除TXT记录 %s 失败: %s", fqdn, strings.TrimSpace(err.Error()))
+	}
+	log.Printf("已删除TXT记录 %s: %s\n", fqdn, value)
+	return nil
+}
+
+// acmeChallengeDomain 获得验证使用的完整域名, 如 *.example.com 为 _acme-challenge.example.com
+func acmeChallengeDomain(domain string) string {
+	domain = strings.ToLower(strings.Trim(strings.TrimSpace(domain), "."))
+	domain = strings.TrimPrefix(domain, "*.")
+	if !strings.HasPrefix(domain, acmeChallengePrefix) {
+		domain = acmeChallengePrefix + domain
+	}
+	return domain
+}
+
+// txtValue 去掉TXT记录值两边的引号
+func txtValue(value string) string {
+	return strings.Trim(value, `"`)
+}
@@ -0,0 +1,20 @@
+package dns
+
+import "testing"
+
+// TestACMEChallengeDomain 验证使用的完整域名
+func TestACMEChallengeDomain(t *testing.T) {
+	tests := map[string]string{
+		"example.com":                      "_acme-challenge.example.com",
+		"*.Example.com":                    "_acme-challenge.example.com",
+		"_acme-challenge.www.example.com.": "_acme-challenge.www.example.com",
+	}
+	for domain, expected := range tests {
+		if result := acmeChallengeDomain(domain); result != expected {
+			t.Errorf("%s 结果为 %s, 应为 %s", domain, result, expected)
+		}
+	}
+	if txtValue(`"abc"`) != "abc" {
+		t.Error("应去掉TXT记录值两边的引号")
+	}
+}
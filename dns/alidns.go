@@ -2,8 +2,11 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"ddns-go/config"
 	"ddns-go/util"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
@@ -20,6 +23,7 @@ type Alidns struct {
 	DNSConfig config.DNSConfig
 	Domains   config.Domains
 	TTL       string
+	ctx       context.Context
 }
 
 // AlidnsSubDomainRecords 记录
@@ -41,9 +45,10 @@ type AlidnsResp struct {
 }
 
 // Init 初始化
-func (ali *Alidns) Init(conf *config.Config) {
+func (ali *Alidns) Init(ctx context.Context, conf *config.Config, domains config.Domains) {
+	ali.ctx = ctx
 	ali.DNSConfig = conf.DNS
-	ali.Domains.GetNewIp(conf)
+	ali.Domains = domains
 	if conf.TTL == "" {
 		// 默认600s
 		ali.TTL = "600"
@@ -67,6 +72,13 @@ func (ali *Alidns) addUpdateDomainRecords(recordType string) {
 	}
 
 	for _, domain := range domains {
+		key := recordCacheKey(ali.DNSConfig, recordType, domain)
+		if updateCachedRecord(ali.ctx, key, recordType, domain, ipAddr, func(record cachedRecord) error {
+			return ali.update(record.ID, domain, recordType, ipAddr)
+		}) {
+			continue
+		}
+
 		var record AlidnsSubDomainRecords
 		// 获取当前域名信息
 		params := url.Values{}
@@ -79,6 +91,11 @@ func (ali *Alidns) addUpdateDomainRecords(recordType string) {
 			return
 		}
 
+		if record.TotalCount > 0 && len(record.DomainRecords.Record) > 0 {
+			r := record.DomainRecords.Record[0]
+			stateFrom(ali.ctx).setCachedRecord(key, cachedRecord{ID: r.RecordID, Value: r.Value}, time.Now())
+		}
+
 		if record.TotalCount > 0 {
 			// 存在，更新
 			ali.modify(record, domain, recordType, ipAddr)
@@ -92,6 +109,10 @@ func (ali *Alidns) addUpdateDomainRecords(recordType string) {
 
 // 创建
 func (ali *Alidns) create(domain *config.Domain, recordType string, ipAddr string) {
+	if dryRunPlan(ali.ctx, recordType, domain, "", ipAddr) {
+		return
+	}
+
 	params := url.Values{}
 	params.Set("Action", "AddDomainRecord")
 	params.Set("DomainName", domain.DomainName)
@@ -109,6 +130,7 @@ func (ali *Alidns) create(domain *config.Domain, recordType string, ipAddr strin
 	} else {
 		log.Printf("新增域名解析 %s 失败！", domain)
 		domain.UpdateStatus = config.UpdatedFailed
+		domain.Error = requestError("新增域名解析失败", err)
 	}
 }
 
@@ -120,25 +142,48 @@ func (ali *Alidns) modify(record AlidnsSubDomainRecords, domain *config.Domain,
 		log.Printf("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
 		return
 	}
+	if dryRunPlan(ali.ctx, recordType, domain, record.DomainRecords.Record[0].Value, ipAddr) {
+		return
+	}
+
+	recordID := record.DomainRecords.Record[0].RecordID
+	err := ali.update(recordID, domain, recordType, ipAddr)
+
+	if err == nil {
+		log.Printf("更新域名解析 %s 成功！IP: %s", domain, ipAddr)
+		domain.UpdateStatus = config.UpdatedSuccess
+		stateFrom(ali.ctx).setCachedRecord(recordCacheKey(ali.DNSConfig, recordType, domain), cachedRecord{ID: recordID, Value: ipAddr}, time.Now())
+	} else {
+		log.Printf("更新域名解析 %s 失败！", domain)
+		domain.UpdateStatus = config.UpdatedFailed
+		domain.Error = requestError("更新域名解析失败", err)
+	}
+}
 
+// update 修改记录的值
+func (ali *Alidns) update(recordID string, domain *config.Domain, recordType string, ipAddr string) error {
 	params := url.Values{}
 	params.Set("Action", "UpdateDomainRecord")
 	params.Set("RR", domain.GetSubDomain())
-	params.Set("RecordId", record.DomainRecords.Record[0].RecordID)
+	params.Set("RecordId", recordID)
 	params.Set("Type", recordType)
 	params.Set("Value", ipAddr)
 	params.Set("TTL", ali.TTL)
 
 	var result AlidnsResp
 	err := ali.request(params, &result)
+	if err == nil && result.RecordID == "" {
+		err = errors.New("未返回RecordId")
+	}
+	return err
+}
 
-	if err == nil && result.RecordID != "" {
-		log.Printf("更新域名解析 %s 成功！IP: %s", domain, ipAddr)
-		domain.UpdateStatus = config.UpdatedSuccess
-	} else {
-		log.Printf("更新域名解析 %s 失败！", domain)
-		domain.UpdateStatus = config.UpdatedFailed
+// requestError 失败的原因, 接口未返回错误时只有操作名称
+func requestError(action string, err error) string {
+	if err != nil {
+		return action + ": " + err.Error()
 	}
+	return action
 }
 
 // request 统一请求接口
@@ -146,7 +191,8 @@ func (ali *Alidns) request(params url.Values, result interface{}) (err error) {
 
 	util.AliyunSigner(ali.DNSConfig.ID, ali.DNSConfig.Secret, &params)
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ali.ctx,
 		"GET",
 		alidnsEndpoint,
 		bytes.NewBuffer(nil),
@@ -158,9 +204,80 @@ func (ali *Alidns) request(params url.Values, result interface{}) (err error) {
 		return
 	}
 
-	client := http.Client{Timeout: 10 * time.Second}
+	client := providerClient(ali.DNSConfig)
 	resp, err := client.Do(req)
 	err = util.GetHTTPResponse(resp, alidnsEndpoint, err, result)
 
 	return
 }
+
+// checkAuth 查询域名列表校验ID/Secret
+func (ali *Alidns) checkAuth(ctx context.Context, dnsConf config.DNSConfig) error {
+	ali.ctx = ctx
+	ali.DNSConfig = dnsConf
+	params := url.Values{}
+	params.Set("Action", "DescribeDomains")
+	params.Set("PageSize", "1")
+	var result struct{ TotalCount int }
+	return ali.request(params, &result)
+}
+
+// addTXTRecord 添加ACME验证的TXT记录
+func (ali *Alidns) addTXTRecord(ctx context.Context, dnsConf config.DNSConfig, domain *config.Domain, value string) error {
+	ali.ctx = ctx
+	ali.DNSConfig = dnsConf
+	record, err := ali.getTXTRecords(domain)
+	if err != nil {
+		return err
+	}
+	for _, r := range record.DomainRecords.Record {
+		if txtValue(r.Value) == value {
+			return nil
+		}
+	}
+
+	params := url.Values{}
+	params.Set("Action", "AddDomainRecord")
+	params.Set("DomainName", domain.DomainName)
+	params.Set("RR", domain.GetSubDomain())
+	params.Set("Type", "TXT")
+	params.Set("Value", value)
+	params.Set("TTL", "600")
+	var result AlidnsResp
+	if err = ali.request(params, &result); err == nil && result.RecordID == "" {
+		err = fmt.Errorf("RequestId: %s", result.RequestID)
+	}
+	return err
+}
+
+// deleteTXTRecord 删除ACME验证的TXT记录
+func (ali *Alidns) deleteTXTRecord(ctx context.Context, dnsConf config.DNSConfig, domain *config.Domain, value string) error {
+	ali.ctx = ctx
+	ali.DNSConfig = dnsConf
+	record, err := ali.getTXTRecords(domain)
+	if err != nil {
+		return err
+	}
+	for _, r := range record.DomainRecords.Record {
+		if txtValue(r.Value) != value {
+			continue
+		}
+		params := url.Values{}
+		params.Set("Action", "DeleteDomainRecord")
+		params.Set("RecordId", r.RecordID)
+		var result AlidnsResp
+		if err = ali.request(params, &result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ali *Alidns) getTXTRecords(domain *config.Domain) (record AlidnsSubDomainRecords, err error) {
+	params := url.Values{}
+	params.Set("Action", "DescribeSubDomainRecords")
+	params.Set("SubDomain", domain.GetFullDomain())
+	params.Set("Type", "TXT")
+	err = ali.request(params, &record)
+	return
+}
@@ -0,0 +1,76 @@
+package dns
+
+import (
+	"ddns-go/config"
+	"ddns-go/util"
+	"log"
+	"time"
+)
+
+const (
+	// DNS服务商连续失败的次数达到后暂停定时更新
+	breakerThreshold = 5
+	// 暂停的时间, 之后再尝试一次
+	breakerCooldown = 30 * time.Minute
+)
+
+// breaker DNS服务商的熔断状态
+type breaker struct {
+	failures  int
+	openUntil time.Time
+}
+
+// breakerAllow 定时更新时是否可调用DNS服务商, 暂停时返回暂停到的时间
+func (s *State) breakerAllow(provider string, now time.Time) (bool, time.Time) {
+	s.breakerLock.Lock()
+	defer s.breakerLock.Unlock()
+
+	b, ok := s.breakers[provider]
+	if !ok || now.After(b.openUntil) {
+		return true, time.Time{}
+	}
+	return false, b.openUntil
+}
+
+// breakerRecord 记录DNS服务商的更新结果, 连续失败达到次数时暂停定时更新. 本次开始暂停时返回暂停到的时间
+func (s *State) breakerRecord(provider string, failed bool, now time.Time) (pausedUntil time.Time) {
+	s.breakerLock.Lock()
+	defer s.breakerLock.Unlock()
+
+	b, ok := s.breakers[provider]
+	if !ok {
+		b = &breaker{}
+		s.breakers[provider] = b
+	}
+	if !failed {
+		if b.failures >= breakerThreshold {
+			log.Printf("%s 已恢复, 继续定时更新\n", provider)
+		}
+		b.failures = 0
+		b.openUntil = time.Time{}
+		s.setPausedUntil(b.openUntil, 0)
+		return
+	}
+
+	b.failures++
+	if b.failures >= breakerThreshold {
+		b.openUntil = now.Add(breakerCooldown)
+		s.setPausedUntil(b.openUntil, b.failures)
+		log.Printf("%s 连续失败%d次, 暂停定时更新至 %s, 可手动立即更新\n", provider, b.failures, util.FormatTime(b.openUntil))
+		pausedUntil = b.openUntil
+	}
+	return
+}
+
+// providerFailed 获取到IP但调用DNS服务商失败, 获取IP失败不算DNS服务商的失败
+func providerFailed(domains *config.Domains) bool {
+	for _, recordType := range []string{"A", "AAAA"} {
+		_, recordDomains := domains.GetNewIpResult(recordType)
+		for _, domain := range recordDomains {
+			if domains.IPFor(recordType, domain) != "" && domain.UpdateStatus == config.UpdatedFailed {
+				return true
+			}
+		}
+	}
+	return false
+}
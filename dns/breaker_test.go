@@ -0,0 +1,39 @@
+package dns
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBreaker 测试连续失败后暂停定时更新
+func TestBreaker(t *testing.T) {
+	s := NewState()
+	now := time.Now()
+	for i := 0; i < breakerThreshold-1; i++ {
+		s.breakerRecord("huaweicloud", true, now)
+	}
+	if ok, _ := s.breakerAllow("huaweicloud", now); !ok {
+		t.Fatal("未达到连续失败的次数时不应暂停")
+	}
+
+	if until := s.breakerRecord("huaweicloud", true, now); !until.Equal(now.Add(breakerCooldown)) {
+		t.Fatal("开始暂停时应返回暂停到的时间, 用于通知")
+	}
+	if status := s.getStatus(); status.PausedFailures != breakerThreshold {
+		t.Errorf("状态中应显示连续失败的次数: %d", status.PausedFailures)
+	}
+	if ok, until := s.breakerAllow("huaweicloud", now); ok || !until.Equal(now.Add(breakerCooldown)) {
+		t.Fatal("连续失败后应暂停定时更新")
+	}
+	if ok, _ := s.breakerAllow("huaweicloud", now.Add(breakerCooldown+time.Second)); !ok {
+		t.Error("暂停时间过后应再次尝试")
+	}
+	if ok, _ := s.breakerAllow("alidns", now); !ok {
+		t.Error("不应影响其它DNS服务商")
+	}
+
+	s.breakerRecord("huaweicloud", false, now)
+	if ok, _ := s.breakerAllow("huaweicloud", now); !ok {
+		t.Error("更新成功后应恢复")
+	}
+}
@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"context"
 	"ddns-go/config"
 	"ddns-go/util"
 	"encoding/json"
@@ -9,19 +10,26 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 )
 
 type Callback struct {
 	DNSConfig config.DNSConfig
 	Domains   config.Domains
 	TTL       string
+	ctx       context.Context
+	// 比较解析记录使用的DNS服务器
+	resolver string
 }
 
+// serialUpdate 上次调用的IP对所有域名相同, 需一次调用所有域名
+func (cb *Callback) serialUpdate() {}
+
 // Init 初始化
-func (cb *Callback) Init(conf *config.Config) {
+func (cb *Callback) Init(ctx context.Context, conf *config.Config, domains config.Domains) {
+	cb.ctx = ctx
 	cb.DNSConfig = conf.DNS
-	cb.Domains.GetNewIp(conf)
+	cb.resolver = conf.Resolver
+	cb.Domains = domains
 	if conf.TTL == "" {
 		// 默认600
 		cb.TTL = "600"
@@ -37,8 +45,30 @@ func (cb *Callback) AddUpdateDomainRecords() config.Domains {
 	return cb.Domains
 }
 
-var lastIpv4 string
-var lastIpv6 string
+// lastIPKey 上次IP的key, 域名单独设置了获取IP方式时分开记录
+func (cb *Callback) lastIPKey(recordType string, domains []*config.Domain) string {
+	key := recordType + " " + hashKey(cb.DNSConfig.ID)
+	if len(domains) > 0 && !domains[0].Source.IsZero() {
+		key += " " + hashKey(domains[0].Source.String())
+	}
+	return key
+}
+
+// lastIP 上次成功调用的IP
+func (cb *Callback) lastIP(key string) string {
+	s := stateFrom(cb.ctx)
+	s.callbackLastIPLock.Lock()
+	defer s.callbackLastIPLock.Unlock()
+	return s.callbackLastIP[key]
+}
+
+// setLastIP 记录成功调用的IP
+func (cb *Callback) setLastIP(key string, ipAddr string) {
+	s := stateFrom(cb.ctx)
+	s.callbackLastIPLock.Lock()
+	defer s.callbackLastIPLock.Unlock()
+	s.callbackLastIP[key] = ipAddr
+}
 
 func (cb *Callback) addUpdateDomainRecords(recordType string) {
 	ipAddr, domains := cb.Domains.GetNewIpResult(recordType)
@@ -47,20 +77,43 @@ func (cb *Callback) addUpdateDomainRecords(recordType string) {
 		return
 	}
 
-	if recordType == "A" {
-		if lastIpv4 == ipAddr {
+	key := cb.lastIPKey(recordType, domains)
+	lastIP := cb.lastIP(key)
+	if lastIP == ipAddr {
+		if recordType == "A" {
 			log.Println("你的IPv4未变化, 未触发Callback")
-			return
-		}
-		lastIpv4 = ipAddr
-	} else {
-		if lastIpv6 == ipAddr {
+		} else {
 			log.Println("你的IPv6未变化, 未触发Callback")
+		}
+		return
+	}
+
+	// 试运行时不调用Callback, 也不记录IP
+	planned := false
+	for _, domain := range domains {
+		planned = dryRunPlan(cb.ctx, recordType, domain, lastIP, ipAddr)
+	}
+	if planned {
+		return
+	}
+
+	// 重启后没有上次的IP, 配置了DNS服务器时查询解析记录, 均已是新IP时不调用
+	if lastIP == "" && cb.resolver != "" && len(domains) > 0 {
+		resolved := true
+		for _, domain := range domains {
+			resolved = resolved && recordResolved(cb.ctx, cb.resolver, domain.String(), recordType, ipAddr)
+		}
+		if resolved {
+			log.Printf("解析记录已是 %s, 未触发Callback\n", ipAddr)
+			cb.setLastIP(key, ipAddr)
+			for _, domain := range domains {
+				domain.UpdateStatus = config.UpdatedNothing
+			}
 			return
 		}
-		lastIpv6 = ipAddr
 	}
 
+	success := true
 	for _, domain := range domains {
 		method := "GET"
 		postPara := ""
@@ -78,25 +131,31 @@ func (cb *Callback) addUpdateDomainRecords(recordType string) {
 			log.Println("Callback的URL不正确")
 			return
 		}
-		req, err := http.NewRequest(method, u.String(), strings.NewReader(postPara))
+		req, err := http.NewRequestWithContext(cb.ctx, method, u.String(), strings.NewReader(postPara))
 		if err != nil {
 			log.Println("创建Callback请求异常, Err:", err)
 			return
 		}
 		req.Header.Add("content-type", contentType)
 
-		clt := http.Client{}
-		clt.Timeout = 30 * time.Second
+		clt := providerClient(cb.DNSConfig, util.URLSecrets(requestURL)...)
 		resp, err := clt.Do(req)
-		body, err := util.GetHTTPResponseOrg(resp, requestURL, err)
+		body, err := util.GetHTTPResponseOrg(resp, util.RedactURLString(requestURL), err)
 		if err == nil {
 			log.Println(fmt.Sprintf("Callback调用成功, 返回数据: %s", string(body)))
 			domain.UpdateStatus = config.UpdatedSuccess
 		} else {
 			log.Println(fmt.Sprintf("Callback调用失败，Err：%s", err))
 			domain.UpdateStatus = config.UpdatedFailed
+			domain.Error = err.Error()
+			success = false
 		}
 	}
+
+	// 全部成功后才记录IP, 失败时重试或下次继续调用
+	if success {
+		cb.setLastIP(key, ipAddr)
+	}
 }
 
 // replacePara 替换参数
@@ -2,6 +2,7 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"ddns-go/config"
 	"ddns-go/util"
 	"encoding/json"
@@ -9,6 +10,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,6 +23,7 @@ type Cloudflare struct {
 	DNSConfig config.DNSConfig
 	Domains   config.Domains
 	TTL       int
+	ctx       context.Context
 }
 
 // CloudflareZonesResp cloudflare zones返回结果
@@ -56,10 +59,30 @@ type CloudflareStatus struct {
 	Messages []string
 }
 
+// cloudflareChange 需新增或修改的记录, 同一区域的合并为一次批量请求
+type cloudflareChange struct {
+	domain *config.Domain
+	zoneID string
+	// 为空时新增
+	recordID string
+	// 使用缓存的记录, 失败时重新查询
+	cached bool
+}
+
+// CloudflareBatchResp 批量修改的返回结果
+type CloudflareBatchResp struct {
+	CloudflareStatus
+	Result struct {
+		Posts   []CloudflareRecord `json:"posts"`
+		Patches []CloudflareRecord `json:"patches"`
+	}
+}
+
 // Init 初始化
-func (cf *Cloudflare) Init(conf *config.Config) {
+func (cf *Cloudflare) Init(ctx context.Context, conf *config.Config, domains config.Domains) {
+	cf.ctx = ctx
 	cf.DNSConfig = conf.DNS
-	cf.Domains.GetNewIp(conf)
+	cf.Domains = domains
 	if conf.TTL == "" {
 		// 默认1 auto ttl
 		cf.TTL = 1
@@ -80,6 +103,9 @@ func (cf *Cloudflare) AddUpdateDomainRecords() config.Domains {
 	return cf.Domains
 }
 
+// batchUpdate 同一区域的记录使用一次批量请求
+func (cf *Cloudflare) batchUpdate() {}
+
 func (cf *Cloudflare) addUpdateDomainRecords(recordType string) {
 	ipAddr, domains := cf.Domains.GetNewIpResult(recordType)
 
@@ -87,51 +113,160 @@ func (cf *Cloudflare) addUpdateDomainRecords(recordType string) {
 		return
 	}
 
+	var changes []cloudflareChange
 	for _, domain := range domains {
-		// get zone
+		key := recordCacheKey(cf.DNSConfig, recordType, domain)
+		if record, ok := stateFrom(cf.ctx).getCachedRecord(key, time.Now()); ok {
+			// 相同不修改
+			if record.Value == ipAddr {
+				log.Printf("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
+				continue
+			}
+			if dryRunPlan(cf.ctx, recordType, domain, record.Value, ipAddr) {
+				continue
+			}
+			changes = append(changes, cloudflareChange{domain: domain, zoneID: record.ZoneID, recordID: record.ID, cached: true})
+			continue
+		}
+
+		domainChanges, ok := cf.getChanges(domain, recordType, ipAddr)
+		if !ok {
+			break
+		}
+		changes = append(changes, domainChanges...)
+	}
+
+	for _, zoneChanges := range groupCloudflareChanges(changes) {
+		cf.apply(zoneChanges, recordType, ipAddr)
+	}
+}
+
+// getChanges 查询区域及记录, 返回需新增或修改的记录. 查询失败时返回false
+func (cf *Cloudflare) getChanges(domain *config.Domain, recordType string, ipAddr string) (changes []cloudflareChange, ok bool) {
+	// get zone
+	zoneKey := zoneCacheKey(cf.DNSConfig, domain)
+	zoneID, ok := stateFrom(cf.ctx).getCachedZone(zoneKey)
+	if !ok {
 		result, err := cf.getZones(domain)
 		if err != nil || len(result.Result) != 1 {
-			return
+			return nil, false
 		}
-		zoneID := result.Result[0].ID
+		zoneID = result.Result[0].ID
+		stateFrom(cf.ctx).setCachedZone(zoneKey, zoneID)
+	}
 
-		var records CloudflareRecordsResp
-		// getDomains 最多更新前50条
-		err = cf.request(
-			"GET",
-			fmt.Sprintf(zonesAPI+"/%s/dns_records?type=%s&name=%s&per_page=50", zoneID, recordType, domain),
-			nil,
-			&records,
-		)
+	var records CloudflareRecordsResp
+	// getDomains 最多更新前50条
+	err := cf.request(
+		"GET",
+		fmt.Sprintf(zonesAPI+"/%s/dns_records?type=%s&name=%s&per_page=50", zoneID, recordType, domain),
+		nil,
+		&records,
+	)
+
+	if err != nil || !records.Success {
+		// 区域已被删除时重新查询
+		stateFrom(cf.ctx).deleteCachedZone(zoneKey, zoneID)
+		return nil, false
+	}
+
+	if len(records.Result) == 1 {
+		stateFrom(cf.ctx).setCachedRecord(recordCacheKey(cf.DNSConfig, recordType, domain), cachedRecord{ZoneID: zoneID, ID: records.Result[0].ID, Value: records.Result[0].Content}, time.Now())
+	}
+
+	if len(records.Result) == 0 {
+		// 新增
+		if !dryRunPlan(cf.ctx, recordType, domain, "", ipAddr) {
+			changes = append(changes, cloudflareChange{domain: domain, zoneID: zoneID})
+		}
+		return changes, true
+	}
+
+	// 更新
+	for _, record := range records.Result {
+		// 相同不修改
+		if record.Content == ipAddr {
+			log.Printf("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
+			continue
+		}
+		if dryRunPlan(cf.ctx, recordType, domain, record.Content, ipAddr) {
+			continue
+		}
+		changes = append(changes, cloudflareChange{domain: domain, zoneID: zoneID, recordID: record.ID})
+	}
+	return changes, true
+}
+
+// groupCloudflareChanges 按区域分组, 保持原来的顺序
+func groupCloudflareChanges(changes []cloudflareChange) (groups [][]cloudflareChange) {
+	index := make(map[string]int)
+	for _, change := range changes {
+		i, ok := index[change.zoneID]
+		if !ok {
+			i = len(groups)
+			index[change.zoneID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], change)
+	}
+	return
+}
 
-		if err != nil || !records.Success {
+// apply 同一区域有多条记录时使用一次批量请求, 批量请求失败时逐个修改
+func (cf *Cloudflare) apply(changes []cloudflareChange, recordType string, ipAddr string) {
+	if len(changes) > 1 {
+		err := cf.batch(changes, recordType, ipAddr)
+		if err == nil {
 			return
 		}
+		log.Printf("批量更新 %d 条记录失败, 逐个更新: %s", len(changes), err)
+	}
+	for _, change := range changes {
+		cf.applyOne(change, recordType, ipAddr)
+	}
+}
 
-		if len(records.Result) > 0 {
-			// 更新
-			cf.modify(records, zoneID, domain, recordType, ipAddr)
-		} else {
-			// 新增
-			cf.create(zoneID, domain, recordType, ipAddr)
+// applyOne 新增或修改一条记录
+func (cf *Cloudflare) applyOne(change cloudflareChange, recordType string, ipAddr string) {
+	domain := change.domain
+	key := recordCacheKey(cf.DNSConfig, recordType, domain)
+	if change.recordID == "" {
+		cf.create(change.zoneID, domain, recordType, ipAddr)
+		return
+	}
+
+	err := cf.patch(change.zoneID, change.recordID, ipAddr)
+	if err != nil && change.cached {
+		// 记录已被删除等, 重新查询
+		stateFrom(cf.ctx).deleteCachedRecord(key)
+		log.Printf("使用缓存的记录更新域名 %s 失败, 重新查询记录: %s", domain, err)
+		changes, _ := cf.getChanges(domain, recordType, ipAddr)
+		for _, c := range changes {
+			cf.applyOne(c, recordType, ipAddr)
 		}
+		return
+	}
+
+	if err == nil {
+		log.Printf("更新域名解析 %s 成功！IP: %s", domain, ipAddr)
+		domain.UpdateStatus = config.UpdatedSuccess
+		if _, ok := stateFrom(cf.ctx).getCachedRecord(key, time.Now()); ok {
+			stateFrom(cf.ctx).setCachedRecord(key, cachedRecord{ZoneID: change.zoneID, ID: change.recordID, Value: ipAddr}, time.Now())
+		}
+	} else {
+		log.Printf("更新域名解析 %s 失败！%s", domain, err)
+		domain.UpdateStatus = config.UpdatedFailed
+		domain.Error = fmt.Sprintf("更新域名解析失败: %s", err)
 	}
 }
 
 // 创建
 func (cf *Cloudflare) create(zoneID string, domain *config.Domain, recordType string, ipAddr string) {
-	record := &CloudflareRecord{
-		Type:    recordType,
-		Name:    domain.String(),
-		Content: ipAddr,
-		Proxied: false,
-		TTL: cf.TTL,
-	}
 	var status CloudflareStatus
 	err := cf.request(
 		"POST",
 		fmt.Sprintf(zonesAPI+"/%s/dns_records", zoneID),
-		record,
+		cf.newRecord(domain, recordType, ipAddr),
 		&status,
 	)
 	if err == nil && status.Success {
@@ -140,37 +275,78 @@ func (cf *Cloudflare) create(zoneID string, domain *config.Domain, recordType st
 	} else {
 		log.Printf("新增域名解析 %s 失败！Messages: %s", domain, status.Messages)
 		domain.UpdateStatus = config.UpdatedFailed
+		domain.Error = fmt.Sprintf("新增域名解析失败: %s", status.Messages)
 	}
 }
 
-// 修改
-func (cf *Cloudflare) modify(result CloudflareRecordsResp, zoneID string, domain *config.Domain, recordType string, ipAddr string) {
+func (cf *Cloudflare) newRecord(domain *config.Domain, recordType string, ipAddr string) *CloudflareRecord {
+	return &CloudflareRecord{
+		Type:    recordType,
+		Name:    domain.String(),
+		Content: ipAddr,
+		Proxied: false,
+		TTL:     cf.TTL,
+	}
+}
 
-	for _, record := range result.Result {
-		// 相同不修改
-		if record.Content == ipAddr {
-			log.Printf("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
-			continue
+// patch 只修改IP及TTL, 不改变代理等设置
+func (cf *Cloudflare) patch(zoneID string, recordID string, ipAddr string) error {
+	var status CloudflareStatus
+	err := cf.request(
+		"PATCH",
+		fmt.Sprintf(zonesAPI+"/%s/dns_records/%s", zoneID, recordID),
+		map[string]interface{}{"content": ipAddr, "ttl": cf.TTL},
+		&status,
+	)
+	if err == nil && !status.Success {
+		err = fmt.Errorf("Messages: %s", status.Messages)
+	}
+	return err
+}
+
+// batchRequest 批量请求的内容, 新增的在posts中, 修改的在patches中
+func (cf *Cloudflare) batchRequest(changes []cloudflareChange, recordType string, ipAddr string) map[string]interface{} {
+	posts := []*CloudflareRecord{}
+	patches := []map[string]interface{}{}
+	for _, change := range changes {
+		if change.recordID == "" {
+			posts = append(posts, cf.newRecord(change.domain, recordType, ipAddr))
+		} else {
+			patches = append(patches, map[string]interface{}{"id": change.recordID, "content": ipAddr, "ttl": cf.TTL})
 		}
-		var status CloudflareStatus
-		record.Content = ipAddr
-		record.TTL = cf.TTL
+	}
+	return map[string]interface{}{"posts": posts, "patches": patches}
+}
 
-		err := cf.request(
-			"PUT",
-			fmt.Sprintf(zonesAPI+"/%s/dns_records/%s", zoneID, record.ID),
-			record,
-			&status,
-		)
+// batch 一次请求新增或修改同一区域的多条记录. 全部成功或全部失败
+func (cf *Cloudflare) batch(changes []cloudflareChange, recordType string, ipAddr string) error {
+	var result CloudflareBatchResp
+	err := cf.request(
+		"POST",
+		fmt.Sprintf(zonesAPI+"/%s/dns_records/batch", changes[0].zoneID),
+		cf.batchRequest(changes, recordType, ipAddr),
+		&result,
+	)
+	if err == nil && !result.Success {
+		err = fmt.Errorf("Messages: %s", result.Messages)
+	}
+	if err != nil {
+		return err
+	}
 
-		if err == nil && status.Success {
-			log.Printf("更新域名解析 %s 成功！IP: %s", domain, ipAddr)
-			domain.UpdateStatus = config.UpdatedSuccess
+	for _, change := range changes {
+		if change.recordID == "" {
+			log.Printf("新增域名解析 %s 成功！IP: %s", change.domain, ipAddr)
 		} else {
-			log.Printf("更新域名解析 %s 失败！Messages: %s", domain, status.Messages)
-			domain.UpdateStatus = config.UpdatedFailed
+			log.Printf("更新域名解析 %s 成功！IP: %s", change.domain, ipAddr)
+			key := recordCacheKey(cf.DNSConfig, recordType, change.domain)
+			if _, ok := stateFrom(cf.ctx).getCachedRecord(key, time.Now()); ok {
+				stateFrom(cf.ctx).setCachedRecord(key, cachedRecord{ZoneID: change.zoneID, ID: change.recordID, Value: ipAddr}, time.Now())
+			}
 		}
+		change.domain.UpdateStatus = config.UpdatedSuccess
 	}
+	return nil
 }
 
 // 获得域名记录列表
@@ -191,7 +367,8 @@ func (cf *Cloudflare) request(method string, url string, data interface{}, resul
 	if data != nil {
 		jsonStr, _ = json.Marshal(data)
 	}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		cf.ctx,
 		method,
 		url,
 		bytes.NewBuffer(jsonStr),
@@ -203,9 +380,165 @@ func (cf *Cloudflare) request(method string, url string, data interface{}, resul
 	req.Header.Set("Authorization", "Bearer "+cf.DNSConfig.Secret)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := http.Client{Timeout: 30 * time.Second}
+	client := providerClient(cf.DNSConfig)
 	resp, err := client.Do(req)
 	err = util.GetHTTPResponse(resp, url, err, result)
 
 	return
 }
+
+// checkAuth 查询区域列表校验Token
+func (cf *Cloudflare) checkAuth(ctx context.Context, dnsConf config.DNSConfig) error {
+	cf.ctx = ctx
+	cf.DNSConfig = dnsConf
+	var result CloudflareZonesResp
+	err := cf.request("GET", zonesAPI+"?per_page=5", nil, &result)
+	if err == nil && !result.Success {
+		err = fmt.Errorf("Messages: %s", result.Messages)
+	}
+	return err
+}
+
+// setPTRRecord 设置反向解析区域中的PTR记录, 需已将该区域添加到Cloudflare
+func (cf *Cloudflare) setPTRRecord(ctx context.Context, dnsConf config.DNSConfig, name string, target string) (bool, error) {
+	cf.ctx = ctx
+	cf.DNSConfig = dnsConf
+	zoneID, err := cf.getReverseZone(name)
+	if err != nil {
+		return false, err
+	}
+
+	var records CloudflareRecordsResp
+	err = cf.request(
+		"GET",
+		fmt.Sprintf(zonesAPI+"/%s/dns_records?type=PTR&name=%s&per_page=50", zoneID, name),
+		nil,
+		&records,
+	)
+	if err == nil && !records.Success {
+		err = fmt.Errorf("Messages: %s", records.Messages)
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, record := range records.Result {
+		if strings.TrimSuffix(record.Content, ".") == target {
+			return false, nil
+		}
+	}
+
+	if cf.TTL == 0 {
+		cf.TTL = 1
+	}
+	if len(records.Result) > 0 {
+		return true, cf.patch(zoneID, records.Result[0].ID, target)
+	}
+	var status CloudflareStatus
+	err = cf.request(
+		"POST",
+		fmt.Sprintf(zonesAPI+"/%s/dns_records", zoneID),
+		&CloudflareRecord{Type: "PTR", Name: name, Content: target, TTL: cf.TTL},
+		&status,
+	)
+	if err == nil && !status.Success {
+		err = fmt.Errorf("Messages: %s", status.Messages)
+	}
+	return err == nil, err
+}
+
+// getReverseZone 从长到短查找name所在的反向解析区域, 如 4.3.2.1.in-addr.arpa 在 3.2.1.in-addr.arpa 中
+func (cf *Cloudflare) getReverseZone(name string) (string, error) {
+	zoneKey := zoneCacheKey(cf.DNSConfig, &config.Domain{DomainName: name})
+	if zoneID, ok := stateFrom(cf.ctx).getCachedZone(zoneKey); ok {
+		return zoneID, nil
+	}
+	labels := strings.Split(name, ".")
+	// 最短为 x.in-addr.arpa 或 x.ip6.arpa
+	for i := 1; i <= len(labels)-3; i++ {
+		var result CloudflareZonesResp
+		err := cf.request(
+			"GET",
+			fmt.Sprintf(zonesAPI+"?name=%s&status=%s&per_page=%s", strings.Join(labels[i:], "."), "active", "50"),
+			nil,
+			&result,
+		)
+		if err != nil {
+			return "", err
+		}
+		if len(result.Result) == 1 {
+			stateFrom(cf.ctx).setCachedZone(zoneKey, result.Result[0].ID)
+			return result.Result[0].ID, nil
+		}
+	}
+	return "", fmt.Errorf("未找到 %s 所在的反向解析区域", name)
+}
+
+// addTXTRecord 添加ACME验证的TXT记录
+func (cf *Cloudflare) addTXTRecord(ctx context.Context, dnsConf config.DNSConfig, domain *config.Domain, value string) error {
+	zoneID, records, err := cf.getTXTRecords(ctx, dnsConf, domain)
+	if err != nil {
+		return err
+	}
+	for _, record := range records.Result {
+		if txtValue(record.Content) == value {
+			return nil
+		}
+	}
+
+	var status CloudflareStatus
+	err = cf.request(
+		"POST",
+		fmt.Sprintf(zonesAPI+"/%s/dns_records", zoneID),
+		&CloudflareRecord{Type: "TXT", Name: domain.String(), Content: value, TTL: 1},
+		&status,
+	)
+	if err == nil && !status.Success {
+		err = fmt.Errorf("Messages: %s", status.Messages)
+	}
+	return err
+}
+
+// deleteTXTRecord 删除ACME验证的TXT记录
+func (cf *Cloudflare) deleteTXTRecord(ctx context.Context, dnsConf config.DNSConfig, domain *config.Domain, value string) error {
+	zoneID, records, err := cf.getTXTRecords(ctx, dnsConf, domain)
+	if err != nil {
+		return err
+	}
+	for _, record := range records.Result {
+		if txtValue(record.Content) != value {
+			continue
+		}
+		var status CloudflareStatus
+		err = cf.request("DELETE", fmt.Sprintf(zonesAPI+"/%s/dns_records/%s", zoneID, record.ID), nil, &status)
+		if err == nil && !status.Success {
+			err = fmt.Errorf("Messages: %s", status.Messages)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cf *Cloudflare) getTXTRecords(ctx context.Context, dnsConf config.DNSConfig, domain *config.Domain) (zoneID string, records CloudflareRecordsResp, err error) {
+	cf.ctx = ctx
+	cf.DNSConfig = dnsConf
+	zones, err := cf.getZones(domain)
+	if err != nil {
+		return
+	}
+	if len(zones.Result) != 1 {
+		return "", records, fmt.Errorf("未找到域名 %s 的区域", domain.DomainName)
+	}
+	zoneID = zones.Result[0].ID
+	err = cf.request(
+		"GET",
+		fmt.Sprintf(zonesAPI+"/%s/dns_records?type=TXT&name=%s&per_page=50", zoneID, domain),
+		nil,
+		&records,
+	)
+	if err == nil && !records.Success {
+		err = fmt.Errorf("Messages: %s", records.Messages)
+	}
+	return
+}
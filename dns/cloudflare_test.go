@@ -0,0 +1,42 @@
+package dns
+
+import (
+	"ddns-go/config"
+	"encoding/json"
+	"testing"
+)
+
+// TestCloudflareBatch 按区域分组, 新增的在posts中, 修改的在patches中
+func TestCloudflareBatch(t *testing.T) {
+	a := &config.Domain{DomainName: "example.com", SubDomain: "a"}
+	b := &config.Domain{DomainName: "example.org", SubDomain: "b"}
+	c := &config.Domain{DomainName: "example.com", SubDomain: "c"}
+	changes := []cloudflareChange{
+		{domain: a, zoneID: "zone1", recordID: "id1"},
+		{domain: b, zoneID: "zone2", recordID: "id2"},
+		{domain: c, zoneID: "zone1"},
+	}
+
+	groups := groupCloudflareChanges(changes)
+	if len(groups) != 2 || len(groups[0]) != 2 || groups[0][1].domain != c || groups[1][0].domain != b {
+		t.Fatalf("按区域分组不正确: %+v", groups)
+	}
+
+	cf := &Cloudflare{TTL: 120}
+	byt, _ := json.Marshal(cf.batchRequest(groups[0], "A", "1.1.1.1"))
+	var body struct {
+		Posts   []CloudflareRecord
+		Patches []map[string]interface{}
+	}
+	json.Unmarshal(byt, &body)
+	if len(body.Posts) != 1 || body.Posts[0].Name != "c.example.com" || body.Posts[0].Content != "1.1.1.1" || body.Posts[0].TTL != 120 {
+		t.Errorf("新增的记录不正确: %s", byt)
+	}
+	if len(body.Patches) != 1 || body.Patches[0]["id"] != "id1" || body.Patches[0]["content"] != "1.1.1.1" {
+		t.Errorf("修改的记录不正确: %s", byt)
+	}
+
+	if _, ok := NewDNS("cloudflare").(batchUpdater); !ok {
+		t.Error("cloudflare 应按区域批量更新")
+	}
+}
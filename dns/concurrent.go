@@ -0,0 +1,163 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"sync"
+)
+
+// serialUpdater 需一次更新所有域名的DNS服务商, 同时更新时也不拆分
+type serialUpdater interface {
+	serialUpdate()
+}
+
+// batchUpdater 同一区域的记录合并为一次请求的DNS服务商, 同时更新时按区域拆分
+type batchUpdater interface {
+	batchUpdate()
+}
+
+// updateDomains 使用DNS服务商更新域名. 单独设置了获取IP方式的域名按获取到的IP分开更新
+func updateDomains(ctx context.Context, conf *config.Config, domains config.Domains) config.Domains {
+	groups := splitSources(domains)
+	if len(groups) == 1 {
+		result := updateGroup(ctx, conf, groups[0])
+		// DNS服务商只返回IP及域名
+		result.Ipv4Info, result.Ipv6Info, result.ASNChanged, result.Damped = domains.Ipv4Info, domains.Ipv6Info, domains.ASNChanged, domains.Damped
+		return result
+	}
+	for _, group := range groups {
+		updateGroup(ctx, conf, group)
+	}
+	// 域名的更新状态已在各组中修改
+	return domains
+}
+
+// splitSources 按域名的获取IP方式拆分, 第一组为使用IPv4/IPv6的获取IP方式的域名
+func splitSources(domains config.Domains) (groups []config.Domains) {
+	shared := config.Domains{Ipv4Addr: domains.Ipv4Addr, Ipv6Addr: domains.Ipv6Addr}
+	indexes := make(map[string]int)
+	add := func(recordType string, domain *config.Domain) {
+		key := recordType + " " + domain.Source.String()
+		i, ok := indexes[key]
+		if !ok {
+			i = len(groups)
+			indexes[key] = i
+			groups = append(groups, config.Domains{})
+		}
+		if recordType == "AAAA" {
+			groups[i].Ipv6Addr = domain.IP
+			groups[i].Ipv6Domains = append(groups[i].Ipv6Domains, domain)
+		} else {
+			groups[i].Ipv4Addr = domain.IP
+			groups[i].Ipv4Domains = append(groups[i].Ipv4Domains, domain)
+		}
+	}
+	for _, domain := range domains.Ipv4Domains {
+		if domain.Source.IsZero() {
+			shared.Ipv4Domains = append(shared.Ipv4Domains, domain)
+		} else {
+			add("A", domain)
+		}
+	}
+	for _, domain := range domains.Ipv6Domains {
+		if domain.Source.IsZero() {
+			shared.Ipv6Domains = append(shared.Ipv6Domains, domain)
+		} else {
+			add("AAAA", domain)
+		}
+	}
+	if len(groups) == 0 || len(shared.Ipv4Domains)+len(shared.Ipv6Domains) > 0 {
+		groups = append([]config.Domains{shared}, groups...)
+	}
+	return
+}
+
+// updateGroup 使用DNS服务商更新IP相同的域名. conf.Concurrency大于1时每个域名(或区域)为一个任务, 同时更新多个域名
+func updateGroup(ctx context.Context, conf *config.Config, domains config.Domains) config.Domains {
+	provider := NewDNS(conf.DNS.Name)
+	_, byZone := provider.(batchUpdater)
+	jobs := splitDomains(domains, byZone)
+	workers := conf.Concurrency
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	if _, serial := provider.(serialUpdater); workers <= 1 || serial {
+		return updateProvider(ctx, conf, domains)
+	}
+
+	results := make([]config.Domains, len(jobs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = safeUpdateJob(ctx, conf, jobs[i])
+			}
+		}()
+	}
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	merged := config.Domains{Ipv4Addr: domains.Ipv4Addr, Ipv6Addr: domains.Ipv6Addr}
+	for _, result := range results {
+		merged.Ipv4Domains = append(merged.Ipv4Domains, result.Ipv4Domains...)
+		merged.Ipv6Domains = append(merged.Ipv6Domains, result.Ipv6Domains...)
+	}
+	return merged
+}
+
+// updateProvider 创建DNS服务商并更新域名
+func updateProvider(ctx context.Context, conf *config.Config, domains config.Domains) config.Domains {
+	dnsSelected := NewDNS(conf.DNS.Name)
+	if dnsSelected == nil {
+		dnsSelected = &Alidns{}
+	}
+	dnsSelected.Init(ctx, conf, domains)
+	return dnsSelected.AddUpdateDomainRecords()
+}
+
+// safeUpdateJob 更新一个任务, 发生panic时只将此任务的域名标记为失败
+func safeUpdateJob(ctx context.Context, conf *config.Config, job config.Domains) (result config.Domains) {
+	defer func() {
+		if err := recover(); err != nil {
+			logPanic(conf.DNS.Name+" 更新", err)
+			for _, domain := range job.Ipv4Domains {
+				domain.UpdateStatus = config.UpdatedFailed
+			}
+			for _, domain := range job.Ipv6Domains {
+				domain.UpdateStatus = config.UpdatedFailed
+			}
+			result = job
+		}
+	}()
+	return updateProvider(ctx, conf, job)
+}
+
+// splitDomains 每个域名拆分为一个任务, 只包含此域名及对应的IP. byZone时同一根域名的在一个任务中
+func splitDomains(domains config.Domains, byZone bool) (jobs []config.Domains) {
+	zones := make(map[string]int)
+	jobFor := func(zone string) int {
+		if i, ok := zones[zone]; ok && byZone {
+			return i
+		}
+		zones[zone] = len(jobs)
+		jobs = append(jobs, config.Domains{})
+		return len(jobs) - 1
+	}
+	for _, domain := range domains.Ipv4Domains {
+		i := jobFor("A " + domain.DomainName)
+		jobs[i].Ipv4Addr = domains.Ipv4Addr
+		jobs[i].Ipv4Domains = append(jobs[i].Ipv4Domains, domain)
+	}
+	for _, domain := range domains.Ipv6Domains {
+		i := jobFor("AAAA " + domain.DomainName)
+		jobs[i].Ipv6Addr = domains.Ipv6Addr
+		jobs[i].Ipv6Domains = append(jobs[i].Ipv6Domains, domain)
+	}
+	return
+}
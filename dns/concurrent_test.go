@@ -0,0 +1,82 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"testing"
+)
+
+// TestUpdateDomains 同时更新时每个域名一个任务, 结果按原来的顺序合并
+func TestUpdateDomains(t *testing.T) {
+	conf := &config.Config{Concurrency: 4}
+	conf.DNS.Name = "alidns"
+	domains := config.Domains{
+		Ipv4Addr:    "1.1.1.1",
+		Ipv4Domains: []*config.Domain{{DomainName: "example.com", SubDomain: "a"}, {DomainName: "example.com", SubDomain: "b"}},
+		Ipv6Addr:    "::1",
+		Ipv6Domains: []*config.Domain{{DomainName: "example.com", SubDomain: "c"}},
+	}
+
+	jobs := splitDomains(domains, false)
+	if len(jobs) != 3 || jobs[0].Ipv6Addr != "" || jobs[2].Ipv4Addr != "" || jobs[2].Ipv6Domains[0].SubDomain != "c" {
+		t.Fatalf("拆分的任务不正确: %+v", jobs)
+	}
+
+	if jobs := splitDomains(domains, true); len(jobs) != 2 || len(jobs[0].Ipv4Domains) != 2 {
+		t.Errorf("按区域拆分的任务不正确: %+v", jobs)
+	}
+
+	// 已取消时不会请求DNS服务商
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := updateDomains(ctx, conf, domains)
+	if result.Ipv4Addr != "1.1.1.1" || result.Ipv6Addr != "::1" || len(result.Ipv4Domains) != 2 || len(result.Ipv6Domains) != 1 {
+		t.Fatalf("合并的结果不正确: %+v", result)
+	}
+	for i, sub := range []string{"a", "b"} {
+		if result.Ipv4Domains[i].SubDomain != sub {
+			t.Errorf("域名的顺序不正确: %s", result.Ipv4Domains[i])
+		}
+	}
+}
+
+// TestSerialUpdater Callback、自定义程序及插件不拆分
+func TestSerialUpdater(t *testing.T) {
+	for _, name := range []string{"callback", "exec", "plugin-example"} {
+		if _, ok := NewDNS(name).(serialUpdater); !ok {
+			t.Errorf("%s 应依次更新", name)
+		}
+	}
+	if _, ok := NewDNS("cloudflare").(serialUpdater); ok {
+		t.Error("cloudflare 可同时更新")
+	}
+}
+
+// TestSplitSources 单独设置了获取IP方式的域名分开更新
+func TestSplitSources(t *testing.T) {
+	lan := config.DomainSource{NetInterface: "eth0"}
+	domains := config.Domains{
+		Ipv4Addr: "1.1.1.1",
+		Ipv4Domains: []*config.Domain{
+			{DomainName: "example.com", SubDomain: "a"},
+			{DomainName: "example.com", SubDomain: "lan", Source: lan, IP: "192.168.1.2"},
+			{DomainName: "example.com", SubDomain: "nas", Source: lan, IP: "192.168.1.2"},
+		},
+	}
+	groups := splitSources(domains)
+	if len(groups) != 2 || groups[0].Ipv4Addr != "1.1.1.1" || len(groups[0].Ipv4Domains) != 1 {
+		t.Fatalf("拆分的结果不正确: %+v", groups)
+	}
+	if groups[1].Ipv4Addr != "192.168.1.2" || len(groups[1].Ipv4Domains) != 2 {
+		t.Errorf("单独设置的域名应在一组: %+v", groups[1])
+	}
+	if ip := domains.IPFor("A", domains.Ipv4Domains[1]); ip != "192.168.1.2" {
+		t.Errorf("域名的IP不正确: %s", ip)
+	}
+
+	// 都单独设置了获取IP方式时没有第一组
+	domains.Ipv4Domains = domains.Ipv4Domains[1:]
+	if groups := splitSources(domains); len(groups) != 1 || groups[0].Ipv4Addr != "192.168.1.2" {
+		t.Errorf("拆分的结果不正确: %+v", groups)
+	}
+}
@@ -1,11 +1,14 @@
 package dns
 
 import (
+	"context"
 	"ddns-go/config"
 	"ddns-go/util"
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -13,6 +16,8 @@ const (
 	recordListAPI   string = "https://dnsapi.cn/Record.List"
 	recordModifyURL string = "https://dnsapi.cn/Record.Modify"
 	recordCreateAPI string = "https://dnsapi.cn/Record.Create"
+	recordRemoveAPI string = "https://dnsapi.cn/Record.Remove"
+	userDetailAPI   string = "https://dnsapi.cn/User.Detail"
 )
 
 // https://cloud.tencent.com/document/api/302/8516
@@ -21,6 +26,7 @@ type Dnspod struct {
 	DNSConfig config.DNSConfig
 	Domains   config.Domains
 	TTL       string
+	ctx       context.Context
 }
 
 // DnspodRecordListResp recordListAPI结果
@@ -44,9 +50,10 @@ type DnspodStatus struct {
 }
 
 // Init 初始化
-func (dnspod *Dnspod) Init(conf *config.Config) {
+func (dnspod *Dnspod) Init(ctx context.Context, conf *config.Config, domains config.Domains) {
+	dnspod.ctx = ctx
 	dnspod.DNSConfig = conf.DNS
-	dnspod.Domains.GetNewIp(conf)
+	dnspod.Domains = domains
 	if conf.TTL == "" {
 		// 默认600s
 		dnspod.TTL = "600"
@@ -70,11 +77,26 @@ func (dnspod *Dnspod) addUpdateDomainRecords(recordType string) {
 	}
 
 	for _, domain := range domains {
+		key := recordCacheKey(dnspod.DNSConfig, recordType, domain)
+		if updateCachedRecord(dnspod.ctx, key, recordType, domain, ipAddr, func(record cachedRecord) error {
+			status, err := dnspod.update(record.ID, domain, recordType, ipAddr)
+			if err == nil && status.Status.Code != "1" {
+				err = fmt.Errorf("Code: %s, Message: %s", status.Status.Code, status.Status.Message)
+			}
+			return err
+		}) {
+			continue
+		}
+
 		result, err := dnspod.getRecordList(domain, recordType)
 		if err != nil {
 			return
 		}
 
+		if len(result.Records) == 1 {
+			stateFrom(dnspod.ctx).setCachedRecord(key, cachedRecord{ID: result.Records[0].ID, Value: result.Records[0].Value}, time.Now())
+		}
+
 		if len(result.Records) > 0 {
 			// 更新
 			dnspod.modify(result, domain, recordType, ipAddr)
@@ -87,6 +109,9 @@ func (dnspod *Dnspod) addUpdateDomainRecords(recordType string) {
 
 // 创建
 func (dnspod *Dnspod) create(result DnspodRecordListResp, domain *config.Domain, recordType string, ipAddr string) {
+	if dryRunPlan(dnspod.ctx, recordType, domain, "", ipAddr) {
+		return
+	}
 	status, err := dnspod.commonRequest(
 		recordCreateAPI,
 		url.Values{
@@ -107,6 +132,7 @@ func (dnspod *Dnspod) create(result DnspodRecordListResp, domain *config.Domain,
 	} else {
 		log.Printf("新增域名解析 %s 失败！Code: %s, Message: %s", domain, status.Status.Code, status.Status.Message)
 		domain.UpdateStatus = config.UpdatedFailed
+		domain.Error = fmt.Sprintf("新增域名解析失败: Code: %s, Message: %s", status.Status.Code, status.Status.Message)
 	}
 }
 
@@ -118,43 +144,63 @@ func (dnspod *Dnspod) modify(result DnspodRecordListResp, domain *config.Domain,
 			log.Printf("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
 			continue
 		}
-		status, err := dnspod.commonRequest(
-			recordModifyURL,
-			url.Values{
-				"login_token": {dnspod.DNSConfig.ID + "," + dnspod.DNSConfig.Secret},
-				"domain":      {domain.DomainName},
-				"sub_domain":  {domain.GetSubDomain()},
-				"record_type": {recordType},
-				"record_line": {"默认"},
-				"record_id":   {record.ID},
-				"value":       {ipAddr},
-				"ttl":         {dnspod.TTL},
-				"format":      {"json"},
-			},
-			domain,
-		)
+		if dryRunPlan(dnspod.ctx, recordType, domain, record.Value, ipAddr) {
+			continue
+		}
+		status, err := dnspod.update(record.ID, domain, recordType, ipAddr)
 		if err == nil && status.Status.Code == "1" {
 			log.Printf("更新域名解析 %s 成功！IP: %s", domain, ipAddr)
 			domain.UpdateStatus = config.UpdatedSuccess
+			if len(result.Records) == 1 {
+				stateFrom(dnspod.ctx).setCachedRecord(recordCacheKey(dnspod.DNSConfig, recordType, domain), cachedRecord{ID: record.ID, Value: ipAddr}, time.Now())
+			}
 		} else {
 			log.Printf("更新域名解析 %s 失败！Code: %s, Message: %s", domain, status.Status.Code, status.Status.Message)
 			domain.UpdateStatus = config.UpdatedFailed
+			domain.Error = fmt.Sprintf("更新域名解析失败: Code: %s, Message: %s", status.Status.Code, status.Status.Message)
 		}
 	}
 }
 
+// update 修改记录的值
+func (dnspod *Dnspod) update(recordID string, domain *config.Domain, recordType string, ipAddr string) (DnspodStatus, error) {
+	return dnspod.commonRequest(
+		recordModifyURL,
+		url.Values{
+			"login_token": {dnspod.DNSConfig.ID + "," + dnspod.DNSConfig.Secret},
+			"domain":      {domain.DomainName},
+			"sub_domain":  {domain.GetSubDomain()},
+			"record_type": {recordType},
+			"record_line": {"默认"},
+			"record_id":   {recordID},
+			"value":       {ipAddr},
+			"ttl":         {dnspod.TTL},
+			"format":      {"json"},
+		},
+		domain,
+	)
+}
+
 // 公共
 func (dnspod *Dnspod) commonRequest(apiAddr string, values url.Values, domain *config.Domain) (status DnspodStatus, err error) {
-	resp, err := http.PostForm(
-		apiAddr,
-		values,
-	)
+	client := providerClient(dnspod.DNSConfig)
+	resp, err := dnspod.postForm(client, apiAddr, values)
 
 	err = util.GetHTTPResponse(resp, apiAddr, err, &status)
 
 	return
 }
 
+// postForm 提交表单, ctx取消时停止请求
+func (dnspod *Dnspod) postForm(client *http.Client, apiAddr string, values url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(dnspod.ctx, "POST", apiAddr, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return client.Do(req)
+}
+
 // 获得域名记录列表
 func (dnspod *Dnspod) getRecordList(domain *config.Domain, typ string) (result DnspodRecordListResp, err error) {
 	values := url.Values{
@@ -165,13 +211,94 @@ func (dnspod *Dnspod) getRecordList(domain *config.Domain, typ string) (result D
 		"format":      {"json"},
 	}
 
-	client := http.Client{Timeout: 10 * time.Second}
-	resp, err := client.PostForm(
-		recordListAPI,
-		values,
-	)
+	client := providerClient(dnspod.DNSConfig)
+	resp, err := dnspod.postForm(client, recordListAPI, values)
 
 	err = util.GetHTTPResponse(resp, recordListAPI, err, &result)
 
 	return
 }
+
+// checkAuth 查询用户信息校验ID/Token
+func (dnspod *Dnspod) checkAuth(ctx context.Context, dnsConf config.DNSConfig) error {
+	dnspod.ctx = ctx
+	dnspod.DNSConfig = dnsConf
+	status, err := dnspod.commonRequest(
+		userDetailAPI,
+		url.Values{
+			"login_token": {dnspod.DNSConfig.ID + "," + dnspod.DNSConfig.Secret},
+			"format":      {"json"},
+		},
+		nil,
+	)
+	if err == nil && status.Status.Code != "1" {
+		err = fmt.Errorf("Code: %s, Message: %s", status.Status.Code, status.Status.Message)
+	}
+	return err
+}
+
+// addTXTRecord 添加ACME验证的TXT记录
+func (dnspod *Dnspod) addTXTRecord(ctx context.Context, dnsConf config.DNSConfig, domain *config.Domain, value string) error {
+	dnspod.ctx = ctx
+	dnspod.DNSConfig = dnsConf
+	result, err := dnspod.getRecordList(domain, "TXT")
+	if err != nil {
+		return err
+	}
+	for _, record := range result.Records {
+		if txtValue(record.Value) == value {
+			return nil
+		}
+	}
+
+	status, err := dnspod.commonRequest(
+		recordCreateAPI,
+		url.Values{
+			"login_token": {dnspod.DNSConfig.ID + "," + dnspod.DNSConfig.Secret},
+			"domain":      {domain.DomainName},
+			"sub_domain":  {domain.GetSubDomain()},
+			"record_type": {"TXT"},
+			"record_line": {"默认"},
+			"value":       {value},
+			"ttl":         {"600"},
+			"format":      {"json"},
+		},
+		domain,
+	)
+	if err == nil && status.Status.Code != "1" {
+		err = fmt.Errorf("Code: %s, Message: %s", status.Status.Code, status.Status.Message)
+	}
+	return err
+}
+
+// deleteTXTRecord 删除ACME验证的TXT记录
+func (dnspod *Dnspod) deleteTXTRecord(ctx context.Context, dnsConf config.DNSConfig, domain *config.Domain, value string) error {
+	dnspod.ctx = ctx
+	dnspod.DNSConfig = dnsConf
+	result, err := dnspod.getRecordList(domain, "TXT")
+	if err != nil {
+		return err
+	}
+	for _, record := range result.Records {
+		if txtValue(record.Value) != value {
+			continue
+		}
+		status, err := dnspod.commonRequest(
+			recordRemoveAPI,
+			url.Values{
+				"login_token": {dnspod.DNSConfig.ID + "," + dnspod.DNSConfig.Secret},
+				"domain":      {domain.DomainName},
+				"record_id":   {record.ID},
+				"format":      {"json"},
+			},
+			domain,
+		)
+		if err == nil && status.Status.Code != "1" {
+			err = fmt.Errorf("Code: %s, Message: %s", status.Status.Code, status.Status.Message)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
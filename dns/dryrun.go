@@ -0,0 +1,47 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"log"
+)
+
+// 启动参数-dry-run
+var dryRunFlag bool
+
+// dryRunKey 本次更新是否为试运行, 随ctx传递
+type dryRunKey struct{}
+
+// SetDryRun 试运行, 只输出计划的修改, 不修改解析记录
+func SetDryRun(enable bool) {
+	dryRunFlag = enable
+}
+
+// IsDryRun 是否为试运行, 启动参数或配置中启用
+func IsDryRun(conf *config.Config) bool {
+	return dryRunFlag || conf.DryRun
+}
+
+// withDryRun 返回标记了本次更新是否为试运行的ctx
+func withDryRun(ctx context.Context, dry bool) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, dry)
+}
+
+// isDryRun 本次更新是否为试运行
+func isDryRun(ctx context.Context) bool {
+	dry, _ := ctx.Value(dryRunKey{}).(bool)
+	return dry
+}
+
+// dryRunPlan 试运行时输出计划的修改并返回true, 此时不调用接口修改解析记录. oldIP为空时为新增
+func dryRunPlan(ctx context.Context, recordType string, domain *config.Domain, oldIP string, newIP string) bool {
+	if !isDryRun(ctx) {
+		return false
+	}
+	if oldIP == "" {
+		log.Printf("[试运行] 将新增 %s %s %s\n", recordType, domain, newIP)
+	} else {
+		log.Printf("[试运行] 将更新 %s %s %s → %s\n", recordType, domain, oldIP, newIP)
+	}
+	return true
+}
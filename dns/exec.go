@@ -0,0 +1,157 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"ddns-go/config"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// 自定义程序的超时时间
+var execTimeout = time.Minute
+
+// Exec 调用自定义的程序更新解析记录, 用于不支持的DNS服务商
+// ID为程序的路径, Secret通过环境变量 DDNS_GO_SECRET 传递
+type Exec struct {
+	DNSConfig config.DNSConfig
+	Domains   config.Domains
+	TTL       string
+	ctx       context.Context
+}
+
+// ExecRequest 通过标准输入传给程序的JSON
+type ExecRequest struct {
+	Domain     string `json:"domain"`
+	DomainName string `json:"domainName"`
+	SubDomain  string `json:"subDomain"`
+	RecordType string `json:"recordType"`
+	IP         string `json:"ip"`
+	// 上次成功更新的IP, 重启后为空
+	OldIP string `json:"oldIP"`
+	TTL   string `json:"ttl"`
+}
+
+// ExecResponse 程序在标准输出中返回的JSON, 不是JSON时按退出码判断是否成功
+type ExecResponse struct {
+	// success, nothing(无需更新) 或 failed
+	Result  string `json:"result"`
+	Message string `json:"message"`
+}
+
+// serialUpdate 自定义程序不一定支持同时运行多个
+func (e *Exec) serialUpdate() {}
+
+// Init 初始化
+func (e *Exec) Init(ctx context.Context, conf *config.Config, domains config.Domains) {
+	e.ctx = ctx
+	e.DNSConfig = conf.DNS
+	e.Domains = domains
+	if conf.TTL == "" {
+		// 默认600
+		e.TTL = "600"
+	} else {
+		e.TTL = conf.TTL
+	}
+}
+
+// AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
+func (e *Exec) AddUpdateDomainRecords() config.Domains {
+	e.addUpdateDomainRecords("A")
+	e.addUpdateDomainRecords("AAAA")
+	return e.Domains
+}
+
+func (e *Exec) addUpdateDomainRecords(recordType string) {
+	ipAddr, domains := e.Domains.GetNewIpResult(recordType)
+	if ipAddr == "" {
+		return
+	}
+
+	for _, domain := range domains {
+		key := recordType + " " + e.DNSConfig.ID + " " + domain.String()
+		s := stateFrom(e.ctx)
+		s.execLastIPLock.Lock()
+		lastIP := s.execLastIP[key]
+		s.execLastIPLock.Unlock()
+		if lastIP == ipAddr {
+			log.Printf("你的IP %s 没有变化, 域名 %s\n", ipAddr, domain)
+			domain.UpdateStatus = config.UpdatedNothing
+			continue
+		}
+		if dryRunPlan(e.ctx, recordType, domain, lastIP, ipAddr) {
+			continue
+		}
+
+		resp, err := e.run(ExecRequest{
+			Domain:     domain.String(),
+			DomainName: domain.DomainName,
+			SubDomain:  domain.GetSubDomain(),
+			RecordType: recordType,
+			IP:         ipAddr,
+			OldIP:      lastIP,
+			TTL:        e.TTL,
+		})
+		if err != nil {
+			log.Printf("调用程序更新域名 %s 失败! 异常信息: %s\n", domain, err)
+			domain.UpdateStatus = config.UpdatedFailed
+			domain.Error = err.Error()
+			continue
+		}
+
+		switch resp.Result {
+		case "failed":
+			log.Printf("调用程序更新域名 %s 失败! 返回: %s\n", domain, resp.Message)
+			domain.UpdateStatus = config.UpdatedFailed
+			domain.Error = resp.Message
+			continue
+		case "nothing":
+			log.Printf("你的IP %s 没有变化, 域名 %s\n", ipAddr, domain)
+			domain.UpdateStatus = config.UpdatedNothing
+		default:
+			log.Printf("调用程序更新域名 %s 成功! IP: %s %s\n", domain, ipAddr, resp.Message)
+			domain.UpdateStatus = config.UpdatedSuccess
+		}
+		s.execLastIPLock.Lock()
+		s.execLastIP[key] = ipAddr
+		s.execLastIPLock.Unlock()
+	}
+}
+
+// run 运行程序, 标准输入为请求的JSON
+func (e *Exec) run(request ExecRequest) (resp ExecResponse, err error) {
+	ctx, cancel := context.WithTimeout(e.ctx, execTimeout)
+	defer cancel()
+
+	input, _ := json.Marshal(request)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.DNSConfig.ID)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "DDNS_GO_SECRET="+e.DNSConfig.Secret)
+	err = cmd.Run()
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		log.Printf("程序 %s 的错误输出: %s\n", e.DNSConfig.ID, msg)
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+	if json.Unmarshal(output, &resp) != nil {
+		resp = ExecResponse{Message: string(output)}
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return resp, fmt.Errorf("运行超过%s", execTimeout)
+		}
+		if resp.Message != "" {
+			return resp, fmt.Errorf("%s, %s", err, resp.Message)
+		}
+		return resp, err
+	}
+	return resp, nil
+}
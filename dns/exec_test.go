@@ -0,0 +1,59 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestExec 通过标准输入传递请求, 根据标准输出或退出码判断结果
+func TestExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要sh")
+	}
+	dir := t.TempDir()
+	script := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte("#!/bin/sh\n"+content+"\n"), 0700)
+		return path
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"success", `grep -q '"ip":"1.2.3.4"' && [ "$DDNS_GO_SECRET" = "secret" ] && echo '{"result":"success"}'`, config.UpdatedSuccess},
+		{"nothing", `echo '{"result":"nothing"}'`, string(config.UpdatedNothing)},
+		{"failed", `echo '{"result":"failed","message":"error"}'`, config.UpdatedFailed},
+		{"exit", `echo error; exit 1`, config.UpdatedFailed},
+		{"plain", `echo ok`, config.UpdatedSuccess},
+	}
+	for _, tt := range tests {
+		domain := &config.Domain{DomainName: "example.com", SubDomain: tt.name}
+		e := &Exec{
+			DNSConfig: config.DNSConfig{ID: script(tt.name, tt.script), Secret: "secret"},
+			Domains:   config.Domains{Ipv4Addr: "1.2.3.4", Ipv4Domains: []*config.Domain{domain}},
+			ctx:       context.Background(),
+		}
+		e.addUpdateDomainRecords("A")
+		if string(domain.UpdateStatus) != tt.want {
+			t.Errorf("%s: 更新状态为 %q, 应为 %q", tt.name, domain.UpdateStatus, tt.want)
+		}
+	}
+
+	// 同一程序的IP未变化时不再调用
+	domain := &config.Domain{DomainName: "example.com", SubDomain: "success"}
+	e := &Exec{
+		DNSConfig: config.DNSConfig{ID: script("success", "exit 1")},
+		Domains:   config.Domains{Ipv4Addr: "1.2.3.4", Ipv4Domains: []*config.Domain{domain}},
+		ctx:       context.Background(),
+	}
+	e.addUpdateDomainRecords("A")
+	if domain.UpdateStatus != config.UpdatedNothing {
+		t.Errorf("IP未变化时应不调用程序, 更新状态为 %q", domain.UpdateStatus)
+	}
+}
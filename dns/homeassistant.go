@@ -0,0 +1,49 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"ddns-go/util"
+	"log"
+	"time"
+)
+
+// publishHomeAssistant 作为Home Assistant加载项运行时, 每次更新后设置IP及更新状态的实体
+func publishHomeAssistant(ctx context.Context) {
+	status := GetStatus()
+	failed := []string{}
+	for _, ds := range status.Domains {
+		if ds.LastResult == config.UpdatedFailed {
+			failed = append(failed, ds.RecordType+" "+ds.Domain)
+		}
+	}
+	result := "ok"
+	if len(failed) > 0 {
+		result = "failed"
+	}
+
+	states := []struct {
+		entityID   string
+		state      string
+		attributes map[string]interface{}
+	}{
+		{"sensor.ddns_go_ipv4", status.Ipv4Addr, map[string]interface{}{"friendly_name": "ddns-go IPv4", "icon": "mdi:ip-network"}},
+		{"sensor.ddns_go_ipv6", status.Ipv6Addr, map[string]interface{}{"friendly_name": "ddns-go IPv6", "icon": "mdi:ip-network"}},
+		{"sensor.ddns_go_status", result, map[string]interface{}{
+			"friendly_name":  "ddns-go",
+			"icon":           "mdi:dns",
+			"provider":       status.Provider,
+			"last_run":       status.LastRun.Format(time.RFC3339),
+			"failed_domains": failed,
+		}},
+	}
+	for _, s := range states {
+		if s.state == "" {
+			s.state = "unknown"
+		}
+		if err := util.SetHomeAssistantState(ctx, s.entityID, s.state, s.attributes); err != nil {
+			log.Println(err)
+			return
+		}
+	}
+}
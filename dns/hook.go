@@ -0,0 +1,105 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 更新前后运行的命令的超时时间
+var hookTimeout = time.Minute
+
+// runBeforeHook 获取IP前运行命令, 失败时只输出日志, 仍继续更新
+func runBeforeHook(ctx context.Context, conf *config.Config) {
+	if conf.Hooks.Before == "" {
+		return
+	}
+	st := GetStatus()
+	runHook(ctx, "更新前", conf.Hooks.Before, []string{
+		"DDNS_GO_PROVIDER=" + conf.DNS.Name,
+		"DDNS_GO_OLD_IPV4=" + st.Ipv4Addr,
+		"DDNS_GO_OLD_IPV6=" + st.Ipv6Addr,
+	})
+}
+
+// runAfterHooks 有域名更新成功或失败时分别运行命令
+func runAfterHooks(ctx context.Context, conf *config.Config, results []DomainResult) {
+	hooks := []struct {
+		result  string
+		name    string
+		command string
+	}{
+		{config.UpdatedSuccess, "success", conf.Hooks.Success},
+		{config.UpdatedFailed, "failed", conf.Hooks.Failed},
+	}
+	for _, hook := range hooks {
+		if hook.command == "" {
+			continue
+		}
+		if env := hookEnv(conf.DNS.Name, hook.name, hook.result, results); env != nil {
+			runHook(ctx, "更新"+hook.result+"后", hook.command, env)
+		}
+	}
+}
+
+// hookEnv 结果为result的域名及IP的环境变量, 没有该结果的域名时返回nil
+func hookEnv(provider string, name string, result string, results []DomainResult) []string {
+	domains := map[string][]string{}
+	newIPs := map[string]string{}
+	oldIPs := map[string]string{}
+	for _, r := range results {
+		if r.Result != result {
+			continue
+		}
+		ipType := "IPV4"
+		if r.RecordType == "AAAA" {
+			ipType = "IPV6"
+		}
+		domains[ipType] = append(domains[ipType], r.Domain)
+		newIPs[ipType] = r.NewIP
+		if oldIPs[ipType] == "" {
+			oldIPs[ipType] = r.OldIP
+		}
+	}
+	if len(domains) == 0 {
+		return nil
+	}
+
+	env := []string{"DDNS_GO_PROVIDER=" + provider, "DDNS_GO_RESULT=" + name}
+	for _, ipType := range []string{"IPV4", "IPV6"} {
+		env = append(env,
+			"DDNS_GO_"+ipType+"="+newIPs[ipType],
+			"DDNS_GO_OLD_"+ipType+"="+oldIPs[ipType],
+			"DDNS_GO_"+ipType+"_DOMAINS="+strings.Join(domains[ipType], " "),
+		)
+	}
+	return env
+}
+
+// runHook 运行命令, 输出写入日志
+func runHook(ctx context.Context, name string, command string, env []string) {
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	output, err := cmd.CombinedOutput()
+	if msg := strings.TrimSpace(string(output)); msg != "" {
+		log.Printf("%s的命令输出: %s\n", name, msg)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("%s的命令运行超过%s, 已结束\n", name, hookTimeout)
+	} else if err != nil {
+		log.Printf("%s的命令运行失败: %s\n", name, err)
+	}
+}
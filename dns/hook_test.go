@@ -0,0 +1,54 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestHookEnv 只传递结果相同的域名
+func TestHookEnv(t *testing.T) {
+	results := []DomainResult{
+		{Domain: "a.example.com", RecordType: "A", OldIP: "1.1.1.1", NewIP: "2.2.2.2", Result: config.UpdatedSuccess},
+		{Domain: "b.example.com", RecordType: "A", OldIP: "1.1.1.1", NewIP: "2.2.2.2", Result: config.UpdatedSuccess},
+		{Domain: "c.example.com", RecordType: "AAAA", NewIP: "::1", Result: config.UpdatedFailed},
+		{Domain: "d.example.com", RecordType: "A", OldIP: "2.2.2.2", NewIP: "2.2.2.2", Result: string(config.UpdatedNothing)},
+	}
+
+	env := strings.Join(hookEnv("alidns", "success", config.UpdatedSuccess, results), "\n")
+	for _, want := range []string{"DDNS_GO_RESULT=success", "DDNS_GO_IPV4=2.2.2.2", "DDNS_GO_OLD_IPV4=1.1.1.1",
+		"DDNS_GO_IPV4_DOMAINS=a.example.com b.example.com", "DDNS_GO_IPV6_DOMAINS=\n"} {
+		if !strings.Contains(env+"\n", want) {
+			t.Errorf("环境变量中没有 %q:\n%s", want, env)
+		}
+	}
+	env = strings.Join(hookEnv("alidns", "failed", config.UpdatedFailed, results), "\n")
+	if !strings.Contains(env, "DDNS_GO_IPV6_DOMAINS=c.example.com") || !strings.Contains(env, "DDNS_GO_IPV4_DOMAINS=\n") {
+		t.Errorf("失败的环境变量不正确:\n%s", env)
+	}
+	if hookEnv("alidns", "success", config.UpdatedSuccess, results[2:]) != nil {
+		t.Error("没有更新成功的域名时不应运行")
+	}
+}
+
+// TestRunAfterHooks 有更新成功的域名时运行命令
+func TestRunAfterHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要sh")
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	conf := &config.Config{DNS: config.DNSConfig{Name: "alidns"}}
+	conf.Hooks.Success = `echo "$DDNS_GO_RESULT $DDNS_GO_IPV4 $DDNS_GO_IPV4_DOMAINS" > ` + out
+	conf.Hooks.Failed = "exit 1"
+	runAfterHooks(context.Background(), conf, []DomainResult{
+		{Domain: "a.example.com", RecordType: "A", NewIP: "2.2.2.2", Result: config.UpdatedSuccess},
+	})
+	byt, err := ioutil.ReadFile(out)
+	if err != nil || string(byt) != "success 2.2.2.2 a.example.com\n" {
+		t.Errorf("命令的输出不正确: %q %v", byt, err)
+	}
+}
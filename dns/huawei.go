@@ -2,6 +2,7 @@ package dns
 
 import (
 	"bytes"
+	"context"
 	"ddns-go/config"
 	"ddns-go/util"
 	"encoding/json"
@@ -22,6 +23,7 @@ type Huaweicloud struct {
 	DNSConfig config.DNSConfig
 	Domains   config.Domains
 	TTL       int
+	ctx       context.Context
 }
 
 // HuaweicloudZonesResp zones response
@@ -50,9 +52,10 @@ type HuaweicloudRecordsets struct {
 }
 
 // Init 初始化
-func (hw *Huaweicloud) Init(conf *config.Config) {
+func (hw *Huaweicloud) Init(ctx context.Context, conf *config.Config, domains config.Domains) {
+	hw.ctx = ctx
 	hw.DNSConfig = conf.DNS
-	hw.Domains.GetNewIp(conf)
+	hw.Domains = domains
 	if conf.TTL == "" {
 		// 默认300s
 		hw.TTL = 300
@@ -81,6 +84,16 @@ func (hw *Huaweicloud) addUpdateDomainRecords(recordType string) {
 	}
 
 	for _, domain := range domains {
+		key := recordCacheKey(hw.DNSConfig, recordType, domain)
+		if updateCachedRecord(hw.ctx, key, recordType, domain, ipAddr, func(record cachedRecord) error {
+			result, err := hw.update(record.ZoneID, record.ID, ipAddr)
+			if err == nil && !(len(result.Records) > 0 && result.Records[0] == ipAddr) {
+				err = fmt.Errorf("Status: %s", result.Status)
+			}
+			return err
+		}) {
+			continue
+		}
 
 		var records HuaweicloudRecordsResp
 
@@ -99,6 +112,9 @@ func (hw *Huaweicloud) addUpdateDomainRecords(recordType string) {
 		for _, record := range records.Recordsets {
 			// 名称相同才更新。华为云默认是模糊搜索
 			if record.Name == domain.String()+"." {
+				if len(record.Records) == 1 {
+					stateFrom(hw.ctx).setCachedRecord(key, cachedRecord{ZoneID: record.ZoneID, ID: record.ID, Value: record.Records[0]}, time.Now())
+				}
 				// 更新
 				hw.modify(record, domain, recordType, ipAddr)
 				find = true
@@ -133,6 +149,10 @@ func (hw *Huaweicloud) create(domain *config.Domain, recordType string, ipAddr s
 		}
 	}
 
+	if dryRunPlan(hw.ctx, recordType, domain, "", ipAddr) {
+		return
+	}
+
 	record := &HuaweicloudRecordsets{
 		Type:    recordType,
 		Name:    domain.String() + ".",
@@ -152,6 +172,7 @@ func (hw *Huaweicloud) create(domain *config.Domain, recordType string, ipAddr s
 	} else {
 		log.Printf("新增域名解析 %s 失败！Status: %s", domain, result.Status)
 		domain.UpdateStatus = config.UpdatedFailed
+		domain.Error = fmt.Sprintf("新增域名解析失败: Status: %s", result.Status)
 	}
 }
 
@@ -163,29 +184,42 @@ func (hw *Huaweicloud) modify(record HuaweicloudRecordsets, domain *config.Domai
 		log.Printf("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
 		return
 	}
+	oldIP := ""
+	if len(record.Records) > 0 {
+		oldIP = record.Records[0]
+	}
+	if dryRunPlan(hw.ctx, recordType, domain, oldIP, ipAddr) {
+		return
+	}
 
-	var request map[string]interface{} = make(map[string]interface{})
-	request["records"] = []string{ipAddr}
-	request["ttl"] = hw.TTL
-
-	var result HuaweicloudRecordsets
-
-	err := hw.request(
-		"PUT",
-		fmt.Sprintf(huaweicloudEndpoint+"/v2/zones/%s/recordsets/%s", record.ZoneID, record.ID),
-		&request,
-		&result,
-	)
+	result, err := hw.update(record.ZoneID, record.ID, ipAddr)
 
 	if err == nil && (len(result.Records) > 0 && result.Records[0] == ipAddr) {
 		log.Printf("更新域名解析 %s 成功！IP: %s, 状态: %s", domain, ipAddr, result.Status)
 		domain.UpdateStatus = config.UpdatedSuccess
+		stateFrom(hw.ctx).setCachedRecord(recordCacheKey(hw.DNSConfig, recordType, domain), cachedRecord{ZoneID: record.ZoneID, ID: record.ID, Value: ipAddr}, time.Now())
 	} else {
 		log.Printf("更新域名解析 %s 失败！Status: %s", domain, result.Status)
 		domain.UpdateStatus = config.UpdatedFailed
+		domain.Error = fmt.Sprintf("更新域名解析失败: Status: %s", result.Status)
 	}
 }
 
+// update 修改记录集的值
+func (hw *Huaweicloud) update(zoneID string, recordID string, ipAddr string) (result HuaweicloudRecordsets, err error) {
+	var request map[string]interface{} = make(map[string]interface{})
+	request["records"] = []string{ipAddr}
+	request["ttl"] = hw.TTL
+
+	err = hw.request(
+		"PUT",
+		fmt.Sprintf(huaweicloudEndpoint+"/v2/zones/%s/recordsets/%s", zoneID, recordID),
+		&request,
+		&result,
+	)
+	return
+}
+
 // 获得域名记录列表
 func (hw *Huaweicloud) getZones(domain *config.Domain) (result HuaweicloudZonesResp, err error) {
 	err = hw.request(
@@ -205,7 +239,8 @@ func (hw *Huaweicloud) request(method string, url string, data interface{}, resu
 		jsonStr, _ = json.Marshal(data)
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		hw.ctx,
 		method,
 		url,
 		bytes.NewBuffer(jsonStr),
@@ -224,9 +259,117 @@ func (hw *Huaweicloud) request(method string, url string, data interface{}, resu
 
 	req.Header.Add("content-type", "application/json")
 
-	client := http.Client{Timeout: 10 * time.Second}
+	client := providerClient(hw.DNSConfig)
 	resp, err := client.Do(req)
 	err = util.GetHTTPResponse(resp, url, err, result)
 
 	return
 }
+
+// checkAuth 查询区域列表校验ID/Secret
+func (hw *Huaweicloud) checkAuth(ctx context.Context, dnsConf config.DNSConfig) error {
+	hw.ctx = ctx
+	hw.DNSConfig = dnsConf
+	var result HuaweicloudZonesResp
+	return hw.request("GET", huaweicloudEndpoint+"/v2/zones?limit=1", nil, &result)
+}
+
+// addTXTRecord 添加ACME验证的TXT记录, 同名的记录集中已有其它值时追加
+func (hw *Huaweicloud) addTXTRecord(ctx context.Context, dnsConf config.DNSConfig, domain *config.Domain, value string) error {
+	hw.ctx = ctx
+	hw.DNSConfig = dnsConf
+	quoted := strconv.Quote(value)
+	record, err := hw.getTXTRecordset(domain)
+	if err != nil {
+		return err
+	}
+	if record != nil {
+		for _, v := range record.Records {
+			if txtValue(v) == value {
+				return nil
+			}
+		}
+		return hw.putTXTRecords(record, append(record.Records, quoted))
+	}
+
+	zone, err := hw.getZones(domain)
+	if err != nil {
+		return err
+	}
+	zoneID := ""
+	for _, z := range zone.Zones {
+		if z.Name == domain.DomainName+"." {
+			zoneID = z.ID
+		}
+	}
+	if zoneID == "" {
+		return fmt.Errorf("未能找到公网域名 %s, 请检查域名是否添加", domain.DomainName)
+	}
+	var result HuaweicloudRecordsets
+	return hw.request(
+		"POST",
+		fmt.Sprintf(huaweicloudEndpoint+"/v2/zones/%s/recordsets", zoneID),
+		&HuaweicloudRecordsets{Type: "TXT", Name: domain.String() + ".", Records: []string{quoted}, TTL: 300},
+		&result,
+	)
+}
+
+// deleteTXTRecord 删除ACME验证的TXT记录, 记录集中没有其它值时删除记录集
+func (hw *Huaweicloud) deleteTXTRecord(ctx context.Context, dnsConf config.DNSConfig, domain *config.Domain, value string) error {
+	hw.ctx = ctx
+	hw.DNSConfig = dnsConf
+	record, err := hw.getTXTRecordset(domain)
+	if err != nil || record == nil {
+		return err
+	}
+	var remain []string
+	for _, v := range record.Records {
+		if txtValue(v) != value {
+			remain = append(remain, v)
+		}
+	}
+	if len(remain) == len(record.Records) {
+		return nil
+	}
+	if len(remain) > 0 {
+		return hw.putTXTRecords(record, remain)
+	}
+	var result HuaweicloudRecordsets
+	return hw.request(
+		"DELETE",
+		fmt.Sprintf(huaweicloudEndpoint+"/v2/zones/%s/recordsets/%s", record.ZoneID, record.ID),
+		nil,
+		&result,
+	)
+}
+
+// getTXTRecordset 获得名称相同的TXT记录集, 没有时返回nil
+func (hw *Huaweicloud) getTXTRecordset(domain *config.Domain) (*HuaweicloudRecordsets, error) {
+	var records HuaweicloudRecordsResp
+	err := hw.request(
+		"GET",
+		fmt.Sprintf(huaweicloudEndpoint+"/v2/recordsets?type=TXT&name=%s", domain),
+		nil,
+		&records,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records.Recordsets {
+		// 华为云默认是模糊搜索
+		if record.Name == domain.String()+"." {
+			return &record, nil
+		}
+	}
+	return nil, nil
+}
+
+func (hw *Huaweicloud) putTXTRecords(record *HuaweicloudRecordsets, values []string) error {
+	var result HuaweicloudRecordsets
+	return hw.request(
+		"PUT",
+		fmt.Sprintf(huaweicloudEndpoint+"/v2/zones/%s/recordsets/%s", record.ZoneID, record.ID),
+		map[string]interface{}{"records": values, "ttl": record.TTL},
+		&result,
+	)
+}
@@ -1,24 +1,118 @@
 package dns
 
 import (
+	"context"
 	"ddns-go/config"
+	"ddns-go/util"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
 	"time"
 )
 
-// DNS interface
+// DNS DNS服务商
 type DNS interface {
-	Init(conf *config.Config)
+	// 初始化, domains为已获取的IP及要更新的域名, ctx取消时停止更新. 只使用conf中DNS服务商、TTL及DNS服务器的配置
+	Init(ctx context.Context, conf *config.Config, domains config.Domains)
 	// 添加或更新IPv4/IPv6记录
 	AddUpdateDomainRecords() (domains config.Domains)
 }
 
-// RunTimer 定时运行
-func RunTimer(firstDelay time.Duration, delay time.Duration) {
-	time.Sleep(firstDelay)
+// RunningProvider 获得正在更新的DNS服务商名称, 未在更新返回空
+func RunningProvider() string {
+	name, _ := defaultState.runningProvider.Load().(string)
+	return name
+}
+
+// 更新使用的context, 取消时停止正在进行的更新
+var baseCtx = context.Background()
+var baseCtxLock sync.Mutex
+
+// SetContext 设置更新使用的context, 取消后停止正在进行的更新
+func SetContext(ctx context.Context) {
+	baseCtxLock.Lock()
+	defer baseCtxLock.Unlock()
+	baseCtx = ctx
+}
+
+func currentContext() context.Context {
+	baseCtxLock.Lock()
+	defer baseCtxLock.Unlock()
+	return baseCtx
+}
+
+// Wait 等待正在进行的更新及通知完成, ctx取消时不再等待
+func Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		defaultState.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sleep 等待d, ctx取消时返回false
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// RunTimer 定时运行, 配置中或cronExpr有cron表达式时按cron表达式运行, 否则间隔delay运行
+// jitter大于0时每次随机延迟0~jitter, 避免大量实例同时请求DNS服务商及获取IP的接口. ctx取消时停止定时运行
+func RunTimer(ctx context.Context, firstDelay time.Duration, delay time.Duration, cronExpr string, jitter time.Duration) {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	defaultState.setNextRun(time.Now().Add(firstDelay))
+	if !sleep(ctx, firstDelay) {
+		return
+	}
 	for {
-		RunOnce()
-		time.Sleep(delay)
+		runScheduled()
+		next := nextRunTime(time.Now(), delay, cronExpr)
+		if jitter > 0 {
+			next = next.Add(time.Duration(random.Int63n(int64(jitter))))
+		}
+		next = windowOpenTime(time.Now(), next)
+		defaultState.setNextRun(next)
+		if !sleep(ctx, time.Until(next)) {
+			log.Println("已停止定时更新")
+			return
+		}
+	}
+}
+
+// nextRunTime 获得下次运行的时间, 配置中的cron表达式优先
+func nextRunTime(now time.Time, delay time.Duration, cronExpr string) time.Time {
+	if conf, err := config.GetConfigCache(); err == nil && conf.Cron != "" {
+		cronExpr = conf.Cron
+	}
+	if cronExpr == "" {
+		return now.Add(delay)
+	}
+
+	schedule, err := util.ParseCron(cronExpr)
+	if err != nil {
+		log.Println(err, ", 使用同步间隔", delay)
+		return now.Add(delay)
+	}
+	next := schedule.Next(now)
+	if next.IsZero() {
+		log.Printf("cron表达式 %s 没有符合的时间, 使用同步间隔 %s\n", cronExpr, delay)
+		return now.Add(delay)
 	}
+	return next
 }
 
 // RunOnce RunOnce
@@ -27,24 +121,215 @@ func RunOnce() {
 	if err != nil {
 		return
 	}
+	run(currentContext(), &conf, true)
+}
+
+// runScheduled 定时更新, DNS服务商连续失败被暂停时不调用
+func runScheduled() {
+	conf, err := config.GetConfigCache()
+	if err != nil {
+		return
+	}
+	if ok, until := defaultState.breakerAllow(conf.DNS.Name, time.Now()); !ok {
+		log.Printf("%s 已暂停定时更新至 %s\n", conf.DNS.Name, util.FormatTime(until))
+		return
+	}
+	run(currentContext(), &conf, true)
+}
+
+// RunManual 手动立即更新, provider/domain不为空时只更新指定的DNS服务商/域名
+func RunManual(provider string, domain string) error {
+	conf, err := config.GetConfigCache()
+	if err != nil {
+		return err
+	}
+
+	if provider != "" && provider != conf.DNS.Name {
+		return fmt.Errorf("未配置DNS服务商 %s", provider)
+	}
+	if err := pausedError(&conf); err != nil {
+		return err
+	}
+	if err := windowError(&conf, time.Now()); err != nil {
+		return err
+	}
+
+	if domain == "" {
+		run(currentContext(), &conf, true)
+		return nil
+	}
+
+	conf.Ipv4.Domains = filterDomains(conf.Ipv4.Domains, domain)
+	conf.Ipv6.Domains = filterDomains(conf.Ipv6.Domains, domain)
+	if len(conf.Ipv4.Domains) == 0 && len(conf.Ipv6.Domains) == 0 {
+		return fmt.Errorf("未找到域名 %s", domain)
+	}
+	run(currentContext(), &conf, false)
+	return nil
+}
 
-	var dnsSelected DNS
-	switch conf.DNS.Name {
+// filterDomains 只保留指定的域名
+func filterDomains(domainArr []string, domain string) (result []string) {
+	for _, domainStr := range domainArr {
+		if name, _ := config.SplitDomainParams(domainStr); name == domain {
+			result = append(result, domainStr)
+		}
+	}
+	return
+}
+
+// run 更新域名解析, full为false时只更新了部分域名. ctx取消时停止更新, 不更新状态及通知
+func run(ctx context.Context, conf *config.Config, full bool) {
+	if ctx.Err() != nil {
+		return
+	}
+	defaultState.running.Add(1)
+	defer defaultState.running.Done()
+	defer func() {
+		if err := recover(); err != nil {
+			logPanic("更新", err)
+		}
+	}()
+
+	if err := pausedError(conf); err != nil {
+		log.Println(err)
+		return
+	}
+	if err := windowError(conf, time.Now()); err != nil {
+		log.Println(err)
+		return
+	}
+
+	defaultState.runningProvider.Store(conf.DNS.Name)
+	defer defaultState.runningProvider.Store("")
+
+	// 读取文件引用的ID/Secret
+	dnsConf, err := conf.DNS.Resolved()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	conf.DNS = dnsConf
+
+	dryRun := IsDryRun(conf)
+	ctx = withDryRun(ctx, dryRun)
+	if dryRun {
+		ctx = config.WithoutDampingUpdate(ctx)
+	}
+
+	// 追踪本次更新
+	ctx, span := util.StartSpan(ctx, "update")
+	defer span.End()
+	span.SetAttribute("dns.provider", conf.DNS.Name)
+
+	if dryRun && conf.Hooks.Before != "" {
+		log.Println("[试运行] 不运行更新前的命令")
+	} else {
+		runBeforeHook(ctx, conf)
+	}
+	domains := updateWithRetry(ctx, conf, func(ctx context.Context) config.Domains {
+		return safeUpdate(conf, func() config.Domains {
+			ctx, span := util.StartSpan(ctx, "provider "+conf.DNS.Name)
+			defer span.End()
+			var newDomains config.Domains
+			newDomains.GetNewIp(ctx, conf)
+			domains := updateDomains(ctx, conf, newDomains)
+			if providerFailed(&domains) {
+				span.SetError("更新失败")
+			}
+			return domains
+		})
+	})
+	if providerFailed(&domains) {
+		span.SetError("更新失败")
+	}
+	if ctx.Err() != nil {
+		log.Println("正在退出, 已取消更新")
+		return
+	}
+	updatePTR(ctx, conf, &domains)
+	if dryRun {
+		log.Println("试运行完成, 未修改解析记录")
+		return
+	}
+	domains.PausedUntil = defaultState.breakerRecord(conf.DNS.Name, providerFailed(&domains), time.Now())
+	results := defaultState.updateStatus(conf.DNS.Name, &domains, full)
+	handleResults(results)
+	saveHistory(results, conf.HistoryDays)
+	savePushed()
+	if conf.Resolver != "" {
+		defaultState.running.Add(1)
+		go func() {
+			defer defaultState.running.Done()
+			verifyResults(currentContext(), conf.Resolver, results)
+		}()
+	}
+	if conf.Hooks.Success != "" || conf.Hooks.Failed != "" {
+		defaultState.running.Add(1)
+		go func() {
+			defer defaultState.running.Done()
+			runAfterHooks(currentContext(), conf, results)
+		}()
+	}
+	if util.IsHomeAssistant() {
+		defaultState.running.Add(1)
+		go func() {
+			defer defaultState.running.Done()
+			publishHomeAssistant(currentContext())
+		}()
+	}
+	config.ExecNotify(ctx, &domains, conf)
+}
+
+// pausedError 暂停了更新时返回原因
+func pausedError(conf *config.Config) error {
+	if conf.Paused {
+		return errors.New("已暂停所有更新")
+	}
+	if conf.IsProviderPaused(conf.DNS.Name) {
+		return fmt.Errorf("已暂停DNS服务商 %s 的更新", conf.DNS.Name)
+	}
+	return nil
+}
+
+// windowError 不在允许更新的时间段内时返回原因
+func windowError(conf *config.Config, now time.Time) error {
+	if conf.UpdateAllowed(now) {
+		return nil
+	}
+	if next := conf.NextUpdateAllowed(now); !next.IsZero() {
+		return fmt.Errorf("当前不在允许更新的时间段内, 将在 %s 更新", util.FormatTime(next))
+	}
+	return errors.New("当前不在允许更新的时间段内")
+}
+
+// windowOpenTime 当前不允许更新时, 时间段开始早于下次运行则在开始时更新
+func windowOpenTime(now time.Time, next time.Time) time.Time {
+	conf, err := config.GetConfigCache()
+	if err != nil || conf.UpdateAllowed(now) {
+		return next
+	}
+	if open := conf.NextUpdateAllowed(now); !open.IsZero() && open.Before(next) {
+		return open
+	}
+	return next
+}
+
+// NewDNS 根据名称获得DNS服务商, 不支持的返回nil
+func NewDNS(name string) DNS {
+	switch name {
 	case "alidns":
-		dnsSelected = &Alidns{}
+		return &Alidns{}
 	case "dnspod":
-		dnsSelected = &Dnspod{}
+		return &Dnspod{}
 	case "cloudflare":
-		dnsSelected = &Cloudflare{}
+		return &Cloudflare{}
 	case "huaweicloud":
-		dnsSelected = &Huaweicloud{}
+		return &Huaweicloud{}
 	case "callback":
-		dnsSelected = &Callback{}
-	default:
-		dnsSelected = &Alidns{}
+		return &Callback{}
+	case "exec":
+		return &Exec{}
 	}
-	dnsSelected.Init(&conf)
-
-	domains := dnsSelected.AddUpdateDomainRecords()
-	config.ExecWebhook(&domains, &conf)
+	return newPluginDNS(name)
 }
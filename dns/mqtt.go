@@ -0,0 +1,133 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"ddns-go/util"
+	"log"
+	"strings"
+	"time"
+)
+
+// MQTT命令, 可在命令后加DNS服务商, 如 pause cloudflare
+const (
+	mqttCommandUpdate = "update"
+	mqttCommandPause  = "pause"
+	mqttCommandResume = "resume"
+)
+
+// MQTT连接断开后重新连接的间隔
+var mqttRetryInterval = 30 * time.Second
+
+// 检查MQTT配置是否修改的间隔
+var mqttConfigInterval = 5 * time.Second
+
+// RunMQTT 订阅配置的MQTT主题, 收到命令时立即更新或暂停/恢复更新. 配置修改后重新连接, ctx取消时停止
+func RunMQTT(ctx context.Context) {
+	for {
+		conf, _ := config.GetConfigCache()
+		if conf.MQTTURL == "" || conf.MQTTTopic == "" {
+			if !sleep(ctx, mqttConfigInterval) {
+				return
+			}
+			continue
+		}
+
+		subCtx, cancel := context.WithCancel(ctx)
+		go cancelOnMQTTChange(subCtx, cancel, conf.MQTTURL, conf.MQTTTopic)
+		log.Printf("订阅MQTT主题 %s\n", conf.MQTTTopic)
+		err := util.SubscribeMQTT(subCtx, conf.MQTTURL, conf.MQTTTopic, handleMQTTMessage)
+		changed := subCtx.Err() != nil
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if changed {
+			continue
+		}
+		log.Printf("MQTT连接断开: %s, %s后重新连接\n", err, mqttRetryInterval)
+		if !sleep(ctx, mqttRetryInterval) {
+			return
+		}
+	}
+}
+
+// cancelOnMQTTChange MQTT地址或主题修改时取消订阅
+func cancelOnMQTTChange(ctx context.Context, cancel context.CancelFunc, url string, topic string) {
+	ticker := time.NewTicker(mqttConfigInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			conf, _ := config.GetConfigCache()
+			if conf.MQTTURL != url || conf.MQTTTopic != topic {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// handleMQTTMessage 执行收到的命令, 忽略保留的消息, 避免每次连接时执行旧的命令
+func handleMQTTMessage(msg util.MQTTMessage) {
+	payload := strings.TrimSpace(string(msg.Payload))
+	if msg.Retained {
+		log.Printf("忽略MQTT主题 %s 中保留的消息: %s\n", msg.Topic, payload)
+		return
+	}
+	fields := strings.Fields(payload)
+	if len(fields) == 0 {
+		return
+	}
+	provider := ""
+	if len(fields) > 1 {
+		provider = fields[1]
+	}
+
+	switch command := strings.ToLower(fields[0]); command {
+	case mqttCommandUpdate:
+		log.Println("收到MQTT命令, 立即更新")
+		go RunOnce()
+	case mqttCommandPause, mqttCommandResume:
+		setPausedByMQTT(command, provider, command == mqttCommandPause)
+	default:
+		log.Printf("未知的MQTT命令: %s, 支持 update, pause, resume\n", payload)
+	}
+}
+
+// setPausedByMQTT 暂停或恢复更新并保存到配置文件, 恢复后立即更新
+func setPausedByMQTT(command string, provider string, paused bool) {
+	conf, err := config.GetConfigCache()
+	if err != nil {
+		return
+	}
+	old := conf
+	conf.SetPaused(provider, paused)
+	if err := conf.SaveConfig(); err != nil {
+		log.Println("执行MQTT命令失败:", err)
+		return
+	}
+	if changes := config.DiffConfig(old, conf); len(changes) > 0 {
+		config.AddAuditRecord(config.AuditRecord{
+			Type:    config.AuditTypeConfig,
+			User:    "MQTT",
+			Action:  "MQTT " + command,
+			Success: true,
+			Changes: changes,
+		})
+	}
+
+	action := map[bool]string{true: "暂停", false: "恢复"}[paused]
+	if provider != "" {
+		action += " " + provider + " 的"
+	}
+	log.Printf("收到MQTT命令, 已%s更新\n", action)
+	if paused {
+		return
+	}
+	if !conf.IsPaused(conf.DNS.Name) {
+		go RunOnce()
+	}
+}
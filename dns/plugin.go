@@ -0,0 +1,112 @@
+//go:build !noweb
+// +build !noweb
+
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"ddns-go/plugin"
+	"log"
+)
+
+// newPluginDNS 名称为插件时返回插件的DNS服务商
+func newPluginDNS(name string) DNS {
+	if plugin.IsPluginName(name) {
+		return &Plugin{}
+	}
+	return nil
+}
+
+// Plugin 插件目录中的DNS服务商插件
+type Plugin struct {
+	DNSConfig config.DNSConfig
+	Domains   config.Domains
+	TTL       string
+	plugin    plugin.Plugin
+	found     bool
+	ctx       context.Context
+}
+
+// serialUpdate 每次更新启动一次插件
+func (p *Plugin) serialUpdate() {}
+
+// Init 初始化
+func (p *Plugin) Init(ctx context.Context, conf *config.Config, domains config.Domains) {
+	p.ctx = ctx
+	p.DNSConfig = conf.DNS
+	p.Domains = domains
+	p.plugin, p.found = plugin.Lookup(conf.DNS.Name)
+	if conf.TTL == "" {
+		// 默认600
+		p.TTL = "600"
+	} else {
+		p.TTL = conf.TTL
+	}
+}
+
+// AddUpdateDomainRecords 添加或更新IPv4/IPv6记录
+func (p *Plugin) AddUpdateDomainRecords() config.Domains {
+	var client *plugin.Client
+	var err error
+	if !p.found {
+		log.Printf("插件 %s 不存在, 请检查启动参数-plugins的插件目录\n", p.DNSConfig.Name)
+	} else if client, err = plugin.Start(p.ctx, p.plugin.Path); err != nil {
+		log.Printf("启动插件 %s 失败! 异常信息: %s\n", p.plugin.Path, err)
+	} else {
+		defer client.Close()
+	}
+
+	p.addUpdateDomainRecords(client, "A")
+	p.addUpdateDomainRecords(client, "AAAA")
+	return p.Domains
+}
+
+// addUpdateDomainRecords client为nil时插件启动失败, 所有域名更新失败
+func (p *Plugin) addUpdateDomainRecords(client *plugin.Client, recordType string) {
+	ipAddr, domains := p.Domains.GetNewIpResult(recordType)
+	if ipAddr == "" {
+		return
+	}
+
+	for _, domain := range domains {
+		if client == nil {
+			domain.UpdateStatus = config.UpdatedFailed
+			domain.Error = "插件启动失败"
+			continue
+		}
+		if isDryRun(p.ctx) {
+			log.Printf("[试运行] 将通过插件更新 %s %s %s\n", recordType, domain, ipAddr)
+			continue
+		}
+
+		resp, err := client.UpdateRecord(p.ctx, &plugin.UpdateRecordRequest{
+			Id:         p.DNSConfig.ID,
+			Secret:     p.DNSConfig.Secret,
+			Domain:     domain.String(),
+			DomainName: domain.DomainName,
+			SubDomain:  domain.GetSubDomain(),
+			RecordType: recordType,
+			Ip:         ipAddr,
+			Ttl:        p.TTL,
+		})
+		if err != nil {
+			log.Printf("插件更新域名 %s 失败! 异常信息: %s\n", domain, err)
+			domain.UpdateStatus = config.UpdatedFailed
+			domain.Error = err.Error()
+			continue
+		}
+		switch resp.Result {
+		case plugin.UpdateRecordResponse_NOTHING:
+			log.Printf("你的IP %s 没有变化, 域名 %s\n", ipAddr, domain)
+			domain.UpdateStatus = config.UpdatedNothing
+		case plugin.UpdateRecordResponse_SUCCESS:
+			log.Printf("插件更新域名 %s 成功! IP: %s %s\n", domain, ipAddr, resp.Message)
+			domain.UpdateStatus = config.UpdatedSuccess
+		default:
+			log.Printf("插件更新域名 %s 失败! 返回: %s\n", domain, resp.Message)
+			domain.UpdateStatus = config.UpdatedFailed
+			domain.Error = resp.Message
+		}
+	}
+}
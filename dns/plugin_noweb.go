@@ -0,0 +1,9 @@
+//go:build noweb
+// +build noweb
+
+package dns
+
+// newPluginDNS 使用 noweb 构建时不支持插件
+func newPluginDNS(name string) DNS {
+	return nil
+}
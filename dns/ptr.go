@@ -0,0 +1,63 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"ddns-go/util"
+	"log"
+)
+
+// ptrRecorder 可更新反向解析(PTR)记录的DNS服务商, 需在该服务商托管IP的反向解析区域
+type ptrRecorder interface {
+	// 设置name的PTR记录指向target, 已相同时不修改并返回false
+	setPTRRecord(ctx context.Context, dnsConf config.DNSConfig, name string, target string) (changed bool, err error)
+}
+
+// updatePTR 更新获取到的IPv4/IPv6的PTR记录, 只处理填写了PTR域名的
+func updatePTR(ctx context.Context, conf *config.Config, domains *config.Domains) {
+	for _, item := range [][2]string{{domains.Ipv4Addr, conf.Ipv4.PTR}, {domains.Ipv6Addr, conf.Ipv6.PTR}} {
+		ipAddr, target := item[0], item[1]
+		if ipAddr == "" || target == "" {
+			continue
+		}
+		recorder, ok := NewDNS(conf.DNS.Name).(ptrRecorder)
+		if !ok {
+			log.Printf("DNS服务商 %s 不支持更新PTR记录\n", conf.DNS.Name)
+			return
+		}
+		setPTR(ctx, recorder, conf.DNS, ipAddr, target)
+	}
+}
+
+// setPTR 设置ipAddr的PTR记录, 上次已成功设置为相同的域名时不请求
+func setPTR(ctx context.Context, recorder ptrRecorder, dnsConf config.DNSConfig, ipAddr string, target string) {
+	name, err := util.ReverseAddr(ipAddr)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	key := dnsConf.Name + " " + hashKey(dnsConf.ID+" "+dnsConf.Secret) + " " + name
+	s := stateFrom(ctx)
+	s.ptrLastTarget.Lock()
+	last := s.ptrLastTarget.m[key]
+	s.ptrLastTarget.Unlock()
+	if last == target {
+		return
+	}
+	if isDryRun(ctx) {
+		log.Printf("[试运行] 将设置 PTR %s %s\n", name, target)
+		return
+	}
+
+	changed, err := recorder.setPTRRecord(ctx, dnsConf, name, target)
+	if err != nil {
+		log.Printf("更新PTR记录 %s 失败！%s\n", name, err)
+		return
+	}
+	if changed {
+		log.Printf("更新PTR记录 %s 成功！指向: %s\n", name, target)
+	}
+	s.ptrLastTarget.Lock()
+	s.ptrLastTarget.m[key] = target
+	s.ptrLastTarget.Unlock()
+}
@@ -0,0 +1,44 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"testing"
+)
+
+type fakePTRRecorder struct {
+	calls   int
+	name    string
+	target  string
+	changed bool
+}
+
+func (r *fakePTRRecorder) setPTRRecord(ctx context.Context, dnsConf config.DNSConfig, name string, target string) (bool, error) {
+	r.calls++
+	r.name, r.target = name, target
+	return r.changed, nil
+}
+
+// TestSetPTR 设置成功后相同的域名不再请求, 试运行时不请求
+func TestSetPTR(t *testing.T) {
+	dnsConf := config.DNSConfig{Name: "test-ptr", ID: "id", Secret: "secret"}
+	recorder := &fakePTRRecorder{changed: true}
+
+	setPTR(context.Background(), recorder, dnsConf, "1.2.3.4", "mail.example.com")
+	if recorder.calls != 1 || recorder.name != "4.3.2.1.in-addr.arpa" || recorder.target != "mail.example.com" {
+		t.Fatalf("设置PTR记录不正确: %+v", recorder)
+	}
+	setPTR(context.Background(), recorder, dnsConf, "1.2.3.4", "mail.example.com")
+	if recorder.calls != 1 {
+		t.Error("PTR记录未变化时不应再请求")
+	}
+
+	setPTR(withDryRun(context.Background(), true), recorder, dnsConf, "1.2.3.4", "smtp.example.com")
+	if recorder.calls != 1 {
+		t.Error("试运行时不应修改PTR记录")
+	}
+
+	if _, ok := NewDNS("cloudflare").(ptrRecorder); !ok {
+		t.Error("cloudflare 应支持更新PTR记录")
+	}
+}
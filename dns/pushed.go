@@ -0,0 +1,115 @@
+package dns
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// pushedState 上次成功更新到DNS服务商的记录及IP, 保存后重启时不需要重新查询
+type pushedState struct {
+	Records  map[string]cachedRecord `json:"records"`
+	Callback map[string]string       `json:"callback"`
+	Exec     map[string]string       `json:"exec"`
+}
+
+var pushed = struct {
+	sync.Mutex
+	path string
+	// 上次保存的内容, 未变化时不再写入
+	saved []byte
+}{}
+
+// hashKey 缓存的key中包含的帐号、密钥或URL, 保存到文件时不保存明文
+func hashKey(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+// PersistPushed 上次成功更新的记录及IP保存到path, 启动时加载, 每次更新后保存
+func PersistPushed(path string) {
+	pushed.Lock()
+	defer pushed.Unlock()
+	pushed.path = path
+	byt, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	var state pushedState
+	if err == nil {
+		err = json.Unmarshal(byt, &state)
+	}
+	if err != nil {
+		log.Printf("读取上次更新的记录 %s 失败: %s\n", path, err)
+		return
+	}
+	pushed.saved = byt
+
+	now := time.Now()
+	s := defaultState
+	s.recordCache.Lock()
+	for key, record := range state.Records {
+		if now.Before(record.Expires) {
+			s.recordCache.records[key] = record
+		}
+	}
+	s.recordCache.Unlock()
+	s.callbackLastIPLock.Lock()
+	for key, ip := range state.Callback {
+		s.callbackLastIP[key] = ip
+	}
+	s.callbackLastIPLock.Unlock()
+	s.execLastIPLock.Lock()
+	for key, ip := range state.Exec {
+		s.execLastIP[key] = ip
+	}
+	s.execLastIPLock.Unlock()
+}
+
+// savePushed 有变化时保存上次成功更新的记录及IP, 未调用PersistPushed时不保存
+func savePushed() {
+	pushed.Lock()
+	defer pushed.Unlock()
+	if pushed.path == "" {
+		return
+	}
+
+	state := pushedState{Records: make(map[string]cachedRecord), Callback: make(map[string]string), Exec: make(map[string]string)}
+	s := defaultState
+	s.recordCache.Lock()
+	for key, record := range s.recordCache.records {
+		state.Records[key] = record
+	}
+	s.recordCache.Unlock()
+	s.callbackLastIPLock.Lock()
+	for key, ip := range s.callbackLastIP {
+		state.Callback[key] = ip
+	}
+	s.callbackLastIPLock.Unlock()
+	s.execLastIPLock.Lock()
+	for key, ip := range s.execLastIP {
+		state.Exec[key] = ip
+	}
+	s.execLastIPLock.Unlock()
+
+	byt, err := json.Marshal(state)
+	if err != nil || bytes.Equal(byt, pushed.saved) {
+		return
+	}
+	// 先写入临时文件再重命名, 防止保存中退出时文件不完整
+	tmpPath := pushed.path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, byt, 0600); err == nil {
+		err = os.Rename(tmpPath, pushed.path)
+	}
+	if err != nil {
+		log.Printf("保存上次更新的记录到 %s 失败: %s\n", pushed.path, err)
+		return
+	}
+	pushed.saved = byt
+}
@@ -0,0 +1,58 @@
+package dns
+
+import (
+	"ddns-go/config"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPersistPushed 重启后加载上次更新的记录及IP, 文件中不包含密钥
+func TestPersistPushed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pushed.json")
+	PersistPushed(path)
+	defer func() {
+		pushed.Lock()
+		pushed.path, pushed.saved = "", nil
+		pushed.Unlock()
+	}()
+
+	dnsConf := config.DNSConfig{Name: "dnspod", ID: "12345", Secret: "secret-token"}
+	domain := &config.Domain{DomainName: "example.com", SubDomain: "www"}
+	key := recordCacheKey(dnsConf, "A", domain)
+	expiredKey := recordCacheKey(dnsConf, "AAAA", domain)
+	cb := &Callback{DNSConfig: config.DNSConfig{ID: "https://example.com/update?token=secret-token"}}
+	cbKey := cb.lastIPKey("A", nil)
+	defer defaultState.deleteCachedRecord(key)
+
+	defaultState.setCachedRecord(key, cachedRecord{ID: "id1", Value: "1.1.1.1"}, time.Now())
+	defaultState.setCachedRecord(expiredKey, cachedRecord{ID: "id2", Value: "::1"}, time.Now().Add(-recordCacheTTL))
+	cb.setLastIP(cbKey, "1.1.1.1")
+	savePushed()
+
+	byt, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(byt), "secret-token") {
+		t.Errorf("保存的文件中不应包含密钥: %s", byt)
+	}
+
+	// 模拟重启
+	defaultState.deleteCachedRecord(key)
+	defaultState.deleteCachedRecord(expiredKey)
+	cb.setLastIP(cbKey, "")
+	PersistPushed(path)
+
+	if record, ok := defaultState.getCachedRecord(key, time.Now()); !ok || record.ID != "id1" || record.Value != "1.1.1.1" {
+		t.Errorf("重启后应加载上次更新的记录: %+v", record)
+	}
+	if _, ok := defaultState.getCachedRecord(expiredKey, time.Now()); ok {
+		t.Error("过期的记录不应加载")
+	}
+	if ip := cb.lastIP(cbKey); ip != "1.1.1.1" {
+		t.Errorf("重启后应加载上次调用Callback的IP: %s", ip)
+	}
+}
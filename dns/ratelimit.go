@@ -0,0 +1,23 @@
+package dns
+
+import (
+	"ddns-go/config"
+	"ddns-go/util"
+	"log"
+	"net/http"
+)
+
+// providerClient 请求DNS服务商使用的http.Client, 同一帐号的请求共享限速. secrets为日志中需隐藏的其它内容, 如URL中的密码
+func providerClient(dnsConf config.DNSConfig, secrets ...string) *http.Client {
+	client := util.ProviderHTTPClient(dnsConf.Proxy, dnsConf.IPVersion, dnsConf.Bind, append([]string{dnsConf.Secret}, secrets...)...)
+	rate, burst, err := util.ParseRateLimit(dnsConf.RateLimit)
+	if err != nil {
+		log.Println(err)
+		rate, burst = util.DefaultRateLimit, util.DefaultRateBurst
+	}
+	if rate > 0 {
+		limiter := util.SharedRateLimiter(dnsConf.Name+" "+dnsConf.ID, rate, burst)
+		client.Transport = util.RateLimitTransport(client.Transport, limiter)
+	}
+	return client
+}
@@ -0,0 +1,108 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"log"
+	"time"
+)
+
+// 缓存的记录多久后重新查询, 记录在DNS服务商处被修改时也能更新
+const recordCacheTTL = time.Hour
+
+// cachedRecord 缓存的记录, 之后直接使用ID更新, 不再查询区域及记录列表
+type cachedRecord struct {
+	ZoneID string
+	ID     string
+	// 记录当前的值
+	Value   string
+	Expires time.Time
+}
+
+func zoneCacheKey(dnsConf config.DNSConfig, domain *config.Domain) string {
+	return dnsConf.Name + " " + hashKey(dnsConf.ID+" "+dnsConf.Secret) + " " + domain.DomainName
+}
+
+func recordCacheKey(dnsConf config.DNSConfig, recordType string, domain *config.Domain) string {
+	return dnsConf.Name + " " + hashKey(dnsConf.ID+" "+dnsConf.Secret) + " " + recordType + " " + domain.String()
+}
+
+// getCachedZone 缓存的区域ID
+func (s *State) getCachedZone(key string) (string, bool) {
+	s.recordCache.Lock()
+	defer s.recordCache.Unlock()
+	zoneID, ok := s.recordCache.zones[key]
+	return zoneID, ok
+}
+
+func (s *State) setCachedZone(key string, zoneID string) {
+	s.recordCache.Lock()
+	defer s.recordCache.Unlock()
+	s.recordCache.zones[key] = zoneID
+}
+
+// deleteCachedZone 区域不存在时删除缓存, 同时删除该区域的记录
+func (s *State) deleteCachedZone(key string, zoneID string) {
+	s.recordCache.Lock()
+	defer s.recordCache.Unlock()
+	delete(s.recordCache.zones, key)
+	for k, record := range s.recordCache.records {
+		if record.ZoneID == zoneID {
+			delete(s.recordCache.records, k)
+		}
+	}
+}
+
+// getCachedRecord 缓存的记录, 过期后返回false
+func (s *State) getCachedRecord(key string, now time.Time) (cachedRecord, bool) {
+	s.recordCache.Lock()
+	defer s.recordCache.Unlock()
+	record, ok := s.recordCache.records[key]
+	if ok && !now.Before(record.Expires) {
+		delete(s.recordCache.records, key)
+		return record, false
+	}
+	return record, ok
+}
+
+// setCachedRecord 缓存查询到或更新后的记录
+func (s *State) setCachedRecord(key string, record cachedRecord, now time.Time) {
+	s.recordCache.Lock()
+	defer s.recordCache.Unlock()
+	record.Expires = now.Add(recordCacheTTL)
+	s.recordCache.records[key] = record
+}
+
+func (s *State) deleteCachedRecord(key string) {
+	s.recordCache.Lock()
+	defer s.recordCache.Unlock()
+	delete(s.recordCache.records, key)
+}
+
+// updateCachedRecord 使用缓存的记录ID更新, 返回是否已处理.
+// 未缓存或更新失败(如记录已被删除)时删除缓存并返回false, 需查询记录后更新
+func updateCachedRecord(ctx context.Context, key string, recordType string, domain *config.Domain, ipAddr string, update func(record cachedRecord) error) bool {
+	s := stateFrom(ctx)
+	record, ok := s.getCachedRecord(key, time.Now())
+	if !ok {
+		return false
+	}
+	// 相同不修改
+	if record.Value == ipAddr {
+		log.Printf("你的IP %s 没有变化, 域名 %s", ipAddr, domain)
+		return true
+	}
+	if dryRunPlan(ctx, recordType, domain, record.Value, ipAddr) {
+		return true
+	}
+	if err := update(record); err != nil {
+		s.deleteCachedRecord(key)
+		log.Printf("使用缓存的记录更新域名 %s 失败, 重新查询记录: %s", domain, err)
+		return false
+	}
+	log.Printf("更新域名解析 %s 成功！IP: %s", domain, ipAddr)
+	domain.UpdateStatus = config.UpdatedSuccess
+	record.Value = ipAddr
+	s.setCachedRecord(key, record, time.Now())
+	return true
+}
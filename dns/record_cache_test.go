@@ -0,0 +1,83 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestUpdateCachedRecord 缓存的记录直接更新, 更新失败时删除缓存并重新查询
+func TestUpdateCachedRecord(t *testing.T) {
+	dnsConf := config.DNSConfig{Name: "cloudflare", Secret: "token"}
+	domain := &config.Domain{DomainName: "example.com", SubDomain: "www"}
+	key := recordCacheKey(dnsConf, "A", domain)
+	defer defaultState.deleteCachedRecord(key)
+
+	calls := 0
+	update := func(err error) func(record cachedRecord) error {
+		return func(record cachedRecord) error {
+			calls++
+			if record.ID != "id1" {
+				t.Errorf("记录ID %s 不正确", record.ID)
+			}
+			return err
+		}
+	}
+
+	if updateCachedRecord(context.Background(), key, "A", domain, "1.1.1.2", update(nil)) {
+		t.Fatal("未缓存时应查询记录")
+	}
+
+	defaultState.setCachedRecord(key, cachedRecord{ZoneID: "zone1", ID: "id1", Value: "1.1.1.1"}, time.Now())
+	if !updateCachedRecord(context.Background(), key, "A", domain, "1.1.1.1", update(nil)) || calls != 0 {
+		t.Fatal("IP没有变化时不应请求")
+	}
+	if !updateCachedRecord(context.Background(), key, "A", domain, "1.1.1.2", update(nil)) || calls != 1 {
+		t.Fatal("应使用缓存的记录更新")
+	}
+	if domain.UpdateStatus != config.UpdatedSuccess {
+		t.Error("更新成功的状态不正确")
+	}
+	if record, _ := defaultState.getCachedRecord(key, time.Now()); record.Value != "1.1.1.2" {
+		t.Errorf("更新后缓存的值 %s 不正确", record.Value)
+	}
+
+	if updateCachedRecord(context.Background(), key, "A", domain, "1.1.1.3", update(errors.New("404"))) {
+		t.Fatal("更新失败时应重新查询记录")
+	}
+	if _, ok := defaultState.getCachedRecord(key, time.Now()); ok {
+		t.Error("更新失败时应删除缓存")
+	}
+}
+
+// TestCachedRecordExpires 缓存过期或区域删除后重新查询
+func TestCachedRecordExpires(t *testing.T) {
+	dnsConf := config.DNSConfig{Name: "huaweicloud", ID: "ak", Secret: "sk"}
+	domain := &config.Domain{DomainName: "example.com", SubDomain: "www"}
+	key := recordCacheKey(dnsConf, "AAAA", domain)
+	zoneKey := zoneCacheKey(dnsConf, domain)
+	defer defaultState.deleteCachedRecord(key)
+
+	now := time.Now()
+	defaultState.setCachedRecord(key, cachedRecord{ZoneID: "zone1", ID: "id1", Value: "::1"}, now)
+	if _, ok := defaultState.getCachedRecord(key, now.Add(recordCacheTTL)); ok {
+		t.Error("过期的记录不应使用")
+	}
+
+	defaultState.setCachedZone(zoneKey, "zone1")
+	defaultState.setCachedRecord(key, cachedRecord{ZoneID: "zone1", ID: "id1", Value: "::1"}, now)
+	defaultState.deleteCachedZone(zoneKey, "zone1")
+	if _, ok := defaultState.getCachedZone(zoneKey); ok {
+		t.Error("区域的缓存未删除")
+	}
+	if _, ok := defaultState.getCachedRecord(key, now); ok {
+		t.Error("区域删除后该区域的记录也应删除")
+	}
+
+	other := recordCacheKey(config.DNSConfig{Name: "huaweicloud", ID: "ak2", Secret: "sk"}, "AAAA", domain)
+	if other == key {
+		t.Error("不同帐号应分别缓存")
+	}
+}
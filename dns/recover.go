@@ -0,0 +1,23 @@
+package dns
+
+import (
+	"ddns-go/config"
+	"log"
+	"runtime/debug"
+)
+
+// logPanic 记录panic及堆栈, 在recover后调用
+func logPanic(action string, err interface{}) {
+	log.Printf("%s时发生异常: %v\n%s", action, err, debug.Stack())
+}
+
+// safeUpdate 调用DNS服务商更新, 发生panic时记录堆栈并将域名标记为失败, 不影响之后的更新
+func safeUpdate(conf *config.Config, update func() config.Domains) (domains config.Domains) {
+	defer func() {
+		if err := recover(); err != nil {
+			logPanic(conf.DNS.Name+" 更新", err)
+			domains = config.FailedDomains(conf)
+		}
+	}()
+	return update()
+}
@@ -0,0 +1,26 @@
+package dns
+
+import (
+	"ddns-go/config"
+	"testing"
+)
+
+// TestSafeUpdate 测试DNS服务商panic时域名标记为失败
+func TestSafeUpdate(t *testing.T) {
+	conf := &config.Config{}
+	conf.DNS.Name = "alidns"
+	conf.Ipv4.Enable = true
+	conf.Ipv4.Domains = []string{"www.example.com"}
+	conf.Ipv6.Domains = []string{"v6.example.com"}
+
+	domains := safeUpdate(conf, func() config.Domains {
+		var domains *config.Domains
+		return *domains
+	})
+	if len(domains.Ipv4Domains) != 1 || domains.Ipv4Domains[0].UpdateStatus != config.UpdatedFailed {
+		t.Error("发生异常时域名应标记为失败")
+	}
+	if len(domains.Ipv6Domains) != 0 {
+		t.Error("未启用IPv6时不应包含IPv6域名")
+	}
+}
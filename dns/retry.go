@@ -0,0 +1,79 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"log"
+	"time"
+)
+
+const (
+	// 一次更新中失败后最多重试的次数
+	maxRetries = 3
+	// 第一次重试的间隔, 之后每次翻倍
+	retryBaseDelay = 5 * time.Second
+	// 重试的最大间隔
+	retryMaxDelay = time.Minute
+)
+
+// retryDelay 第retry次重试的间隔
+func retryDelay(retry int) time.Duration {
+	delay := retryBaseDelay << uint(retry-1)
+	if delay > retryMaxDelay || delay <= 0 {
+		return retryMaxDelay
+	}
+	return delay
+}
+
+// needRetry 获取IP失败或有域名更新失败
+func needRetry(conf *config.Config, domains *config.Domains) bool {
+	for _, recordType := range []string{"A", "AAAA"} {
+		enable := conf.Ipv4.Enable
+		if recordType == "AAAA" {
+			enable = conf.Ipv6.Enable
+		}
+		_, recordDomains := domains.GetNewIpResult(recordType)
+		for _, domain := range recordDomains {
+			if (enable && domains.IPFor(recordType, domain) == "") || domain.UpdateStatus == config.UpdatedFailed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// updateWithRetry 更新失败时按指数退避重试. 重试时不再记录检测到的IP, 避免计入IP的变化
+func updateWithRetry(ctx context.Context, conf *config.Config, update func(ctx context.Context) config.Domains) config.Domains {
+	domains := update(ctx)
+	ctx = config.WithoutDampingUpdate(ctx)
+	for retry := 1; retry <= maxRetries && needRetry(conf, &domains); retry++ {
+		delay := retryDelay(retry)
+		log.Printf("更新失败, %s后第%d次重试\n", delay, retry)
+		if !sleep(ctx, delay) {
+			break
+		}
+		domains = mergeRetry(domains, update(ctx))
+	}
+	return domains
+}
+
+// mergeRetry 之前已更新成功的域名, 重试时解析已是最新的IP, 保留成功的状态用于通知
+func mergeRetry(previous config.Domains, current config.Domains) config.Domains {
+	mergeRetryStatus(previous.Ipv4Domains, current.Ipv4Domains)
+	mergeRetryStatus(previous.Ipv6Domains, current.Ipv6Domains)
+	return current
+}
+
+func mergeRetryStatus(previous []*config.Domain, current []*config.Domain) {
+	for _, domain := range current {
+		if domain.UpdateStatus == config.UpdatedFailed {
+			continue
+		}
+		for _, p := range previous {
+			if p.String() == domain.String() && p.UpdateStatus == config.UpdatedSuccess {
+				domain.UpdateStatus = config.UpdatedSuccess
+				break
+			}
+		}
+	}
+}
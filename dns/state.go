@@ -0,0 +1,77 @@
+package dns
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// State 更新中记录的状态及缓存. 定时更新、网页等使用进程内默认的, provider包中每个Updater使用单独的
+type State struct {
+	// DNS服务商的熔断状态
+	breakerLock sync.Mutex
+	breakers    map[string]*breaker
+
+	// 运行状态
+	statusLock sync.Mutex
+	status     Status
+
+	// 每个Callback上次成功调用的IP, key为记录类型及URL的hash
+	callbackLastIPLock sync.Mutex
+	callbackLastIP     map[string]string
+
+	// 每个程序及域名上次成功更新的IP, 未变化时不调用程序
+	execLastIPLock sync.Mutex
+	execLastIP     map[string]string
+
+	// 缓存的区域ID及记录, key为DNS服务商、帐号及域名
+	recordCache struct {
+		sync.Mutex
+		zones   map[string]string
+		records map[string]cachedRecord
+	}
+
+	// 上次成功设置的PTR记录, key为DNS服务商帐号及反向解析域名, 值为指向的域名. 未变化时不再请求
+	ptrLastTarget struct {
+		sync.Mutex
+		m map[string]string
+	}
+
+	// 正在进行的更新, 退出时等待完成
+	running sync.WaitGroup
+	// 正在更新的DNS服务商
+	runningProvider atomic.Value
+}
+
+// NewState 创建单独的状态及缓存
+func NewState() *State {
+	s := &State{
+		breakers:       make(map[string]*breaker),
+		callbackLastIP: make(map[string]string),
+		execLastIP:     make(map[string]string),
+	}
+	s.recordCache.zones = make(map[string]string)
+	s.recordCache.records = make(map[string]cachedRecord)
+	s.ptrLastTarget.m = make(map[string]string)
+	return s
+}
+
+// 进程内默认的状态及缓存
+var defaultState = NewState()
+
+type stateKey struct{}
+
+// WithState 返回使用state的ctx, 传给DNS服务商的Init后使用该状态及缓存
+func WithState(ctx context.Context, state *State) context.Context {
+	return context.WithValue(ctx, stateKey{}, state)
+}
+
+// stateFrom ctx中的状态及缓存, 未设置时使用默认的
+func stateFrom(ctx context.Context) *State {
+	if ctx != nil {
+		if state, ok := ctx.Value(stateKey{}).(*State); ok {
+			return state
+		}
+	}
+	return defaultState
+}
@@ -0,0 +1,227 @@
+package dns
+
+import (
+	"ddns-go/config"
+	"time"
+)
+
+// DomainStatus 域名状态
+type DomainStatus struct {
+	Domain     string
+	RecordType string
+	// 当前解析的IP, 未成功更新过为空
+	Value string
+	// 最后一次成功更新解析的时间
+	LastUpdate time.Time
+	// 最后一次检查的时间
+	LastCheck  time.Time
+	LastResult string
+	// 最后一次失败的原因, 成功后清空
+	LastError string
+	// 连续失败的次数
+	FailCount int
+}
+
+// Status 运行状态
+type Status struct {
+	Provider string
+	LastRun  time.Time
+	NextRun  time.Time
+	// DNS服务商连续失败时暂停定时更新到的时间及连续失败的次数
+	PausedUntil    time.Time
+	PausedFailures int
+	// 最后一次获取到的IP
+	Ipv4Addr string
+	Ipv6Addr string
+	// 最后一次获取到的IP的归属
+	Ipv4Info config.IPInfo
+	Ipv6Info config.IPInfo
+	Domains  []DomainStatus
+}
+
+// DomainResult 一次更新中域名的结果, 用于结构化日志
+type DomainResult struct {
+	Provider   string
+	Domain     string
+	RecordType string
+	OldIP      string
+	NewIP      string
+	Result     string
+}
+
+// 域名更新结果的处理
+var resultHandlers []func(result DomainResult)
+
+// OnResult 注册域名更新结果的处理, 如输出结构化日志. 需在init中调用
+func OnResult(handler func(result DomainResult)) {
+	resultHandlers = append(resultHandlers, handler)
+}
+
+// saveHistory 保存域名的更新结果到历史记录
+func saveHistory(results []DomainResult, historyDays int) {
+	records := make([]config.UpdateRecord, len(results))
+	for i, result := range results {
+		records[i] = config.UpdateRecord{
+			Provider:   result.Provider,
+			Domain:     result.Domain,
+			RecordType: result.RecordType,
+			OldIP:      result.OldIP,
+			IP:         result.NewIP,
+			Result:     result.Result,
+		}
+	}
+	config.AddUpdateRecords(records, historyDays)
+}
+
+// handleResults 处理域名的更新结果
+func handleResults(results []DomainResult) {
+	for _, result := range results {
+		for _, handler := range resultHandlers {
+			handler(result)
+		}
+	}
+}
+
+// GetStatus 获得运行状态
+func GetStatus() Status {
+	return defaultState.getStatus()
+}
+
+func (s *State) getStatus() Status {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	result := s.status
+	result.Domains = append([]DomainStatus{}, s.status.Domains...)
+	return result
+}
+
+// setNextRun 设置下次运行时间
+func (s *State) setNextRun(t time.Time) {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	s.status.NextRun = t
+}
+
+// TimerStalled 定时更新超过d仍未按时运行, 如更新卡住. 未开始定时运行时返回false
+func TimerStalled(d time.Duration) bool {
+	s := defaultState
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	return !s.status.NextRun.IsZero() && time.Since(s.status.NextRun) > d
+}
+
+// setPausedUntil 设置暂停定时更新到的时间及连续失败的次数
+func (s *State) setPausedUntil(t time.Time, failures int) {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	s.status.PausedUntil = t
+	s.status.PausedFailures = failures
+}
+
+// updateStatus 根据更新结果刷新域名状态, 返回本次检查了的域名的结果
+// full为true时已从配置中删除的域名不再显示, 为false时保留未更新域名的状态
+func (s *State) updateStatus(provider string, domains *config.Domains, full bool) (results []DomainResult) {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	now := time.Now()
+	previous := make(map[string]DomainStatus, len(s.status.Domains))
+	for _, ds := range s.status.Domains {
+		previous[ds.RecordType+ds.Domain] = ds
+	}
+
+	var result []DomainStatus
+	for _, recordType := range []string{"A", "AAAA"} {
+		_, recordDomains := domains.GetNewIpResult(recordType)
+		for _, domain := range recordDomains {
+			ipAddr := domains.IPFor(recordType, domain)
+			ds, ok := previous[recordType+domain.String()]
+			if !ok || s.status.Provider != provider {
+				ds = DomainStatus{Domain: domain.String(), RecordType: recordType}
+			}
+			oldIP := ds.Value
+			failedBefore := ds.FailCount > 0
+			// 未获取到IP时不会更新, 保留之前的状态. 多次获取IP失败时为失败
+			if ipAddr != "" {
+				ds.LastCheck = now
+				switch domain.UpdateStatus {
+				case config.UpdatedSuccess:
+					ds.Value = ipAddr
+					ds.LastUpdate = now
+					ds.LastResult = config.UpdatedSuccess
+					ds.LastError = ""
+					ds.FailCount = 0
+				case config.UpdatedFailed:
+					ds.LastResult = config.UpdatedFailed
+					ds.LastError = domain.Error
+					ds.FailCount++
+				default:
+					ds.Value = ipAddr
+					ds.LastResult = string(config.UpdatedNothing)
+					ds.LastError = ""
+					ds.FailCount = 0
+				}
+			} else if domain.UpdateStatus == config.UpdatedFailed {
+				ds.LastCheck = now
+				ds.LastResult = config.UpdatedFailed
+				ds.LastError = domain.Error
+				ds.FailCount++
+			}
+			if ds.LastCheck.Equal(now) {
+				results = append(results, DomainResult{
+					Provider:   provider,
+					Domain:     ds.Domain,
+					RecordType: recordType,
+					OldIP:      oldIP,
+					NewIP:      ipAddr,
+					Result:     ds.LastResult,
+				})
+			}
+			// 通知中显示连续失败的次数
+			domain.FailCount = ds.FailCount
+			domain.Recovered = failedBefore && ds.FailCount == 0
+			domain.OldIP = oldIP
+			result = append(result, ds)
+		}
+	}
+
+	if !full && s.status.Provider == provider {
+		result = mergeDomainStatus(s.status.Domains, result)
+	}
+
+	s.status.Provider = provider
+	s.status.LastRun = now
+	s.status.Domains = result
+	if domains.Ipv4Addr != "" {
+		s.status.Ipv4Addr = domains.Ipv4Addr
+		s.status.Ipv4Info = domains.Ipv4Info
+	}
+	if domains.Ipv6Addr != "" {
+		s.status.Ipv6Addr = domains.Ipv6Addr
+		s.status.Ipv6Info = domains.Ipv6Info
+	}
+	return
+}
+
+// mergeDomainStatus 用本次更新的域名状态替换之前的, 保持原有顺序
+func mergeDomainStatus(previous []DomainStatus, updated []DomainStatus) []DomainStatus {
+	result := append([]DomainStatus{}, previous...)
+	for _, ds := range updated {
+		found := false
+		for i := range result {
+			if result[i].RecordType == ds.RecordType && result[i].Domain == ds.Domain {
+				result[i] = ds
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, ds)
+		}
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package dns
+
+import (
+	"ddns-go/config"
+	"testing"
+	"time"
+)
+
+// TestUpdateStatus 测试域名状态
+func TestUpdateStatus(t *testing.T) {
+	domains := &config.Domains{
+		Ipv4Addr: "1.1.1.1",
+		Ipv4Domains: []*config.Domain{
+			{DomainName: "example.com", SubDomain: "a", UpdateStatus: config.UpdatedSuccess},
+			{DomainName: "example.com", SubDomain: "b", UpdateStatus: config.UpdatedFailed, Error: "更新域名解析失败"},
+		},
+	}
+	defaultState.updateStatus("alidns", domains, true)
+
+	// 第二次获取IP失败, 保留之前的状态
+	domains.Ipv4Addr = ""
+	defaultState.updateStatus("alidns", domains, true)
+
+	result := GetStatus()
+	if len(result.Domains) != 2 {
+		t.Fatalf("域名数量不正确: %d", len(result.Domains))
+	}
+	if result.Domains[0].Value != "1.1.1.1" || result.Domains[0].LastUpdate.IsZero() {
+		t.Error("a.example.com 状态不正确")
+	}
+	if result.Domains[1].Value != "" || result.Domains[1].LastResult != config.UpdatedFailed || result.Domains[1].LastError != "更新域名解析失败" {
+		t.Error("b.example.com 状态不正确")
+	}
+}
+
+// TestFailCount 测试连续失败的次数
+func TestFailCount(t *testing.T) {
+	domains := &config.Domains{
+		Ipv4Addr:    "1.1.1.1",
+		Ipv4Domains: []*config.Domain{{DomainName: "example.com", SubDomain: "c", UpdateStatus: config.UpdatedFailed}},
+	}
+	defaultState.updateStatus("dnspod", domains, true)
+	defaultState.updateStatus("dnspod", domains, true)
+	if domains.Ipv4Domains[0].FailCount != 2 {
+		t.Errorf("连续失败的次数不正确: %d", domains.Ipv4Domains[0].FailCount)
+	}
+
+	domains.Ipv4Domains[0].UpdateStatus = config.UpdatedSuccess
+	defaultState.updateStatus("dnspod", domains, true)
+	if GetStatus().Domains[0].FailCount != 0 {
+		t.Error("更新成功后连续失败的次数应为0")
+	}
+}
+
+// TestTimerStalled 超过下次运行时间太久未运行时为卡住
+func TestTimerStalled(t *testing.T) {
+	defer defaultState.setNextRun(time.Time{})
+
+	if TimerStalled(time.Minute) {
+		t.Error("未开始定时运行时不应为卡住")
+	}
+	defaultState.setNextRun(time.Now().Add(-10 * time.Second))
+	if TimerStalled(time.Minute) {
+		t.Error("刚到运行时间时不应为卡住")
+	}
+	defaultState.setNextRun(time.Now().Add(-2 * time.Minute))
+	if !TimerStalled(time.Minute) {
+		t.Error("超过时间未运行时应为卡住")
+	}
+}
@@ -0,0 +1,57 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// authChecker 不修改解析记录, 只校验DNS服务商的ID/Secret是否正确
+type authChecker interface {
+	checkAuth(ctx context.Context, dnsConf config.DNSConfig) error
+}
+
+// Validate 校验配置及DNS服务商的必填项, checkAuth为true时请求DNS服务商校验ID/Secret
+func Validate(conf *config.Config, checkAuth bool) (errs []error) {
+	errs = conf.Validate()
+
+	dnsSelected := NewDNS(conf.DNS.Name)
+	if dnsSelected == nil {
+		return append(errs, fmt.Errorf("不支持的DNS服务商 %s", conf.DNS.Name))
+	}
+
+	if _, ok := dnsSelected.(ptrRecorder); !ok && ((conf.Ipv4.Enable && conf.Ipv4.PTR != "") || (conf.Ipv6.Enable && conf.Ipv6.PTR != "")) {
+		errs = append(errs, fmt.Errorf("DNS服务商 %s 不支持更新PTR记录", conf.DNS.Name))
+	}
+
+	dnsConf, err := conf.DNS.Resolved()
+	if err != nil {
+		// conf.Validate 中已包含
+		return
+	}
+	switch conf.DNS.Name {
+	case "callback":
+		if u, err := url.Parse(dnsConf.ID); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("Callback URL %s 不正确", dnsConf.ID))
+		}
+	case "cloudflare":
+		if dnsConf.Secret == "" {
+			errs = append(errs, fmt.Errorf("%s 未填写Token", conf.DNS.Name))
+		}
+	default:
+		if dnsConf.ID == "" || dnsConf.Secret == "" {
+			errs = append(errs, fmt.Errorf("%s 未填写ID或Secret", conf.DNS.Name))
+		}
+	}
+
+	if checkAuth && len(errs) == 0 {
+		if checker, ok := dnsSelected.(authChecker); ok {
+			if err := checker.checkAuth(context.Background(), dnsConf); err != nil {
+				errs = append(errs, fmt.Errorf("%s 校验ID/Secret失败: %s", conf.DNS.Name, strings.TrimSpace(err.Error())))
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,66 @@
+package dns
+
+import (
+	"context"
+	"ddns-go/config"
+	"ddns-go/util"
+	"log"
+	"time"
+)
+
+// 更新后等待多久验证解析记录, DNS服务商生效需要一定时间
+var verifyDelay = 10 * time.Second
+
+// lookupRecord 使用配置的DNS服务器查询记录, 未配置或查询失败时返回false
+func lookupRecord(ctx context.Context, resolver string, domain string, recordType string) (ips []string, ok bool) {
+	if resolver == "" {
+		return nil, false
+	}
+	r, err := util.ParseResolver(resolver)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	ips, err = r.LookupIP(ctx, domain, recordType)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	return ips, true
+}
+
+// recordResolved 配置的DNS服务器中域名的记录是否已是ip
+func recordResolved(ctx context.Context, resolver string, domain string, recordType string, ip string) bool {
+	ips, ok := lookupRecord(ctx, resolver, domain, recordType)
+	return ok && containsIP(ips, ip)
+}
+
+// verifyResults 更新成功后使用配置的DNS服务器验证解析记录, 结果只输出到日志
+func verifyResults(ctx context.Context, resolver string, results []DomainResult) {
+	if !sleep(ctx, verifyDelay) {
+		return
+	}
+	for _, result := range results {
+		if result.Result != config.UpdatedSuccess {
+			continue
+		}
+		ips, ok := lookupRecord(ctx, resolver, result.Domain, result.RecordType)
+		if !ok {
+			continue
+		}
+		if containsIP(ips, result.NewIP) {
+			log.Printf("验证解析记录: %s %s 已解析到 %s\n", result.RecordType, result.Domain, result.NewIP)
+		} else {
+			log.Printf("验证解析记录: %s %s 的 %s 记录为 %v, 尚未生效(DNS服务器可能有缓存)\n", resolver, result.Domain, result.RecordType, ips)
+		}
+	}
+}
+
+func containsIP(ips []string, ip string) bool {
+	for _, item := range ips {
+		if item == ip {
+			return true
+		}
+	}
+	return false
+}
@@ -3,7 +3,11 @@ module ddns-go
 go 1.16
 
 require (
+	github.com/antonmedv/expr v1.9.0
 	github.com/kardianos/service v1.2.1-0.20211111172041-6fe2824ee824
 	github.com/mitchellh/go-homedir v1.1.0
+	go.etcd.io/bbolt v1.3.6
+	google.golang.org/grpc v1.44.0
+	google.golang.org/protobuf v1.27.1
 	gopkg.in/yaml.v2 v2.4.0
 )
@@ -1,59 +1,239 @@
 package main
 
 import (
+	"context"
 	"ddns-go/config"
 	"ddns-go/dns"
 	"ddns-go/util"
 	"ddns-go/web"
-	"embed"
 	"flag"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/kardianos/service"
 )
 
 // 监听地址
-var listen = flag.String("l", ":9876", "监听地址")
+var listen = flag.String("l", ":9876", "监听地址, 多个用逗号分隔, 支持unix socket。如: 127.0.0.1:9876,unix:///var/run/ddns-go.sock")
 
 // 更新频率(秒)
 var every = flag.Int("f", 300, "同步间隔时间(秒)")
 
+// cron表达式
+var cronExpr = flag.String("cron", "", "更新的cron表达式, 如 \"*/2 * * * *\" 或 \"@every 90s\", 设置后不使用同步间隔")
+
+// 随机延迟(秒)
+var jitter = flag.Int("jitter", 0, "每次同步随机延迟0~jitter秒, 避免大量实例同时请求")
+
+// 启动时等待网络就绪(秒)
+var waitNetwork = flag.Int("wait-network", 60, "启动时等待网络就绪的最长时间(秒), 有默认路由且能访问获取IP的接口即可, 0为不等待")
+
+// 日志格式
+var logFormat = flag.String("log-format", web.LogFormatText, "标准输出的日志格式, 支持text, json")
+
+// 日志级别
+var logLevel = flag.String("log-level", web.LogLevelInfo, "输出的最低日志级别, 支持debug, info, warn, error。debug时输出DNS服务商请求及返回的内容(隐藏密钥)")
+
+// 时区
+var timezone = flag.String("tz", "", "日志、网页及通知使用的时区, 如: Asia/Shanghai, 为空时使用TZ环境变量或系统的时区")
+
+// 时间格式
+var timeFormat = flag.String("time-format", "", "日志、网页及通知中的时间格式, 使用Go的格式, 如: 2006-01-02 15:04:05")
+
+// OpenTelemetry追踪
+var otlpEndpoint = flag.String("otlp", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "按OTLP/HTTP发送每次更新的追踪数据, 如: http://127.0.0.1:4318, 默认读取环境变量OTEL_EXPORTER_OTLP_ENDPOINT")
+
+// 日志文件
+var logFile = flag.String("log-file", "", "日志文件路径, 为空时只输出到标准输出。如: /var/log/ddns-go/ddns-go.log")
+
+// 日志文件轮转的大小(MB)
+var logMaxSize = flag.Int("log-max-size", 10, "日志文件超过多少MB时轮转, 0为不轮转")
+
+// 旧日志文件保留的天数
+var logMaxAge = flag.Int("log-max-age", 30, "轮转后的旧日志文件保留的天数, 0为不限")
+
+// 旧日志文件保留的数量
+var logMaxBackups = flag.Int("log-max-backups", 5, "轮转后的旧日志文件保留的数量, 0为不限")
+
+// 网页中保存的日志条数
+var logBuffer = flag.Int("log-buffer", 1000, "网页中保存的日志条数")
+
+// 网页中的日志保存到文件
+var logPersist = flag.Bool("log-persist", false, "网页中的日志保存到配置文件同目录的 .logs.json 文件, 重启后不丢失")
+
+// syslog
+var syslogAddr = flag.String("syslog", "", "日志同时发送到syslog。local为本机的syslog(Windows下为事件日志), 远程如: udp://192.168.1.2:514, tcp://192.168.1.2:514")
+
+// gRPC管理接口
+var grpcListen = flag.String("grpc", "", "gRPC管理接口的监听地址, 如: :9877, 需同时设置-grpc-cert、-grpc-key及-grpc-ca, 为空时不启用")
+
+// gRPC服务端证书及验证客户端证书的CA
+var grpcCert = flag.String("grpc-cert", "", "gRPC管理接口的服务端证书")
+var grpcKey = flag.String("grpc-key", "", "gRPC管理接口的服务端私钥")
+var grpcCA = flag.String("grpc-ca", "", "验证gRPC客户端证书的CA证书, 只允许该CA签发的客户端证书连接")
+
 // 服务管理
-var serviceType = flag.String("s", "", "服务管理, 支持install, uninstall")
+var serviceType = flag.String("s", "", "服务管理, 支持install, uninstall, start, stop, restart, status")
+
+// 服务名称
+var serviceName = flag.String("name", "ddns-go", "安装的服务名称, 同一台机器安装多个服务时使用不同的名称及配置文件, 如: ddns-go-home")
 
 // 配置文件路径
-var configFilePath = flag.String("c", util.GetConfigFilePathDefault(), "自定义配置文件路径")
+var configFilePath = flag.String("c", util.GetConfigFilePathDefault(), "自定义配置文件路径, 支持远程配置 https://, consul://, etcd://")
+
+// 只更新一次
+var once = flag.Bool("once", false, "只检测并更新一次, 输出结果后退出, 有失败时退出码为1。可用于外部的cron/systemd定时器")
+
+// 试运行
+var dryRun = flag.Bool("dry-run", false, "试运行, 只在日志中输出计划的修改, 不修改解析记录")
+
+// validate时校验DNS服务商的ID/Secret
+var checkAuth = flag.Bool("auth", false, "validate时请求DNS服务商校验ID/Secret, 不修改解析记录")
+
+// 不提供网页
+var noWeb = flag.Bool("noweb", false, "不提供网页, 只能通过配置文件及API使用, 减少内存占用。使用 -tags noweb 构建时不包含网页")
+
+// 插件目录
+var pluginDir = flag.String("plugins", "", "DNS服务商插件的目录, 加载其中文件名为 ddns-go-provider-<名称> 的程序, 配置中使用 plugin-<名称>")
 
-//go:embed static
-var staticEmbededFiles embed.FS
+// 版本, 编译时设置 -ldflags "-X main.version=1.0.0"
+var version = "dev"
 
-//go:embed favicon.ico
-var faviconEmbededFile embed.FS
+// 服务名称可使用的字符
+var serviceNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// 退出时取消, 停止定时运行
+var timerCtx, stopTimer = context.WithCancel(context.Background())
+
+// 退出时等待超时后取消, 停止正在进行的更新
+var updateCtx, cancelUpdate = context.WithCancel(context.Background())
+
+// 退出时关闭
+var server = &http.Server{}
+
+// 退出完成后关闭
+var stopped = make(chan struct{})
+var stopOnce sync.Once
 
 func main() {
+	// 子命令, 如: ddns-go validate -c /Users/name/ddns-go.yaml
+	command := ""
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		command = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	flag.Parse()
-	if _, err := net.ResolveTCPAddr("tcp", *listen); err != nil {
-		log.Fatalf("解析监听地址异常，%s", err)
+	if util.IsHomeAssistant() {
+		applyHomeAssistantOptions()
+	}
+	web.Version = version
+	listenAddrs := util.SplitListenAddrs(*listen)
+	if len(listenAddrs) == 0 {
+		log.Fatalln("监听地址不能为空")
+	}
+	for _, addr := range listenAddrs {
+		if err := util.CheckListenAddr(addr); err != nil {
+			log.Fatalf("解析监听地址异常，%s", err)
+		}
+	}
+	if *cronExpr != "" {
+		if _, err := util.ParseCron(*cronExpr); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if !serviceNameRegexp.MatchString(*serviceName) {
+		log.Fatalf("服务名称 %s 不正确, 只能包含字母、数字、-、_及.\n", *serviceName)
+	}
+	if err := util.SetTimezone(*timezone); err != nil {
+		log.Fatalln(err)
+	}
+	if err := util.SetTimeFormat(*timeFormat); err != nil {
+		log.Fatalln(err)
+	}
+	if err := web.SetLogFormat(*logFormat); err != nil {
+		log.Fatalln(err)
+	}
+	if err := web.SetLogLevel(*logLevel); err != nil {
+		log.Fatalln(err)
+	}
+	if err := web.SetLogBufferSize(*logBuffer); err != nil {
+		log.Fatalln(err)
+	}
+	if *otlpEndpoint != "" {
+		traceName := os.Getenv("OTEL_SERVICE_NAME")
+		if traceName == "" {
+			traceName = *serviceName
+		}
+		if err := util.SetTraceEndpoint(*otlpEndpoint, traceName); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if *logFile != "" {
+		absPath, _ := filepath.Abs(*logFile)
+		*logFile = absPath
+	}
+	for _, file := range []*string{grpcCert, grpcKey, grpcCA, pluginDir} {
+		if *file != "" {
+			*file, _ = filepath.Abs(*file)
+		}
 	}
 
-	if *configFilePath != "" {
+	if util.IsRemoteConfigPath(*configFilePath) {
+		os.Setenv(util.ConfigFilePathENV, *configFilePath)
+	} else if *configFilePath != "" {
 		absPath, _ := filepath.Abs(*configFilePath)
 		os.Setenv(util.ConfigFilePathENV, absPath)
 	}
+	if command != "" {
+		os.Exit(runCommand(command))
+	}
+	if *logFile != "" && command == "" && *serviceType == "" {
+		openLogFile()
+	}
+	if *syslogAddr != "" && command == "" && *serviceType == "" {
+		openSyslog()
+	}
+	if *logPersist && command == "" && *serviceType == "" && !*once {
+		web.PersistLogs(util.GetDataFilePath("logs.json"))
+	}
+	if command == "" && *serviceType == "" {
+		dns.PersistPushed(util.GetDataFilePath("pushed.json"))
+	}
+	if *pluginDir != "" && *serviceType == "" {
+		loadPlugins()
+	}
+	if *noWeb {
+		web.DisableUI()
+	}
+	dns.SetDryRun(*dryRun)
+	if *once {
+		os.Exit(updateOnce())
+	}
 	switch *serviceType {
 	case "install":
 		installService()
 	case "uninstall":
 		uninstallService()
+	case "start", "stop", "restart":
+		controlService(*serviceType)
+	case "status":
+		os.Exit(serviceStatus())
 	default:
-		if util.IsRunInDocker() {
+		if util.IsRunInDocker() || service.Platform() == "" {
+			// Docker中或没有支持的服务管理(如OpenBSD的rc.d)时直接运行
 			run(100 * time.Millisecond)
 		} else {
 			s := getService()
@@ -76,34 +256,291 @@ func main() {
 }
 
 func run(firstDelay time.Duration) {
+	dns.SetContext(updateCtx)
+
 	// 启动静态文件服务
-	http.Handle("/static/", http.FileServer(http.FS(staticEmbededFiles)))
-	http.Handle("/favicon.ico", http.FileServer(http.FS(faviconEmbededFile)))
+	if web.UIEnabled() {
+		staticHandler, err := web.StaticHandler(staticEmbededFiles)
+		if err != nil {
+			log.Fatalln("读取静态文件失败", err)
+		}
+		faviconHandler, err := web.StaticHandler(faviconEmbededFile)
+		if err != nil {
+			log.Fatalln("读取静态文件失败", err)
+		}
+		http.Handle("/static/", staticHandler)
+		http.Handle("/favicon.ico", faviconHandler)
+	}
+
+	http.HandleFunc("/", web.Auth(config.APIKeyScopeRead, web.Page(web.Writing)))
+	http.HandleFunc("/save", web.Auth(config.APIKeyScopeFull, web.Save))
+	http.HandleFunc("/logs", web.Auth(config.APIKeyScopeRead, web.Logs))
+	http.HandleFunc("/downloadLogs", web.Auth(config.APIKeyScopeRead, web.DownloadLogs))
+	http.HandleFunc("/clearLog", web.Auth(config.APIKeyScopeFull, web.ClearLog))
+	http.HandleFunc("/ipv4NetInterface", web.Auth(config.APIKeyScopeRead, web.Ipv4NetInterfaces))
+	http.HandleFunc("/ipv6NetInterface", web.Auth(config.APIKeyScopeRead, web.Ipv6NetInterfaces))
+	http.HandleFunc("/notifyTest", web.Auth(config.APIKeyScopeFull, web.NotifyTest))
+	http.HandleFunc("/history", web.Auth(config.APIKeyScopeRead, web.Page(web.History)))
+	http.HandleFunc("/historyRecords", web.Auth(config.APIKeyScopeRead, web.HistoryRecords))
+	http.HandleFunc("/domainStatus", web.Auth(config.APIKeyScopeRead, web.DomainStatus))
+	http.HandleFunc("/status", web.Auth(config.APIKeyScopeRead, web.Status))
+	http.HandleFunc("/updateNow", web.Auth(config.APIKeyScopeUpdate, web.UpdateNow))
+	http.HandleFunc("/nic/update", web.APIKeyAuth(config.APIKeyScopeUpdate, web.NicUpdate))
+	http.HandleFunc("/pause", web.Auth(config.APIKeyScopeUpdate, web.Pause))
+	http.HandleFunc("/exportConfig", web.Auth(config.APIKeyScopeFull, web.ExportConfig))
+	http.HandleFunc("/importConfig", web.Auth(config.APIKeyScopeFull, web.ImportConfig))
+	http.HandleFunc("/publicStatus", web.Page(web.PublicStatus))
+	http.HandleFunc("/apiKeys", web.Auth(config.APIKeyScopeFull, web.Page(web.APIKeys)))
+	http.HandleFunc("/audit", web.Auth(config.APIKeyScopeFull, web.Page(web.Audit)))
+	http.HandleFunc("/createApiKey", web.Auth(config.APIKeyScopeFull, web.CreateAPIKey))
+	http.HandleFunc("/revokeApiKey", web.Auth(config.APIKeyScopeFull, web.RevokeAPIKey))
+	http.HandleFunc("/profiles", web.Auth(config.APIKeyScopeFull, web.Page(web.Profiles)))
+	http.HandleFunc("/switchProfile", web.Auth(config.APIKeyScopeFull, web.SwitchProfile))
+	http.HandleFunc("/createProfile", web.Auth(config.APIKeyScopeFull, web.CreateProfile))
+	http.HandleFunc("/deleteProfile", web.Auth(config.APIKeyScopeFull, web.DeleteProfile))
 
-	http.HandleFunc("/", web.BasicAuth(web.Writing))
-	http.HandleFunc("/save", web.BasicAuth(web.Save))
-	http.HandleFunc("/logs", web.BasicAuth(web.Logs))
-	http.HandleFunc("/clearLog", web.BasicAuth(web.ClearLog))
-	http.HandleFunc("/ipv4NetInterface", web.BasicAuth(web.Ipv4NetInterfaces))
-	http.HandleFunc("/ipv6NetInterface", web.BasicAuth(web.Ipv6NetInterfaces))
-	http.HandleFunc("/webhookTest", web.BasicAuth(web.WebhookTest))
+	// 监听所有地址, 任一地址异常则退出. systemd socket激活时使用传递的socket, 不使用-l
+	errCh := make(chan error)
+	listeners, err := util.SdListeners()
+	if err != nil {
+		listenFailed(err)
+	}
+	if len(listeners) == 0 {
+		for _, addr := range util.SplitListenAddrs(*listen) {
+			l, err := util.Listen(addr)
+			if err != nil {
+				listenFailed(err)
+			}
+			listeners = append(listeners, l)
+		}
+	}
+	for _, l := range listeners {
+		log.Println("监听", l.Addr(), "...")
+		go func(l net.Listener) {
+			errCh <- server.Serve(l)
+		}(l)
+	}
 
-	log.Println("监听", *listen, "...")
+	if *grpcListen != "" {
+		serveGRPC(errCh)
+	}
 
 	// 没有配置, 自动打开浏览器
 	autoOpenExplorer()
 
-	// 定时运行
-	go dns.RunTimer(firstDelay, time.Duration(*every)*time.Second)
-	err := http.ListenAndServe(*listen, nil)
+	// 等待网络就绪后定时运行
+	go func() {
+		waitForNetwork()
+		if conf, err := config.GetConfigCache(); err == nil {
+			go config.NotifyStartup(updateCtx, &conf)
+		}
+		// 由systemd启动时通知已就绪, 并定时发送心跳
+		util.SdNotify("READY=1")
+		go sdWatchdog()
+		dns.RunTimer(timerCtx, firstDelay, time.Duration(*every)*time.Second, *cronExpr, time.Duration(*jitter)*time.Second)
+	}()
+
+	// 收到MQTT命令时立即更新或暂停/恢复
+	go dns.RunMQTT(timerCtx)
+
+	// 配置文件被修改时立即更新
+	go config.WatchConfigFile(5*time.Second, dns.RunOnce)
+
+	// 网络重连时立即更新
+	go util.WatchNetworkChange(dns.RunOnce)
+
+	// 收到SIGHUP时重新加载配置
+	go reloadOnSignal()
+
+	// 以服务方式运行时由Stop退出
+	if service.Interactive() {
+		go shutdownOnSignal()
+	}
+
+	if err := <-errCh; err != http.ErrServerClosed {
+		listenFailed(err)
+	}
+	<-stopped
+}
+
+// shutdownOnSignal 收到SIGINT/SIGTERM时退出
+func shutdownOnSignal() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	<-sigCh
+	shutdown()
+}
+
+// shutdown 停止定时运行并关闭HTTP服务, 等待正在进行的更新及通知完成, 超时后取消更新
+func shutdown() {
+	stopOnce.Do(func() {
+		log.Println("正在退出...")
+		util.SdNotify("STOPPING=1")
+		stopTimer()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+		stopGRPC()
+		if dns.Wait(ctx) != nil {
+			cancelUpdate()
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			dns.Wait(ctx)
+		}
+		cancelUpdate()
+		web.SaveLogs()
+		close(stopped)
+	})
+}
+
+// 定时更新超过该时间未按时运行时视为卡住, 不再发送心跳, 由systemd重启
+const watchdogStallTimeout = 30 * time.Minute
 
+// sdWatchdog systemd设置了WatchdogSec时, 每半个间隔发送一次心跳
+func sdWatchdog() {
+	interval := util.SdWatchdogInterval()
+	if interval == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-timerCtx.Done():
+			return
+		case <-ticker.C:
+			if dns.TimerStalled(watchdogStallTimeout) {
+				log.Printf("定时更新超过%s未运行, 停止发送systemd心跳\n", watchdogStallTimeout)
+				continue
+			}
+			util.SdNotify("WATCHDOG=1")
+		}
+	}
+}
+
+// applyHomeAssistantOptions 作为Home Assistant加载项运行时使用加载项的选项, 配置文件保存在 /data 中. 启动参数优先
+func applyHomeAssistantOptions() {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if !set["c"] {
+		*configFilePath = "/data/ddns-go.yaml"
+	}
+	opts, err := util.ReadHomeAssistantOptions()
 	if err != nil {
-		log.Println("启动端口发生异常, 请检查端口是否被占用", err)
-		time.Sleep(time.Minute)
-		os.Exit(1)
+		log.Println(err)
+		return
+	}
+	if opts.Interval > 0 && !set["f"] {
+		*every = opts.Interval
+	}
+	if opts.LogLevel != "" && !set["log-level"] {
+		*logLevel = opts.LogLevel
 	}
 }
 
+// waitForNetwork 等待网络就绪, 开机时网络较慢也不会错过第一次更新
+func waitForNetwork() {
+	if *waitNetwork <= 0 {
+		return
+	}
+	var urls []string
+	if conf, err := config.GetConfigCache(); err == nil {
+		if conf.Ipv4.Enable && config.IsURLGetType(conf.Ipv4.GetType) && conf.Ipv4.URL != "" {
+			urls = append(urls, config.SplitIPURLs(conf.Ipv4.URL)...)
+		}
+		if conf.Ipv6.Enable && config.IsURLGetType(conf.Ipv6.GetType) && conf.Ipv6.URL != "" {
+			urls = append(urls, config.SplitIPURLs(conf.Ipv6.URL)...)
+		}
+		if conf.Ipv4.Enable && conf.Ipv4.GetType == config.GetTypeAuto {
+			urls = append(urls, config.PreferredEndpoint("IPv4"))
+		}
+		if conf.Ipv6.Enable && conf.Ipv6.GetType == config.GetTypeAuto {
+			urls = append(urls, config.PreferredEndpoint("IPv6"))
+		}
+	}
+	if err := util.WaitForNetwork(timerCtx, time.Duration(*waitNetwork)*time.Second, urls); err != nil {
+		log.Printf("等待网络超时, %s, 继续运行\n", err)
+	}
+}
+
+// 打开的日志文件
+var rotateFile *util.RotateFile
+
+// openLogFile 日志同时写入文件
+func openLogFile() {
+	var err error
+	rotateFile, err = util.OpenRotateFile(*logFile, int64(*logMaxSize)<<20,
+		time.Duration(*logMaxAge)*24*time.Hour, *logMaxBackups)
+	if err != nil {
+		log.Fatalf("打开日志文件 %s 失败: %s", *logFile, err)
+	}
+	web.AddLogOutput(rotateFile)
+}
+
+// openSyslog 日志同时发送到syslog或Windows事件日志
+func openSyslog() {
+	if *syslogAddr == "local" {
+		logger, err := getService().SystemLogger(nil)
+		if err != nil {
+			log.Fatalf("打开系统日志失败: %s", err)
+		}
+		web.AddLogHandler(func(level string, msg string) {
+			switch level {
+			case web.LogLevelError:
+				logger.Error(msg)
+			case web.LogLevelWarn:
+				logger.Warning(msg)
+			default:
+				logger.Info(msg)
+			}
+		})
+		return
+	}
+
+	sl, err := util.DialSyslog(*syslogAddr, *serviceName)
+	if err != nil {
+		log.Fatalf("连接syslog失败: %s", err)
+	}
+	web.AddLogHandler(func(level string, msg string) {
+		severity := util.SyslogInfo
+		switch level {
+		case web.LogLevelError:
+			severity = util.SyslogError
+		case web.LogLevelWarn:
+			severity = util.SyslogWarning
+		case web.LogLevelDebug:
+			severity = util.SyslogDebug
+		}
+		sl.Send(severity, msg)
+	})
+}
+
+// reloadOnSignal 收到SIGHUP时重新读取配置文件并更新, 重新打开日志文件
+func reloadOnSignal() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	for range sigCh {
+		if rotateFile != nil {
+			if err := rotateFile.Reopen(); err != nil {
+				log.Printf("重新打开日志文件 %s 失败: %s", *logFile, err)
+			}
+		}
+		log.Println("收到SIGHUP, 重新加载配置")
+		config.ClearConfigCache()
+		dns.RunOnce()
+	}
+}
+
+// 启动端口异常, 延时退出
+func listenFailed(err error) {
+	log.Println("启动端口发生异常, 请检查端口是否被占用", err)
+	time.Sleep(time.Minute)
+	os.Exit(1)
+}
+
 type program struct{}
 
 func (p *program) Start(s service.Service) error {
@@ -112,31 +549,39 @@ func (p *program) Start(s service.Service) error {
 	return nil
 }
 func (p *program) run() {
-	// 服务运行，延时10秒运行，等待网络
-	run(10 * time.Second)
+	// 服务运行, 等待网络就绪后运行
+	run(100 * time.Millisecond)
 }
 func (p *program) Stop(s service.Service) error {
 	// Stop should not block. Return with a few seconds.
+	shutdown()
 	return nil
 }
 
 func getService() service.Service {
 	options := make(service.KeyValue)
-	if service.ChosenSystem().String() == "unix-systemv" {
+	switch service.Platform() {
+	case "unix-systemv":
 		options["SysvScript"] = sysvScript
 		options["UserService"] = false
-	} else {
+	case "linux-systemd":
+		// 就绪前需等待网络
+		options["SystemdScript"] = fmt.Sprintf(systemdScript, *waitNetwork+60)
+		options["UserService"] = true
+	case "freebsd":
+		// 配置文件及运行的用户可在rc.conf中修改
+		options["SysvScript"] = freebsdRcScript(serviceArguments())
+	default:
 		options["UserService"] = true
 	}
 
 	svcConfig := &service.Config{
-		Name:        "ddns-go",
-		DisplayName: "ddns-go",
+		Name:        *serviceName,
+		DisplayName: *serviceName,
 		Description: "简单好用的DDNS。自动更新域名解析到公网IP(支持阿里云、腾讯云dnspod、Cloudflare、华为云)",
-		Arguments:   []string{"-l", *listen, "-f", strconv.Itoa(*every), "-c", *configFilePath},
+		Arguments:   serviceArguments(),
 		Option:      options,
 	}
-
 	prg := &program{}
 	s, err := service.New(prg, svcConfig)
 	if err != nil {
@@ -145,38 +590,159 @@ func getService() service.Service {
 	return s
 }
 
+// serviceArguments 安装服务时的启动参数
+func serviceArguments() []string {
+	args := []string{"-l", *listen, "-f", strconv.Itoa(*every), "-c", *configFilePath}
+	if *cronExpr != "" {
+		args = append(args, "-cron", *cronExpr)
+	}
+	if *logFormat != web.LogFormatText {
+		args = append(args, "-log-format", *logFormat)
+	}
+	if *logLevel != web.LogLevelInfo {
+		args = append(args, "-log-level", *logLevel)
+	}
+	if *timezone != "" {
+		args = append(args, "-tz", *timezone)
+	}
+	if *timeFormat != "" {
+		args = append(args, "-time-format", *timeFormat)
+	}
+	if *otlpEndpoint != "" {
+		args = append(args, "-otlp", *otlpEndpoint)
+	}
+	if *logFile != "" {
+		args = append(args, "-log-file", *logFile,
+			"-log-max-size", strconv.Itoa(*logMaxSize), "-log-max-age", strconv.Itoa(*logMaxAge),
+			"-log-max-backups", strconv.Itoa(*logMaxBackups))
+	}
+	if *logBuffer != 1000 {
+		args = append(args, "-log-buffer", strconv.Itoa(*logBuffer))
+	}
+	if *logPersist {
+		args = append(args, "-log-persist")
+	}
+	if *syslogAddr != "" {
+		args = append(args, "-syslog", *syslogAddr)
+	}
+	if *waitNetwork != 60 {
+		args = append(args, "-wait-network", strconv.Itoa(*waitNetwork))
+	}
+	if *jitter > 0 {
+		args = append(args, "-jitter", strconv.Itoa(*jitter))
+	}
+	if *serviceName != "ddns-go" {
+		// 以服务方式运行时按名称判断是否为服务
+		args = append(args, "-name", *serviceName)
+	}
+	if *grpcListen != "" {
+		args = append(args, "-grpc", *grpcListen,
+			"-grpc-cert", *grpcCert, "-grpc-key", *grpcKey, "-grpc-ca", *grpcCA)
+	}
+	if *pluginDir != "" {
+		args = append(args, "-plugins", *pluginDir)
+	}
+	if *noWeb {
+		args = append(args, "-noweb")
+	}
+	return args
+}
+
 // 卸载服务
 func uninstallService() {
+	if service.Platform() == "" && runtime.GOOS == "openbsd" {
+		uninstallOpenBSDService()
+		return
+	}
 	s := getService()
 	s.Stop()
 	if err := s.Uninstall(); err == nil {
-		log.Println("ddns-go 服务卸载成功!")
+		if service.Platform() == "freebsd" {
+			os.Remove(freebsdRcConfPath())
+		}
+		log.Printf("%s 服务卸载成功!\n", *serviceName)
+	} else {
+		log.Printf("%s 服务卸载失败, ERR: %s\n", *serviceName, err)
+	}
+}
+
+// controlService 启动、停止或重启服务
+func controlService(action string) {
+	var err error
+	if service.Platform() == "" && runtime.GOOS == "openbsd" {
+		err = rcctl(action)
 	} else {
-		log.Printf("ddns-go 服务卸载失败, ERR: %s\n", err)
+		err = service.Control(getService(), action)
+	}
+	if err != nil {
+		log.Fatalf("%s 服务%s失败, ERR: %s\n", *serviceName, serviceActions[action], err)
+	}
+	log.Printf("%s 服务%s成功\n", *serviceName, serviceActions[action])
+}
+
+// 服务操作的名称
+var serviceActions = map[string]string{"start": "启动", "stop": "停止", "restart": "重启"}
+
+// serviceStatus 输出服务状态, 运行中返回0, 已停止返回3, 未安装等返回4
+func serviceStatus() int {
+	if service.Platform() == "" && runtime.GOOS == "openbsd" {
+		if _, err := os.Stat(openbsdRcPath()); err != nil {
+			fmt.Printf("%s 服务未安装\n", *serviceName)
+			return 4
+		}
+		if rcctl("check") != nil {
+			fmt.Printf("%s 服务已停止\n", *serviceName)
+			return 3
+		}
+		fmt.Printf("%s 服务运行中\n", *serviceName)
+		return 0
+	}
+
+	status, err := getService().Status()
+	switch {
+	case err == service.ErrNotInstalled || (err != nil && status == service.StatusUnknown):
+		fmt.Printf("%s 服务未安装\n", *serviceName)
+		return 4
+	case status == service.StatusRunning:
+		fmt.Printf("%s 服务运行中\n", *serviceName)
+		return 0
+	default:
+		fmt.Printf("%s 服务已停止\n", *serviceName)
+		return 3
 	}
 }
 
 // 安装服务
 func installService() {
+	if service.Platform() == "" && runtime.GOOS == "openbsd" {
+		installOpenBSDService()
+		return
+	}
 	s := getService()
 
 	status, err := s.Status()
-	if err != nil && status == service.StatusUnknown {
+	// FreeBSD未安装时返回StatusStopped
+	if (err != nil && status == service.StatusUnknown) || err == service.ErrNotInstalled {
 		// 服务未知，创建服务
 		if err = s.Install(); err == nil {
+			if service.Platform() == "freebsd" {
+				if err := enableFreeBSDService(); err != nil {
+					log.Printf("设置开机启动失败, ERR: %s\n", err)
+				}
+			}
 			s.Start()
-			log.Println("安装 ddns-go 服务成功! 请打开浏览器并进行配置。")
-			if service.ChosenSystem().String() == "unix-systemv" {
+			log.Printf("安装 %s 服务成功! 请打开浏览器并进行配置。\n", *serviceName)
+			if service.Platform() == "unix-systemv" {
 				log.Println("如不能访问，请重启")
 			}
 			return
 		}
 
-		log.Printf("安装 ddns-go 服务失败, ERR: %s\n", err)
+		log.Printf("安装 %s 服务失败, ERR: %s\n", *serviceName, err)
 	}
 
 	if status != service.StatusUnknown {
-		log.Println("ddns-go 服务已安装, 无需在次安装")
+		log.Printf("%s 服务已安装, 无需在次安装\n", *serviceName)
 	}
 }
 
@@ -185,13 +751,21 @@ func autoOpenExplorer() {
 	_, err := config.GetConfigCache()
 	// 未找到配置文件
 	if err != nil {
-		if util.IsRunInDocker() {
+		if !web.UIEnabled() {
+			fmt.Println("网页已禁用, 请创建配置文件", util.GetConfigFilePath())
+		} else if util.IsRunInDocker() {
 			// docker中运行, 提示
 			fmt.Println("Docker中运行, 请在浏览器中打开 http://docker主机IP:端口 进行配置")
 		} else {
-			// 主机运行, 打开浏览器
-			addr, err := net.ResolveTCPAddr("tcp", *listen)
-			if err != nil {
+			// 主机运行, 打开浏览器. 使用第一个TCP监听地址
+			var addr *net.TCPAddr
+			for _, listenAddr := range util.SplitListenAddrs(*listen) {
+				if !util.IsUnixSocketAddr(listenAddr) {
+					addr, _ = net.ResolveTCPAddr("tcp", listenAddr)
+					break
+				}
+			}
+			if addr == nil {
 				return
 			}
 			url := fmt.Sprintf("http://127.0.0.1:%d", addr.Port)
@@ -203,13 +777,40 @@ func autoOpenExplorer() {
 	}
 }
 
+// systemdScript 就绪后通知systemd, 心跳超时时重启. %d为启动超时时间(秒)
+const systemdScript = `[Unit]
+Description={{.Description}}
+ConditionFileIsExecutable={{.Path|cmdEscape}}
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+WatchdogSec=60
+TimeoutStartSec=%d
+StartLimitInterval=5
+StartLimitBurst=10
+ExecStart={{.Path|cmdEscape}}{{range .Arguments}} {{.|cmd}}{{end}}
+{{if .WorkingDirectory}}WorkingDirectory={{.WorkingDirectory|cmdEscape}}{{end}}
+{{if .UserName}}User={{.UserName}}{{end}}
+{{if .PIDFile}}PIDFile={{.PIDFile|cmd}}{{end}}
+{{if gt .LimitNOFILE -1 }}LimitNOFILE={{.LimitNOFILE}}{{end}}
+{{if .Restart}}Restart={{.Restart}}{{end}}
+RestartSec=120
+EnvironmentFile=-/etc/sysconfig/{{.Name}}
+
+[Install]
+WantedBy=multi-user.target
+`
+
 const sysvScript = `#!/bin/sh
 # For RedHat and cousins:
 # chkconfig: - 99 01
 # description: {{.Description}}
 # processname: {{.Path}}
 ### BEGIN INIT INFO
-# Provides:          {{.Path}}
+# Provides:          {{.Name}}
 # Required-Start:
 # Required-Stop:
 # Default-Start:     2 3 4 5
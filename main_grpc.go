@@ -0,0 +1,48 @@
+//go:build !noweb
+// +build !noweb
+
+package main
+
+import (
+	"ddns-go/plugin"
+	"ddns-go/rpc"
+	"log"
+	"net"
+
+	"google.golang.org/grpc"
+)
+
+// 启用gRPC管理接口时退出时关闭
+var grpcServer *grpc.Server
+
+// serveGRPC 启动gRPC管理接口, 异常时与HTTP服务相同退出
+func serveGRPC(errCh chan error) {
+	var err error
+	grpcServer, err = rpc.NewServer(*grpcCert, *grpcKey, *grpcCA)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	l, err := net.Listen("tcp", *grpcListen)
+	if err != nil {
+		listenFailed(err)
+	}
+	log.Println("gRPC管理接口监听", *grpcListen, "...")
+	go func() {
+		if err := grpcServer.Serve(l); err != nil {
+			errCh <- err
+		}
+	}()
+}
+
+func stopGRPC() {
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+}
+
+// loadPlugins 加载插件目录中的DNS服务商插件
+func loadPlugins() {
+	if err := plugin.Load(*pluginDir); err != nil {
+		log.Println(err)
+	}
+}
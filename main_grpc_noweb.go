@@ -0,0 +1,17 @@
+//go:build noweb
+// +build noweb
+
+package main
+
+import "log"
+
+// 使用 noweb 构建时不包含gRPC管理接口及插件, 减小程序体积
+func serveGRPC(errCh chan error) {
+	log.Println("使用 noweb 构建的版本不支持gRPC管理接口, 忽略 -grpc")
+}
+
+func stopGRPC() {}
+
+func loadPlugins() {
+	log.Println("使用 noweb 构建的版本不支持插件, 忽略 -plugins")
+}
@@ -0,0 +1,9 @@
+//go:build noweb
+// +build noweb
+
+package main
+
+import "embed"
+
+// 使用 noweb 构建时不包含网页的静态文件
+var staticEmbededFiles, faviconEmbededFile embed.FS
@@ -0,0 +1,12 @@
+//go:build !noweb
+// +build !noweb
+
+package main
+
+import "embed"
+
+//go:embed static
+var staticEmbededFiles embed.FS
+
+//go:embed favicon.ico
+var faviconEmbededFile embed.FS
@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// 启动插件及获取信息的超时时间
+var startTimeout = 10 * time.Second
+
+// 关闭插件时等待退出的时间, 超时后结束进程
+var stopTimeout = 5 * time.Second
+
+// Client 启动的插件
+type Client struct {
+	ProviderClient
+	cmd    *exec.Cmd
+	conn   *grpc.ClientConn
+	stdin  io.WriteCloser
+	exited chan struct{}
+}
+
+// Start 启动插件并连接, 使用完成后需调用Close
+func Start(ctx context.Context, path string) (*Client, error) {
+	name := filepath.Base(path)
+	handshake := make(chan string, 1)
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), protocolEnv+"="+strconv.Itoa(ProtocolVersion))
+	cmd.Stdout = &outputWriter{name: name, handshake: handshake}
+	cmd.Stderr = &outputWriter{name: name}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := &Client{cmd: cmd, stdin: stdin, exited: make(chan struct{})}
+	go func() {
+		cmd.Wait()
+		close(c.exited)
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+	var line string
+	select {
+	case line = <-handshake:
+	case <-c.exited:
+		return nil, fmt.Errorf("插件已退出: %s", cmd.ProcessState)
+	case <-ctx.Done():
+		c.Close()
+		return nil, fmt.Errorf("插件启动超时")
+	}
+
+	target, err := parseHandshake(line)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	c.conn, err = grpc.DialContext(ctx, target, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("连接插件失败: %s", err)
+	}
+	c.ProviderClient = NewProviderClient(c.conn)
+	return c, nil
+}
+
+// parseHandshake 解析插件输出的第一行, 格式为 协议版本|unix或tcp|地址
+func parseHandshake(line string) (target string, err error) {
+	parts := strings.SplitN(strings.TrimSpace(line), "|", 3)
+	if len(parts) != 3 {
+		return "", fmt.Errorf("插件的输出 %q 不正确, 需在main函数中调用plugin.Serve", line)
+	}
+	if parts[0] != strconv.Itoa(ProtocolVersion) {
+		return "", fmt.Errorf("插件的协议版本 %s 不支持, 支持的版本为 %d", parts[0], ProtocolVersion)
+	}
+	switch parts[1] {
+	case "unix":
+		return "unix://" + parts[2], nil
+	case "tcp":
+		return parts[2], nil
+	}
+	return "", fmt.Errorf("插件的网络类型 %s 不支持", parts[1])
+}
+
+// Close 关闭标准输入通知插件退出, 超时后结束进程
+func (c *Client) Close() error {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.stdin.Close()
+	select {
+	case <-c.exited:
+	case <-time.After(stopTimeout):
+		c.cmd.Process.Kill()
+		<-c.exited
+	}
+	return nil
+}
+
+// outputWriter 插件的输出按行写入日志, handshake不为nil时第一行为连接的地址
+type outputWriter struct {
+	name      string
+	handshake chan string
+	buf       bytes.Buffer
+	lock      sync.Mutex
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// 不完整的行留到下次
+			w.buf.WriteString(line)
+			return len(p), nil
+		}
+		if w.handshake != nil {
+			w.handshake <- line
+			w.handshake = nil
+			continue
+		}
+		log.Printf("插件 %s: %s", w.name, line)
+	}
+}
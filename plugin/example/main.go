@@ -0,0 +1,28 @@
+// 插件示例, 编译后命名为 ddns-go-provider-example 放到插件目录中
+// 在配置中选择 example 或使用 plugin-example 作为DNS服务商的名称
+package main
+
+import (
+	"context"
+	"ddns-go/plugin"
+	"log"
+)
+
+type provider struct {
+	plugin.UnimplementedProviderServer
+}
+
+// Info 网页中显示的名称及ID/Secret的名称
+func (provider) Info(ctx context.Context, req *plugin.InfoRequest) (*plugin.InfoResponse, error) {
+	return &plugin.InfoResponse{Name: "Example", Version: "1.0.0", IdLabel: "API Key", SecretLabel: "API Secret"}, nil
+}
+
+// UpdateRecord 在此调用DNS服务商的接口, 标准错误输出会写入ddns-go的日志
+func (provider) UpdateRecord(ctx context.Context, req *plugin.UpdateRecordRequest) (*plugin.UpdateRecordResponse, error) {
+	log.Printf("更新 %s %s 为 %s, TTL %s\n", req.RecordType, req.Domain, req.Ip, req.Ttl)
+	return &plugin.UpdateRecordResponse{Result: plugin.UpdateRecordResponse_SUCCESS}, nil
+}
+
+func main() {
+	plugin.Serve(provider{})
+}
@@ -0,0 +1,171 @@
+// Package plugin DNS服务商插件, 插件为单独发布的程序, 由ddns-go启动后通过gRPC调用
+//
+// 插件的main函数中调用 plugin.Serve 即可, 放到插件目录中并命名为 ddns-go-provider-<名称>,
+// 在配置中使用 plugin-<名称> 作为DNS服务商的名称
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// ProtocolVersion 插件协议的版本, 不兼容的修改时增加
+const ProtocolVersion = 1
+
+// 启动插件时设置的环境变量, 值为协议的版本
+const protocolEnv = "DDNS_GO_PLUGIN"
+
+// FilePrefix 插件目录中插件的文件名前缀
+const FilePrefix = "ddns-go-provider-"
+
+// NamePrefix 配置中插件的DNS服务商名称的前缀
+const NamePrefix = "plugin-"
+
+// 插件名称可使用的字符
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Plugin 插件目录中加载成功的插件
+type Plugin struct {
+	// 配置中DNS服务商的名称, 如 plugin-example
+	Name string
+	Path string
+	Info *InfoResponse
+}
+
+var plugins []Plugin
+var pluginsLock sync.RWMutex
+
+// Serve 启动插件的gRPC服务, 并在标准输出的第一行告知ddns-go连接的地址, ddns-go关闭标准输入后退出
+func Serve(provider ProviderServer) {
+	if os.Getenv(protocolEnv) != strconv.Itoa(ProtocolVersion) {
+		fmt.Fprintln(os.Stderr, "此程序为ddns-go的DNS服务商插件, 请放到ddns-go的插件目录中, 由ddns-go启动")
+		os.Exit(1)
+	}
+
+	// 输出会写入ddns-go的日志, 不需要再输出时间
+	log.SetFlags(0)
+	l, cleanup, err := listen()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer cleanup()
+
+	server := grpc.NewServer()
+	RegisterProviderServer(server, provider)
+	fmt.Printf("%d|%s|%s\n", ProtocolVersion, l.Addr().Network(), l.Addr().String())
+	go func() {
+		io.Copy(ioutil.Discard, os.Stdin)
+		server.GracefulStop()
+	}()
+	if err := server.Serve(l); err != nil {
+		log.Println(err)
+	}
+}
+
+// listen 监听临时目录中的unix socket, 只有当前用户可连接. Windows中监听127.0.0.1的随机端口
+func listen() (l net.Listener, cleanup func(), err error) {
+	if runtime.GOOS == "windows" {
+		l, err = net.Listen("tcp", "127.0.0.1:0")
+		return l, func() {}, err
+	}
+	dir, err := ioutil.TempDir("", "ddns-go-plugin")
+	if err != nil {
+		return nil, nil, err
+	}
+	l, err = net.Listen("unix", filepath.Join(dir, "plugin.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	return l, func() { os.RemoveAll(dir) }, nil
+}
+
+// Load 加载插件目录中的插件, 启动每个插件获取信息后关闭, 失败的插件只输出日志
+func Load(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("读取插件目录 %s 失败: %s", dir, err)
+	}
+
+	loaded := []Plugin{}
+	for _, file := range files {
+		name := strings.TrimPrefix(file.Name(), FilePrefix)
+		if runtime.GOOS == "windows" {
+			name = strings.TrimSuffix(name, ".exe")
+		}
+		if name == file.Name() || !nameRegexp.MatchString(name) {
+			continue
+		}
+		if !file.Mode().IsRegular() || (runtime.GOOS != "windows" && file.Mode()&0111 == 0) {
+			log.Printf("插件 %s 不是可执行文件\n", file.Name())
+			continue
+		}
+
+		p := Plugin{Name: NamePrefix + name, Path: filepath.Join(dir, file.Name())}
+		p.Info, err = loadInfo(p.Path)
+		if err != nil {
+			log.Printf("加载插件 %s 失败: %s\n", p.Path, err)
+			continue
+		}
+		if p.Info.Name == "" {
+			p.Info.Name = name
+		}
+		if !strings.HasPrefix(p.Info.HelpUrl, "https://") && !strings.HasPrefix(p.Info.HelpUrl, "http://") {
+			p.Info.HelpUrl = ""
+		}
+		log.Printf("已加载插件 %s %s, DNS服务商名称为 %s\n", p.Info.Name, p.Info.Version, p.Name)
+		loaded = append(loaded, p)
+	}
+
+	pluginsLock.Lock()
+	defer pluginsLock.Unlock()
+	plugins = loaded
+	return nil
+}
+
+// loadInfo 启动插件获取信息
+func loadInfo(path string) (*InfoResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+	client, err := Start(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return client.Info(ctx, &InfoRequest{})
+}
+
+// List 已加载的插件
+func List() []Plugin {
+	pluginsLock.RLock()
+	defer pluginsLock.RUnlock()
+	return plugins
+}
+
+// Lookup 根据配置中DNS服务商的名称查找插件
+func Lookup(name string) (Plugin, bool) {
+	for _, p := range List() {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Plugin{}, false
+}
+
+// IsPluginName 是否为插件的DNS服务商名称
+func IsPluginName(name string) bool {
+	return strings.HasPrefix(name, NamePrefix)
+}
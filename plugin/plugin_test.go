@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+type testProvider struct {
+	UnimplementedProviderServer
+}
+
+func (testProvider) Info(ctx context.Context, req *InfoRequest) (*InfoResponse, error) {
+	return &InfoResponse{Version: "1.0", IdLabel: "Key", HelpUrl: "javascript:alert(1)"}, nil
+}
+
+func (testProvider) UpdateRecord(ctx context.Context, req *UpdateRecordRequest) (*UpdateRecordResponse, error) {
+	if req.Secret != "secret" {
+		return &UpdateRecordResponse{Result: UpdateRecordResponse_FAILED, Message: "Secret不正确"}, nil
+	}
+	return &UpdateRecordResponse{Result: UpdateRecordResponse_SUCCESS, Message: req.Domain + " " + req.Ip}, nil
+}
+
+// 由ddns-go启动时作为插件运行
+func TestMain(m *testing.M) {
+	if os.Getenv(protocolEnv) != "" {
+		Serve(testProvider{})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// TestLoad 加载插件目录中的插件并调用
+func TestLoad(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要sh")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\nexec '" + os.Args[0] + "'\n"
+	ioutil.WriteFile(filepath.Join(dir, FilePrefix+"test"), []byte(script), 0700)
+	// 不可执行及名称不正确的文件不加载
+	ioutil.WriteFile(filepath.Join(dir, FilePrefix+"noexec"), []byte(script), 0600)
+	ioutil.WriteFile(filepath.Join(dir, FilePrefix+"a.b"), []byte(script), 0700)
+
+	if err := Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(List()) != 1 {
+		t.Fatalf("应加载1个插件, 加载了 %d 个", len(List()))
+	}
+	p, ok := Lookup("plugin-test")
+	if !ok {
+		t.Fatal("未找到插件 plugin-test")
+	}
+	if p.Info.Name != "test" || p.Info.Version != "1.0" || p.Info.IdLabel != "Key" || p.Info.HelpUrl != "" {
+		t.Errorf("插件的信息不正确: %v", p.Info)
+	}
+
+	client, err := Start(context.Background(), p.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	resp, err := client.UpdateRecord(context.Background(), &UpdateRecordRequest{Secret: "secret", Domain: "www.example.com", Ip: "1.2.3.4"})
+	if err != nil || resp.Result != UpdateRecordResponse_SUCCESS || resp.Message != "www.example.com 1.2.3.4" {
+		t.Errorf("调用插件失败: %v %v", resp, err)
+	}
+}
+
+// TestParseHandshake 解析插件输出的连接地址
+func TestParseHandshake(t *testing.T) {
+	tests := []struct {
+		line   string
+		target string
+		ok     bool
+	}{
+		{"1|unix|/tmp/ddns-go-plugin/plugin.sock\n", "unix:///tmp/ddns-go-plugin/plugin.sock", true},
+		{"1|tcp|127.0.0.1:1234\n", "127.0.0.1:1234", true},
+		{"2|tcp|127.0.0.1:1234\n", "", false},
+		{"1|udp|127.0.0.1:1234\n", "", false},
+		{"hello\n", "", false},
+	}
+	for _, tt := range tests {
+		target, err := parseHandshake(tt.line)
+		if (err == nil) != tt.ok || target != tt.target {
+			t.Errorf("%q: %q %v", tt.line, target, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package provider 在其它程序中使用ddns-go支持的DNS服务商更新解析记录
+//
+// 只更新传入的IP及域名, 不获取IP, 不保存状态、历史记录, 不发送通知. 日志输出到标准库的log.
+// Callback及自定义程序在Updater中记住每个URL/程序上次成功更新的IP, 未变化时不再调用. 不同的Updater互不影响
+package provider
+
+import (
+	"context"
+	"ddns-go/config"
+	"ddns-go/dns"
+	"ddns-go/plugin"
+	"fmt"
+)
+
+// Config DNS服务商的配置, 与配置文件中的dns相同
+type Config = config.DNSConfig
+
+// 更新结果
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusNothing = "nothing"
+)
+
+// Request 要更新的IP及域名, 域名的格式与网页中填写的相同, 如 www.example.com
+type Request struct {
+	Ipv4Addr    string
+	Ipv4Domains []string
+	Ipv6Addr    string
+	Ipv6Domains []string
+	// 为空时使用DNS服务商的默认值
+	TTL string
+}
+
+// Result 域名的更新结果
+type Result struct {
+	Domain     string
+	RecordType string
+	IP         string
+	// success, failed 或 nothing(记录已是该IP)
+	Status string
+}
+
+// Names 支持的DNS服务商, 及已通过 plugin.Load 加载的插件
+func Names() []string {
+	names := []string{"alidns", "dnspod", "cloudflare", "huaweicloud", "callback", "exec"}
+	for _, p := range plugin.List() {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// Updater 单独记录上次成功更新的IP及缓存的记录, 与ddns-go的定时更新及其它Updater互不影响
+type Updater struct {
+	state *dns.State
+}
+
+// NewUpdater 创建Updater
+func NewUpdater() *Updater {
+	return &Updater{state: dns.NewState()}
+}
+
+// 包级别的Update使用的Updater
+var defaultUpdater = NewUpdater()
+
+// Update 使用默认的Updater更新记录, 同 Updater.Update
+func Update(ctx context.Context, conf Config, req Request) ([]Result, error) {
+	return defaultUpdater.Update(ctx, conf, req)
+}
+
+// Update 使用DNS服务商更新记录, 返回每个域名的结果, ctx取消时停止. IP为空的记录类型不更新
+func (u *Updater) Update(ctx context.Context, conf Config, req Request) ([]Result, error) {
+	provider := dns.NewDNS(conf.Name)
+	if provider == nil {
+		return nil, fmt.Errorf("不支持的DNS服务商 %s", conf.Name)
+	}
+	if _, ok := plugin.Lookup(conf.Name); plugin.IsPluginName(conf.Name) && !ok {
+		return nil, fmt.Errorf("插件 %s 未加载", conf.Name)
+	}
+
+	domains := config.Domains{Ipv4Addr: req.Ipv4Addr, Ipv6Addr: req.Ipv6Addr}
+	var err error
+	if domains.Ipv4Domains, err = parseDomains(req.Ipv4Domains); err != nil {
+		return nil, err
+	}
+	if domains.Ipv6Domains, err = parseDomains(req.Ipv6Domains); err != nil {
+		return nil, err
+	}
+
+	provider.Init(dns.WithState(ctx, u.state), &config.Config{DNS: conf, TTL: req.TTL}, domains)
+	domains = provider.AddUpdateDomainRecords()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var results []Result
+	for _, recordType := range []string{"A", "AAAA"} {
+		ipAddr, recordDomains := domains.GetNewIpResult(recordType)
+		if ipAddr == "" {
+			continue
+		}
+		for _, domain := range recordDomains {
+			results = append(results, Result{
+				Domain:     domain.String(),
+				RecordType: recordType,
+				IP:         ipAddr,
+				Status:     status(string(domain.UpdateStatus)),
+			})
+		}
+	}
+	return results, nil
+}
+
+// parseDomains 解析域名, 有不正确的域名时返回错误
+func parseDomains(domainArr []string) (domains []*config.Domain, err error) {
+	for _, domainStr := range domainArr {
+		domain := config.ParseDomain(domainStr)
+		if domain == nil {
+			return nil, fmt.Errorf("域名 %s 不正确", domainStr)
+		}
+		domains = append(domains, domain)
+	}
+	return
+}
+
+// status 转换更新状态, 未设置时为记录已是该IP
+func status(updateStatus string) string {
+	switch updateStatus {
+	case config.UpdatedSuccess:
+		return StatusSuccess
+	case config.UpdatedFailed:
+		return StatusFailed
+	}
+	return StatusNothing
+}
@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// TestUpdate 使用Callback更新, 不获取IP
+func TestUpdate(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	conf := Config{Name: "callback", ID: server.URL, Secret: "#{domain} #{ip} #{ttl}"}
+	req := Request{Ipv4Addr: "1.2.3.4", Ipv4Domains: []string{"www.example.com"}, Ipv6Domains: []string{"example.com"}, TTL: "60"}
+	results, err := Update(context.Background(), conf, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, []Result{{Domain: "www.example.com", RecordType: "A", IP: "1.2.3.4", Status: StatusSuccess}}) {
+		t.Errorf("结果不正确: %v", results)
+	}
+	if !reflect.DeepEqual(bodies, []string{"www.example.com 1.2.3.4 60"}) {
+		t.Errorf("Callback的内容不正确: %v", bodies)
+	}
+
+	// 同一Callback的IP未变化时不再调用
+	results, _ = Update(context.Background(), conf, req)
+	if len(bodies) != 1 || results[0].Status != StatusNothing {
+		t.Errorf("IP未变化时不应调用: %v %v", results, bodies)
+	}
+
+	// 不同的Updater单独记录上次的IP
+	if results, _ = NewUpdater().Update(context.Background(), conf, req); len(bodies) != 2 || results[0].Status != StatusSuccess {
+		t.Errorf("新的Updater应调用Callback: %v %v", results, bodies)
+	}
+
+	if _, err := Update(context.Background(), Config{Name: "unknown"}, req); err == nil {
+		t.Error("不支持的DNS服务商应返回错误")
+	}
+	if _, err := Update(context.Background(), conf, Request{Ipv4Addr: "1.2.3.4", Ipv4Domains: []string{"localhost"}}); err == nil {
+		t.Error("不正确的域名应返回错误")
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// rc.d中变量名不可使用的字符
+var rcNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// shell中需加引号的字符
+var shellSpecialRegexp = regexp.MustCompile(`[^a-zA-Z0-9_./:,@%+=-]`)
+
+// rcName rc.d中使用的名称, 如 ddns-go 为 ddns_go, rc.conf中为 ddns_go_enable
+func rcName() string {
+	return rcNameRegexp.ReplaceAllString(*serviceName, "_")
+}
+
+// shellQuote 包含特殊字符的参数加单引号
+func shellQuote(arg string) string {
+	if arg != "" && !shellSpecialRegexp.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// splitConfigArg 从启动参数中取出配置文件, 其它参数加引号后用空格连接
+func splitConfigArg(args []string) (configPath string, others string) {
+	var quoted []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-c" && i+1 < len(args) {
+			configPath = args[i+1]
+			i++
+			continue
+		}
+		quoted = append(quoted, shellQuote(args[i]))
+	}
+	return configPath, strings.Join(quoted, " ")
+}
+
+// freebsdRcScript FreeBSD(pfSense, OPNsense, TrueNAS CORE)的rc.d脚本
+// 可在 /etc/rc.conf 或 /etc/rc.conf.d/ddns_go 中修改配置文件、运行的用户及其它参数
+func freebsdRcScript(args []string) string {
+	configPath, others := splitConfigArg(args)
+	return strings.NewReplacer("RCNAME", rcName(), "CONFIG", configPath, "ARGS", others).Replace(`#!/bin/sh
+
+# PROVIDE: RCNAME
+# REQUIRE: LOGIN NETWORKING
+# KEYWORD: shutdown
+#
+# 在 /etc/rc.conf 或 /etc/rc.conf.d/RCNAME 中设置:
+# RCNAME_enable="YES"   开机启动
+# RCNAME_config=""      配置文件
+# RCNAME_user="root"    运行的用户, 需可读写配置文件
+# RCNAME_args=""        其它启动参数
+
+. /etc/rc.subr
+
+name="RCNAME"
+rcvar="RCNAME_enable"
+
+load_rc_config $name
+
+: ${RCNAME_enable:="NO"}
+: ${RCNAME_config:="CONFIG"}
+: ${RCNAME_user:="root"}
+: ${RCNAME_args:="ARGS"}
+
+RCNAME_env="IS_DAEMON=1"
+pidfile="/var/run/${name}.pid"
+procname="{{.Path}}"
+command="/usr/sbin/daemon"
+command_args="-S -T ${name} -p ${pidfile} -u ${RCNAME_user} ${procname} -c \"${RCNAME_config}\" ${RCNAME_args}"
+
+run_rc_command "$1"
+`)
+}
+
+// freebsdRcConfPath 开机启动的设置, OPNsense等重新生成rc.conf时不会丢失
+func freebsdRcConfPath() string {
+	return "/etc/rc.conf.d/" + rcName()
+}
+
+// enableFreeBSDService 设置开机启动, 已设置时不修改
+func enableFreeBSDService() error {
+	path := freebsdRcConfPath()
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(rcName()+"_enable=\"YES\"\n"), 0644)
+}
+
+// openbsdRcPath OpenBSD的rc.d脚本路径
+func openbsdRcPath() string {
+	return "/etc/rc.d/" + rcName()
+}
+
+// openbsdRcScript OpenBSD的rc.d脚本, 可使用 rcctl set ddns_go flags/user 修改启动参数及运行的用户
+func openbsdRcScript(path string, args []string) string {
+	configPath, others := splitConfigArg(args)
+	return fmt.Sprintf(`#!/bin/ksh
+#
+# 修改启动参数: rcctl set %[1]s flags "-c /etc/ddns-go.yaml -l :9876 -f 300"
+# 修改运行的用户: rcctl set %[1]s user _ddnsgo, 需可读写配置文件
+
+daemon=%[2]s
+daemon_flags="%[3]s"
+daemon_user="root"
+daemon_logger="daemon.info"
+
+. /etc/rc.d/rc.subr
+
+rc_bg=YES
+rc_reload=NO
+
+rc_cmd $1
+`, rcName(), shellQuote(path), "-c "+shellQuote(configPath)+" "+others)
+}
+
+// installOpenBSDService 安装OpenBSD的rc.d服务, 设置开机启动并启动
+func installOpenBSDService() {
+	rcPath := openbsdRcPath()
+	if _, err := os.Stat(rcPath); err == nil {
+		log.Printf("%s 服务已安装, 无需在次安装\n", *serviceName)
+		return
+	}
+	path, err := os.Executable()
+	if err == nil {
+		err = ioutil.WriteFile(rcPath, []byte(openbsdRcScript(path, serviceArguments())), 0555)
+	}
+	if err == nil {
+		err = rcctl("enable")
+	}
+	if err == nil {
+		err = rcctl("start")
+	}
+	if err != nil {
+		log.Printf("安装 %s 服务失败, ERR: %s\n", *serviceName, err)
+		return
+	}
+	log.Printf("安装 %s 服务成功! 请打开浏览器并进行配置。\n", *serviceName)
+}
+
+// uninstallOpenBSDService 停止并卸载OpenBSD的rc.d服务
+func uninstallOpenBSDService() {
+	rcctl("stop")
+	rcctl("disable")
+	if err := os.Remove(openbsdRcPath()); err != nil {
+		log.Printf("%s 服务卸载失败, ERR: %s\n", *serviceName, err)
+		return
+	}
+	log.Printf("%s 服务卸载成功!\n", *serviceName)
+}
+
+// rcctl 执行OpenBSD的rcctl
+func rcctl(action string) error {
+	out, err := exec.Command("rcctl", action, rcName()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rcctl %s %s: %s %s", action, rcName(), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
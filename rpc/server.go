@@ -0,0 +1,198 @@
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ddns.proto
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"ddns-go/config"
+	"ddns-go/dns"
+	"ddns-go/web"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+)
+
+// server 实现DDNS接口
+type server struct {
+	UnimplementedDDNSServer
+}
+
+// NewServer 创建gRPC管理接口, 只允许使用clientCAFile签发的客户端证书连接
+func NewServer(certFile string, keyFile string, clientCAFile string) (*grpc.Server, error) {
+	tlsConfig, err := serverTLSConfig(certFile, keyFile, clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	RegisterDDNSServer(s, &server{})
+	return s, nil
+}
+
+// serverTLSConfig 服务端证书及验证客户端证书的CA
+func serverTLSConfig(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" || clientCAFile == "" {
+		return nil, errors.New("gRPC管理接口需设置服务端证书、私钥及客户端的CA证书")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取gRPC服务端证书失败: %s", err)
+	}
+	ca, err := ioutil.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取gRPC客户端的CA证书失败: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("gRPC客户端的CA证书 %s 不正确", clientCAFile)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// GetStatus 运行状态
+func (s *server) GetStatus(ctx context.Context, req *GetStatusRequest) (*Status, error) {
+	result := web.GetStatus()
+	return &Status{
+		Version:       result.Version,
+		StartTime:     unix(result.StartTime),
+		Uptime:        result.Uptime,
+		Ok:            result.OK,
+		Provider:      result.Provider,
+		Ipv4Addr:      result.Ipv4Addr,
+		Ipv6Addr:      result.Ipv6Addr,
+		LastRun:       unix(result.LastRun),
+		NextRun:       unix(result.NextRun),
+		PausedUntil:   unix(result.PausedUntil),
+		LastError:     result.LastError,
+		LastErrorTime: unix(result.LastErrorTime),
+		Domains:       domainStatus(result.Domains, ""),
+	}, nil
+}
+
+// TriggerUpdate 立即更新
+func (s *server) TriggerUpdate(ctx context.Context, req *TriggerUpdateRequest) (*TriggerUpdateResponse, error) {
+	provider := strings.TrimSpace(req.Provider)
+	domain := strings.TrimSpace(req.Domain)
+	if err := dns.RunManual(provider, domain); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &TriggerUpdateResponse{Domains: domainStatus(dns.GetStatus().Domains, domain)}, nil
+}
+
+// GetConfig 获取配置
+func (s *server) GetConfig(ctx context.Context, req *GetConfigRequest) (*GetConfigResponse, error) {
+	conf, err := config.GetConfigCache()
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "未找到配置文件")
+	}
+	if req.Redact {
+		conf = conf.Redacted()
+	}
+	byt, err := yaml.Marshal(conf)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &GetConfigResponse{Yaml: string(byt)}, nil
+}
+
+// SetConfig 覆盖配置
+func (s *server) SetConfig(ctx context.Context, req *SetConfigRequest) (*SetConfigResponse, error) {
+	conf, err := config.ParseConfig("config.yaml", []byte(req.Yaml))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "配置文件不正确: "+err.Error())
+	}
+	old, _ := config.GetConfigCache()
+	if err := keepSecrets(old, &conf); err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if errs := conf.Validate(); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		return nil, status.Error(codes.InvalidArgument, strings.Join(msgs, "; "))
+	}
+	if err = conf.SaveConfig(); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	if changes := config.DiffConfig(old, conf); len(changes) > 0 {
+		user, ip := peerInfo(ctx)
+		config.AddAuditRecord(config.AuditRecord{
+			Type:    config.AuditTypeConfig,
+			User:    user,
+			IP:      ip,
+			Action:  "gRPC SetConfig",
+			Success: true,
+			Changes: changes,
+		})
+	}
+
+	go dns.RunOnce()
+	return &SetConfigResponse{}, nil
+}
+
+// keepSecrets 密钥、密码、API密钥及代理的密码已隐藏时保留当前的, 更新前后的命令及自定义程序的路径只能在配置文件中修改
+func keepSecrets(old config.Config, conf *config.Config) error {
+	conf.Hooks = old.Hooks
+	conf.KeepRedacted(old)
+	return conf.KeepExecPath(old)
+}
+
+// peerInfo 客户端证书的CN及来源IP, 用于审计日志
+func peerInfo(ctx context.Context) (user string, ip string) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		ip = host
+	}
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) > 0 {
+		user = tlsInfo.State.PeerCertificates[0].Subject.CommonName
+	}
+	return
+}
+
+// domainStatus 转换域名状态, domain不为空时只返回该域名
+func domainStatus(domains []dns.DomainStatus, domain string) (result []*DomainStatus) {
+	for _, ds := range domains {
+		if domain != "" && ds.Domain != domain {
+			continue
+		}
+		result = append(result, &DomainStatus{
+			Domain:     ds.Domain,
+			RecordType: ds.RecordType,
+			Value:      ds.Value,
+			LastUpdate: unix(ds.LastUpdate),
+			LastCheck:  unix(ds.LastCheck),
+			LastResult: ds.LastResult,
+			FailCount:  int32(ds.FailCount),
+		})
+	}
+	return
+}
+
+// unix 时间的Unix秒, 零值为0
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
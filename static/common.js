@@ -0,0 +1,22 @@
+// 切换语言, 保存到cookie
+document.addEventListener("change", function (e) {
+  if (e.target.classList.contains("lang_select")) {
+    document.cookie = "lang=" + encodeURIComponent(e.target.value) + "; path=/; max-age=31536000"
+    window.location.reload()
+  }
+})
+
+// 获得页面中的CSRF Token
+function getCSRFToken() {
+  var meta = document.querySelector("meta[name=csrf-token]")
+  return meta ? meta.content : ""
+}
+
+// jQuery请求自动添加CSRF Token
+if (window.jQuery) {
+  jQuery.ajaxSetup({
+    beforeSend: function (xhr) {
+      xhr.setRequestHeader("X-CSRF-Token", getCSRFToken())
+    }
+  })
+}
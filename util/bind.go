@@ -0,0 +1,53 @@
+package util
+
+import (
+	"fmt"
+	"net"
+)
+
+// CheckBind 校验请求DNS服务商时绑定的网卡或源IP, 可为空、IP或网卡名称
+func CheckBind(bind string) error {
+	if bind == "" || net.ParseIP(bind) != nil {
+		return nil
+	}
+	if _, err := net.InterfaceByName(bind); err != nil {
+		return fmt.Errorf("绑定的网卡 %s 不存在", bind)
+	}
+	return nil
+}
+
+// bindAddr 连接ip时使用的源地址. bind为网卡时使用网卡上与ip版本相同的地址, IPv6优先使用全局单播地址
+func bindAddr(bind string, ip string) (*net.TCPAddr, error) {
+	isIPv4 := net.ParseIP(ip).To4() != nil
+	if local := net.ParseIP(bind); local != nil {
+		if (local.To4() != nil) != isIPv4 {
+			return nil, fmt.Errorf("源IP %s 与 %s 的IP版本不同", bind, ip)
+		}
+		return &net.TCPAddr{IP: local}, nil
+	}
+
+	iface, err := net.InterfaceByName(bind)
+	if err != nil {
+		return nil, fmt.Errorf("绑定的网卡 %s 不存在", bind)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	// https://en.wikipedia.org/wiki/IPv6_address#General_allocation
+	_, ipv6Unicast, _ := net.ParseCIDR("2000::/3")
+	var found net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || (ipNet.IP.To4() != nil) != isIPv4 || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+		if found == nil || (!ipv6Unicast.Contains(found) && ipv6Unicast.Contains(ipNet.IP)) {
+			found = ipNet.IP
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("网卡 %s 没有可用于连接 %s 的地址", bind, ip)
+	}
+	return &net.TCPAddr{IP: found}, nil
+}
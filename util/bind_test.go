@@ -0,0 +1,42 @@
+package util
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestBindAddr 源IP需与目标IP版本相同
+func TestBindAddr(t *testing.T) {
+	if addr, err := bindAddr("127.0.0.1", "1.1.1.1"); err != nil || addr.IP.String() != "127.0.0.1" {
+		t.Errorf("源IP应为127.0.0.1: %v %v", addr, err)
+	}
+	if _, err := bindAddr("127.0.0.1", "2606:4700::1111"); err == nil {
+		t.Error("IP版本不同时应失败")
+	}
+	if err := CheckBind("ddns-go-none0"); err == nil {
+		t.Error("不存在的网卡应校验失败")
+	}
+}
+
+// TestBindDial 请求DNS服务商时使用绑定的源IP
+func TestBindDial(t *testing.T) {
+	var remote string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}))
+	defer server.Close()
+
+	resp, err := (&http.Client{Transport: ProxyTransport(ProxyDirect, "", "127.0.0.1")}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if remote != "127.0.0.1" {
+		t.Errorf("源IP应为127.0.0.1: %s", remote)
+	}
+	if _, err := (&http.Client{Transport: ProxyTransport(ProxyDirect, "", "::1")}).Get(server.URL); err == nil {
+		t.Error("源IP与目标IP版本不同时应失败")
+	}
+}
@@ -0,0 +1,191 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 连接DNS服务商接口使用的IP版本, 为空时自动
+const (
+	IPVersionIPv4 = "ipv4"
+	IPVersionIPv6 = "ipv6"
+)
+
+// 解析DNS服务商接口域名使用的DNS服务器及固定的IP, 未设置时使用系统的DNS
+var bootstrap = struct {
+	sync.Mutex
+	resolver *Resolver
+	// 域名对应的IP, 域名为小写
+	hosts map[string][]string
+}{}
+
+// ParseHosts 解析固定IP, 每行与hosts文件相同, 为 IP 域名 [域名...]
+func ParseHosts(lines []string) (map[string][]string, error) {
+	hosts := map[string][]string{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		ip := net.ParseIP(fields[0])
+		if ip == nil || len(fields) < 2 {
+			return nil, fmt.Errorf("固定IP %s 不正确, 如: 104.16.132.229 api.cloudflare.com", line)
+		}
+		for _, host := range fields[1:] {
+			host = strings.ToLower(host)
+			hosts[host] = append(hosts[host], ip.String())
+		}
+	}
+	return hosts, nil
+}
+
+// CheckIPVersion 校验连接DNS服务商接口使用的IP版本, 可为空、ipv4或ipv6
+func CheckIPVersion(ipVersion string) error {
+	switch ipVersion {
+	case "", IPVersionIPv4, IPVersionIPv6:
+		return nil
+	}
+	return fmt.Errorf("IP版本 %s 不正确, 只支持 ipv4, ipv6, 为空时自动", ipVersion)
+}
+
+// SetBootstrap 设置解析DNS服务商接口域名使用的DNS服务器及固定的IP
+func SetBootstrap(resolver string, hostLines []string) error {
+	var r *Resolver
+	if resolver = strings.TrimSpace(resolver); resolver != "" {
+		var err error
+		if r, err = ParseResolver(resolver); err != nil {
+			return err
+		}
+	}
+	hosts, err := ParseHosts(hostLines)
+	if err != nil {
+		return err
+	}
+
+	bootstrap.Lock()
+	defer bootstrap.Unlock()
+	bootstrap.resolver = r
+	bootstrap.hosts = hosts
+	return nil
+}
+
+// lookupBootstrap 使用固定的IP或DNS服务器解析, 只返回network对应版本的IP, 都未设置时返回false
+func lookupBootstrap(ctx context.Context, host string, network string) (ips []string, ok bool, err error) {
+	bootstrap.Lock()
+	r := bootstrap.resolver
+	pinned := bootstrap.hosts[strings.ToLower(host)]
+	bootstrap.Unlock()
+	if len(pinned) > 0 {
+		for _, ip := range pinned {
+			if matchNetwork(network, ip) {
+				ips = append(ips, ip)
+			}
+		}
+		if len(ips) == 0 {
+			return nil, true, fmt.Errorf("%s 的固定IP中没有 %s 可用的IP", host, network)
+		}
+		return ips, true, nil
+	}
+	if r == nil {
+		return nil, false, nil
+	}
+
+	recordTypes := map[string][]string{"tcp4": {"A"}, "tcp6": {"AAAA"}}[network]
+	if recordTypes == nil {
+		recordTypes = []string{"A", "AAAA"}
+	}
+	for _, recordType := range recordTypes {
+		result, err := r.LookupIP(ctx, host, recordType)
+		if err != nil {
+			return nil, true, err
+		}
+		ips = append(ips, result...)
+	}
+	if len(ips) == 0 {
+		return nil, true, fmt.Errorf("%s 未解析到 %s", r, host)
+	}
+	return ips, true, nil
+}
+
+// matchNetwork ip是否可用于tcp4/tcp6
+func matchNetwork(network string, ip string) bool {
+	isIPv4 := net.ParseIP(ip).To4() != nil
+	switch network {
+	case "tcp4":
+		return isIPv4
+	case "tcp6":
+		return !isIPv4
+	}
+	return true
+}
+
+// bootstrapDial 连接DNS服务商的接口, 域名使用固定的IP或设置的DNS服务器解析, 依次尝试每个IP
+// ipVersion为ipv4/ipv6时只使用IPv4/IPv6连接, 避免IPv6不通时等待超时. bind为绑定的网卡或源IP
+func bootstrapDial(ipVersion string, bind string, timeout time.Duration) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		switch ipVersion {
+		case IPVersionIPv4:
+			network = "tcp4"
+		case IPVersionIPv6:
+			network = "tcp6"
+		}
+		return dialProvider(ctx, network, addr, bind, timeout)
+	}
+}
+
+func dialProvider(ctx context.Context, network string, addr string, bind string, timeout time.Duration) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return newDialer(timeout).DialContext(ctx, network, addr)
+	}
+	ips := []string{host}
+	if net.ParseIP(host) == nil {
+		var ok bool
+		ips, ok, err = lookupBootstrap(ctx, host, network)
+		if !ok && bind == "" {
+			return newDialer(timeout).DialContext(ctx, network, addr)
+		}
+		if !ok {
+			// 绑定时需按IP版本选择源IP
+			ips, err = lookupSystem(ctx, host, network)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ip := range ips {
+		dialer := newDialer(timeout)
+		if bind != "" {
+			if dialer.LocalAddr, err = bindAddr(bind, ip); err != nil {
+				continue
+			}
+		}
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// lookupSystem 使用系统的DNS解析, 只返回network对应版本的IP
+func lookupSystem(ctx context.Context, host string, network string) (ips []string, err error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if ip := addr.IP.String(); matchNetwork(network, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("%s 没有 %s 可用的IP", host, network)
+	}
+	return ips, nil
+}
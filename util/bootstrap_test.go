@@ -0,0 +1,71 @@
+package util
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestParseHosts 解析固定IP
+func TestParseHosts(t *testing.T) {
+	hosts, err := ParseHosts([]string{"104.16.132.229 api.cloudflare.com API.example.com", "", "2606:4700::6810:84e5 api.cloudflare.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts["api.cloudflare.com"]) != 2 || hosts["api.example.com"][0] != "104.16.132.229" {
+		t.Errorf("解析结果不正确: %v", hosts)
+	}
+	for _, line := range []string{"api.cloudflare.com 104.16.132.229", "104.16.132.229"} {
+		if _, err := ParseHosts([]string{line}); err == nil {
+			t.Errorf("%s 应解析失败", line)
+		}
+	}
+}
+
+// TestBootstrapHosts 请求DNS服务商时使用固定的IP, 获取IP等不使用
+func TestBootstrapHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	if err := SetBootstrap("", []string{"127.0.0.1 api.ddns-go.invalid"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetBootstrap("", nil)
+
+	url := "http://api.ddns-go.invalid:" + port + "/"
+	resp, err := (&http.Client{Transport: ProxyTransport(ProxyDirect, "", "")}).Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp, err = (&http.Client{Transport: Transport()}).Get(url); err == nil {
+		resp.Body.Close()
+		t.Error("获取IP等请求不应使用固定的IP")
+	}
+}
+
+// TestBootstrapIPVersion 固定IP只使用指定版本的IP
+func TestBootstrapIPVersion(t *testing.T) {
+	if err := SetBootstrap("", []string{"127.0.0.1 api.ddns-go.invalid", "::1 api.ddns-go.invalid"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetBootstrap("", nil)
+
+	tests := map[string]string{"tcp": "127.0.0.1,::1", "tcp4": "127.0.0.1", "tcp6": "::1"}
+	for network, expected := range tests {
+		ips, _, err := lookupBootstrap(context.Background(), "api.ddns-go.invalid", network)
+		if err != nil || strings.Join(ips, ",") != expected {
+			t.Errorf("%s 结果为 %v, 应为 %s", network, ips, expected)
+		}
+	}
+	if CheckIPVersion("ipv5") == nil {
+		t.Error("ipv5 应校验失败")
+	}
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseCron 测试cron表达式
+func TestParseCron(t *testing.T) {
+	now := time.Date(2021, 3, 15, 10, 7, 30, 0, time.UTC) // 周一
+	for expr, want := range map[string]time.Time{
+		"*/2 * * * *":    time.Date(2021, 3, 15, 10, 8, 0, 0, time.UTC),
+		"0 * * * *":      time.Date(2021, 3, 15, 11, 0, 0, 0, time.UTC),
+		"30 2 * * *":     time.Date(2021, 3, 16, 2, 30, 0, 0, time.UTC),
+		"0 0 1 * *":      time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+		"0 9 * * 6,7":    time.Date(2021, 3, 20, 9, 0, 0, 0, time.UTC),
+		"0 0 31 2 *":     {},
+		"@hourly":        time.Date(2021, 3, 15, 11, 0, 0, 0, time.UTC),
+		"@every 90s":     time.Date(2021, 3, 15, 10, 9, 0, 0, time.UTC),
+		"5-10/5 8 * * *": time.Date(2021, 3, 16, 8, 5, 0, 0, time.UTC),
+	} {
+		schedule, err := ParseCron(expr)
+		if err != nil {
+			t.Fatalf("%s 解析失败: %s", expr, err)
+		}
+		if next := schedule.Next(now); !next.Equal(want) {
+			t.Errorf("%s 下次运行时间 %s, 应为 %s", expr, next, want)
+		}
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "@every 1ms", "a * * * *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("%s 应返回错误", expr)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DebugPrefix 调试日志的前缀
+const DebugPrefix = "[DEBUG] "
+
+// 调试模式, 输出请求及返回的内容
+var debugMode = false
+
+// 调试日志中隐藏的请求头
+var debugRedactedHeaders = []string{"Authorization", "Cookie", "X-Auth-Key", "X-Auth-Token", "X-Api-Key"}
+
+// 日志及追踪中隐藏值的URL参数
+var redactedParams = []string{"token", "access_token", "key", "apikey", "api_key", "sendkey", "secret", "password", "passwd", "sign", "signature", "auth"}
+
+// SetDebug 设置调试模式
+func SetDebug(debug bool) {
+	debugMode = debug
+}
+
+// IsDebug 是否为调试模式
+func IsDebug() bool {
+	return debugMode
+}
+
+// transport 调试模式时在日志中输出请求及返回的内容, 开启追踪时记录每个请求, 隐藏密钥
+type transport struct {
+	base    http.RoundTripper
+	secrets []string
+}
+
+// Transport 请求DNS服务商等使用的Transport, 使用设置的代理. secrets为日志及追踪中需隐藏的密钥
+func Transport(secrets ...string) http.RoundTripper {
+	return wrapTransport(proxyTransport("", false, "", ""), secrets)
+}
+
+// ProxyTransport 请求DNS服务商使用的Transport, proxy为空时使用设置的代理, 为direct时不使用代理
+// 接口的域名使用设置的固定IP或DNS服务器解析, ipVersion为ipv4/ipv6时只使用IPv4/IPv6连接, bind为绑定的网卡或源IP
+func ProxyTransport(proxy string, ipVersion string, bind string, secrets ...string) http.RoundTripper {
+	return wrapTransport(proxyTransport(proxy, true, ipVersion, bind), secrets)
+}
+
+func wrapTransport(base http.RoundTripper, secrets []string) http.RoundTripper {
+	if !debugMode && otlp == nil {
+		return base
+	}
+	return &transport{base: base, secrets: secrets}
+}
+
+// RoundTrip 记录请求并输出请求及返回的内容
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// 只记录更新中的请求
+	span := startChildSpan(req.Context(), "HTTP "+req.Method)
+	defer span.End()
+	if span != nil {
+		span.kind = spanKindClient
+	}
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", t.redact(RedactURL(req.URL)))
+
+	resp, err := t.base.RoundTrip(t.logRequest(req))
+	if err != nil {
+		span.SetError(err.Error())
+		return resp, err
+	}
+	span.SetAttribute("http.status_code", strconv.Itoa(resp.StatusCode))
+	if resp.StatusCode >= 300 {
+		span.SetError(resp.Status)
+	}
+	return t.logResponse(req, resp)
+}
+
+// logRequest 调试模式时输出请求的内容
+func (t *transport) logRequest(req *http.Request) *http.Request {
+	if !debugMode {
+		return req
+	}
+	reqBody := ""
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			byt, _ := ioutil.ReadAll(body)
+			body.Close()
+			reqBody = string(byt)
+		}
+	}
+	headers := []string{}
+	for name := range req.Header {
+		value := req.Header.Get(name)
+		for _, redacted := range debugRedactedHeaders {
+			if strings.EqualFold(name, redacted) {
+				value = "******"
+			}
+		}
+		headers = append(headers, name+": "+value)
+	}
+	sort.Strings(headers)
+	log.Printf("%s请求接口 %s %s, 请求头: %s, 请求内容: %s\n", DebugPrefix,
+		req.Method, t.redact(RedactURL(req.URL)), t.redact(strings.Join(headers, "; ")), t.redact(reqBody))
+	return req
+}
+
+// logResponse 调试模式时输出返回的内容
+func (t *transport) logResponse(req *http.Request, resp *http.Response) (*http.Response, error) {
+	if !debugMode {
+		return resp, nil
+	}
+	byt, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(byt))
+	if err != nil {
+		return resp, err
+	}
+	log.Printf("%s接口 %s 返回状态码: %d, 返回内容: %s\n", DebugPrefix,
+		t.redact(RedactURL(req.URL)), resp.StatusCode, t.redact(strings.TrimSpace(string(byt))))
+	return resp, nil
+}
+
+// redact 隐藏内容中的密钥
+func (t *transport) redact(s string) string {
+	for _, secret := range t.secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "******")
+			s = strings.ReplaceAll(s, url.QueryEscape(secret), "******")
+		}
+	}
+	return s
+}
+
+// isRedactedParam 是否为需隐藏值的URL参数
+func isRedactedParam(name string) bool {
+	for _, param := range redactedParams {
+		if strings.EqualFold(name, param) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactURL 隐藏URL中的密码及密钥参数的值
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	if u.RawQuery != "" {
+		redacted := *u
+		pairs := strings.Split(u.RawQuery, "&")
+		for i, pair := range pairs {
+			name := pair
+			if idx := strings.Index(pair, "="); idx >= 0 {
+				name = pair[:idx]
+			}
+			if unescaped, err := url.QueryUnescape(name); err == nil && isRedactedParam(unescaped) {
+				pairs[i] = name + "=******"
+			}
+		}
+		redacted.RawQuery = strings.Join(pairs, "&")
+		u = &redacted
+	}
+	return u.Redacted()
+}
+
+// RedactURLString 同RedactURL, URL不正确时原样返回
+func RedactURLString(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return RedactURL(u)
+}
+
+// URLSecrets 返回URL中的密码及密钥参数的值, 用于在日志及追踪中隐藏
+func URLSecrets(rawURL string) (secrets []string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	if password, ok := u.User.Password(); ok {
+		secrets = append(secrets, password)
+	}
+	for name, values := range u.Query() {
+		if isRedactedParam(name) {
+			secrets = append(secrets, values...)
+		}
+	}
+	return
+}
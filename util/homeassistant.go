@@ -0,0 +1,71 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// HomeAssistantIngressIP Supervisor的ingress代理访问加载项时的来源IP
+const HomeAssistantIngressIP = "172.30.32.2"
+
+// Home Assistant加载项的选项文件
+var homeAssistantOptionsFile = "/data/options.json"
+
+// 通过Supervisor访问Home Assistant的接口
+var homeAssistantAPI = "http://supervisor/core/api"
+
+// HomeAssistantOptions 加载项的选项, 在Home Assistant的加载项配置页面中修改
+type HomeAssistantOptions struct {
+	// 同步间隔时间(秒)
+	Interval int    `json:"interval"`
+	LogLevel string `json:"log_level"`
+}
+
+// IsHomeAssistant 是否作为Home Assistant的加载项运行
+func IsHomeAssistant() bool {
+	if os.Getenv("SUPERVISOR_TOKEN") == "" {
+		return false
+	}
+	_, err := os.Stat(homeAssistantOptionsFile)
+	return err == nil
+}
+
+// ReadHomeAssistantOptions 读取加载项的选项
+func ReadHomeAssistantOptions() (opts HomeAssistantOptions, err error) {
+	byt, err := ioutil.ReadFile(homeAssistantOptionsFile)
+	if err != nil {
+		return opts, err
+	}
+	if err = json.Unmarshal(byt, &opts); err != nil {
+		return opts, fmt.Errorf("解析Home Assistant加载项的选项失败: %s", err)
+	}
+	return opts, nil
+}
+
+// SetHomeAssistantState 设置Home Assistant中实体的状态, 如 sensor.ddns_go_ipv4
+func SetHomeAssistantState(ctx context.Context, entityID string, state string, attributes map[string]interface{}) error {
+	byt, _ := json.Marshal(map[string]interface{}{"state": state, "attributes": attributes})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, homeAssistantAPI+"/states/"+entityID, bytes.NewReader(byt))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("SUPERVISOR_TOKEN"))
+	req.Header.Set("Content-Type", "application/json")
+
+	client := HTTPClient(os.Getenv("SUPERVISOR_TOKEN"))
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("设置Home Assistant实体 %s 的状态失败: %s %s", entityID, resp.Status, body)
+	}
+	return nil
+}
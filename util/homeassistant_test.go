@@ -0,0 +1,60 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestHomeAssistantOptions 有SUPERVISOR_TOKEN及选项文件时为加载项模式
+func TestHomeAssistantOptions(t *testing.T) {
+	old := homeAssistantOptionsFile
+	defer func() { homeAssistantOptionsFile = old }()
+	homeAssistantOptionsFile = filepath.Join(t.TempDir(), "options.json")
+	ioutil.WriteFile(homeAssistantOptionsFile, []byte(`{"interval": 600, "log_level": "debug"}`), 0600)
+
+	if IsHomeAssistant() {
+		t.Error("没有SUPERVISOR_TOKEN时不应为加载项模式")
+	}
+	os.Setenv("SUPERVISOR_TOKEN", "token")
+	defer os.Unsetenv("SUPERVISOR_TOKEN")
+	if !IsHomeAssistant() {
+		t.Error("应为加载项模式")
+	}
+	opts, err := ReadHomeAssistantOptions()
+	if err != nil || opts.Interval != 600 || opts.LogLevel != "debug" {
+		t.Errorf("读取加载项的选项不正确: %v %v", opts, err)
+	}
+}
+
+// TestSetHomeAssistantState 使用SUPERVISOR_TOKEN设置实体的状态
+func TestSetHomeAssistantState(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/states/sensor.ddns_go_ipv4" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	old := homeAssistantAPI
+	defer func() { homeAssistantAPI = old }()
+	homeAssistantAPI = server.URL
+
+	if err := SetHomeAssistantState(context.Background(), "sensor.ddns_go_ipv4", "1.2.3.4", nil); err == nil {
+		t.Error("token不正确时应失败")
+	}
+	os.Setenv("SUPERVISOR_TOKEN", "token")
+	defer os.Unsetenv("SUPERVISOR_TOKEN")
+	err := SetHomeAssistantState(context.Background(), "sensor.ddns_go_ipv4", "1.2.3.4", map[string]interface{}{"friendly_name": "IPv4"})
+	if err != nil || body["state"] != "1.2.3.4" {
+		t.Errorf("设置状态失败: %v %v", body, err)
+	}
+}
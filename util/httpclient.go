@@ -0,0 +1,87 @@
+package util
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPOptions 请求DNS服务商、获取IP及通知使用的超时及连接池
+type HTTPOptions struct {
+	// 建立连接的超时, 包括TLS握手
+	ConnectTimeout time.Duration
+	// 整个请求的超时, 包括读取返回的内容
+	Timeout time.Duration
+	// 每个域名保持的空闲连接数, 之后的请求复用连接, 不需要再次TLS握手
+	MaxIdleConnsPerHost int
+	// 空闲连接保持的时间
+	IdleConnTimeout time.Duration
+}
+
+// DefaultHTTPOptions 未设置时使用的超时及连接池
+var DefaultHTTPOptions = HTTPOptions{
+	ConnectTimeout:      10 * time.Second,
+	Timeout:             30 * time.Second,
+	MaxIdleConnsPerHost: 4,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// 当前使用的超时及连接池, 修改时需锁定proxyTransports
+var httpOptions = DefaultHTTPOptions
+
+// SetHTTPOptions 设置超时及连接池, 为0的使用默认值. 修改后关闭之前的空闲连接
+func SetHTTPOptions(opts HTTPOptions) {
+	if opts.ConnectTimeout <= 0 {
+		opts.ConnectTimeout = DefaultHTTPOptions.ConnectTimeout
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultHTTPOptions.Timeout
+	}
+	if opts.MaxIdleConnsPerHost <= 0 {
+		opts.MaxIdleConnsPerHost = DefaultHTTPOptions.MaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout <= 0 {
+		opts.IdleConnTimeout = DefaultHTTPOptions.IdleConnTimeout
+	}
+
+	proxyTransports.Lock()
+	defer proxyTransports.Unlock()
+	if opts == httpOptions {
+		return
+	}
+	httpOptions = opts
+	for key, t := range proxyTransports.m {
+		t.CloseIdleConnections()
+		delete(proxyTransports.m, key)
+	}
+}
+
+// HTTPClient 获取IP、通知等使用的http.Client, 共享连接池. secrets为日志及追踪中需隐藏的密钥
+func HTTPClient(secrets ...string) *http.Client {
+	return &http.Client{Timeout: requestTimeout(), Transport: Transport(secrets...)}
+}
+
+// ProviderHTTPClient 请求DNS服务商使用的http.Client, 参数同ProxyTransport
+func ProviderHTTPClient(proxy string, ipVersion string, bind string, secrets ...string) *http.Client {
+	return &http.Client{Timeout: requestTimeout(), Transport: ProxyTransport(proxy, ipVersion, bind, secrets...)}
+}
+
+func requestTimeout() time.Duration {
+	proxyTransports.Lock()
+	defer proxyTransports.Unlock()
+	return httpOptions.Timeout
+}
+
+// newTransport 按设置的超时及连接池创建Transport, 需锁定proxyTransports
+func newTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = newDialer(httpOptions.ConnectTimeout).DialContext
+	t.TLSHandshakeTimeout = httpOptions.ConnectTimeout
+	t.MaxIdleConnsPerHost = httpOptions.MaxIdleConnsPerHost
+	t.IdleConnTimeout = httpOptions.IdleConnTimeout
+	return t
+}
+
+func newDialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+}
@@ -0,0 +1,35 @@
+package util
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestSetHTTPOptions 未设置的使用默认值, 修改后重新创建Transport
+func TestSetHTTPOptions(t *testing.T) {
+	defer SetHTTPOptions(DefaultHTTPOptions)
+
+	SetHTTPOptions(HTTPOptions{Timeout: 5 * time.Second})
+	client := HTTPClient()
+	if client.Timeout != 5*time.Second {
+		t.Errorf("请求超时不正确: %s", client.Timeout)
+	}
+	before := client.Transport.(*http.Transport)
+	if before.TLSHandshakeTimeout != DefaultHTTPOptions.ConnectTimeout || before.MaxIdleConnsPerHost != DefaultHTTPOptions.MaxIdleConnsPerHost {
+		t.Errorf("未设置的应使用默认值: %s %d", before.TLSHandshakeTimeout, before.MaxIdleConnsPerHost)
+	}
+	if HTTPClient().Transport != before || ProviderHTTPClient("", "", "").Transport == before {
+		t.Error("相同的配置应复用Transport, DNS服务商使用单独的Transport")
+	}
+
+	SetHTTPOptions(HTTPOptions{Timeout: 5 * time.Second})
+	if HTTPClient().Transport != before {
+		t.Error("配置未变化时不应重新创建Transport")
+	}
+	SetHTTPOptions(HTTPOptions{MaxIdleConnsPerHost: 8})
+	after := HTTPClient().Transport.(*http.Transport)
+	if after == before || after.MaxIdleConnsPerHost != 8 || HTTPClient().Timeout != DefaultHTTPOptions.Timeout {
+		t.Error("修改后应使用新的配置")
+	}
+}
@@ -0,0 +1,127 @@
+package util
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed i18n/*.json
+var i18nEmbedFiles embed.FS
+
+// DefaultLanguage 默认语言. 语言包以简体中文原文为key, 简体中文无需语言包
+const DefaultLanguage = "zh"
+
+// Language 语言
+type Language struct {
+	Code string
+	Name string
+}
+
+// Languages 支持的语言
+var Languages = []Language{
+	{Code: "zh", Name: "简体中文"},
+	{Code: "zh-TW", Name: "繁體中文"},
+	{Code: "en", Name: "English"},
+	{Code: "ja", Name: "日本語"},
+	{Code: "de", Name: "Deutsch"},
+}
+
+var catalogs map[string]map[string]string
+var catalogsOnce sync.Once
+
+// loadCatalogs 加载语言包
+func loadCatalogs() {
+	catalogs = make(map[string]map[string]string)
+	for _, lang := range Languages {
+		if lang.Code == DefaultLanguage {
+			continue
+		}
+		byt, err := i18nEmbedFiles.ReadFile("i18n/" + lang.Code + ".json")
+		if err != nil {
+			log.Println("读取语言包失败", lang.Code, err)
+			continue
+		}
+		catalog := make(map[string]string)
+		if err = json.Unmarshal(byt, &catalog); err != nil {
+			log.Println("解析语言包失败", lang.Code, err)
+			continue
+		}
+		catalogs[lang.Code] = catalog
+	}
+}
+
+// IsSupportedLanguage 是否为支持的语言
+func IsSupportedLanguage(lang string) bool {
+	for _, l := range Languages {
+		if l.Code == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Translate 翻译, 未找到翻译时返回原文. 有args时按fmt.Sprintf格式化
+func Translate(lang string, key string, args ...interface{}) string {
+	catalogsOnce.Do(loadCatalogs)
+
+	msg := key
+	if translated, ok := catalogs[lang][key]; ok && translated != "" {
+		msg = translated
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// MatchLanguage 根据Accept-Language匹配支持的语言, 未匹配返回默认语言
+func MatchLanguage(acceptLanguage string) string {
+	type weightedTag struct {
+		tag    string
+		weight float64
+	}
+
+	var tags []weightedTag
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		sp := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(sp[0]))
+		if tag == "" {
+			continue
+		}
+		weight := 1.0
+		for _, param := range sp[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					weight = q
+				}
+			}
+		}
+		tags = append(tags, weightedTag{tag: tag, weight: weight})
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].weight > tags[j].weight
+	})
+
+	for _, t := range tags {
+		switch {
+		case t.tag == "zh-tw" || t.tag == "zh-hk" || t.tag == "zh-mo" || strings.HasPrefix(t.tag, "zh-hant"):
+			return "zh-TW"
+		case t.tag == "zh" || strings.HasPrefix(t.tag, "zh-"):
+			return "zh"
+		}
+		for _, lang := range Languages {
+			code := strings.ToLower(lang.Code)
+			if t.tag == code || strings.HasPrefix(t.tag, code+"-") {
+				return lang.Code
+			}
+		}
+	}
+	return DefaultLanguage
+}
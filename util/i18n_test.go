@@ -0,0 +1,49 @@
+package util
+
+import (
+	"testing"
+)
+
+// TestMatchLanguage 测试匹配Accept-Language
+func TestMatchLanguage(t *testing.T) {
+	data := map[string]string{
+		"":                        DefaultLanguage,
+		"zh-CN,zh;q=0.9":          "zh",
+		"zh-TW,zh;q=0.9,en;q=0.8": "zh-TW",
+		"zh-Hant-HK":              "zh-TW",
+		"en-US,en;q=0.9":          "en",
+		"fr-FR,de;q=0.5,ja;q=0.8": "ja",
+		"de-AT":                   "de",
+		"fr-FR":                   DefaultLanguage,
+	}
+
+	for key, value := range data {
+		if result := MatchLanguage(key); result != value {
+			t.Errorf("%s 匹配为 %s, 应为 %s\n", key, result, value)
+		}
+	}
+}
+
+// TestTranslate 测试翻译
+func TestTranslate(t *testing.T) {
+	for _, lang := range Languages {
+		if lang.Code == DefaultLanguage {
+			continue
+		}
+		if _, ok := catalogsWithLoad()[lang.Code]; !ok {
+			t.Errorf("语言包 %s 加载失败", lang.Code)
+		}
+	}
+
+	if Translate("en", "%d秒", 5) != "5 s" {
+		t.Error("翻译en失败")
+	}
+	if Translate("zh", "%d秒", 5) != "5秒" {
+		t.Error("未翻译时应返回原文")
+	}
+}
+
+func catalogsWithLoad() map[string]map[string]string {
+	catalogsOnce.Do(loadCatalogs)
+	return catalogs
+}
@@ -0,0 +1,70 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// UnixSocketPrefix unix socket监听地址前缀
+const UnixSocketPrefix = "unix://"
+
+// unixSocketPerm unix socket文件权限, 同组用户(如反向代理)可访问
+const unixSocketPerm os.FileMode = 0660
+
+// SplitListenAddrs 拆分用逗号分隔的监听地址
+func SplitListenAddrs(listen string) (addrs []string) {
+	for _, addr := range strings.Split(listen, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return
+}
+
+// IsUnixSocketAddr 是否为unix socket监听地址
+func IsUnixSocketAddr(addr string) bool {
+	return strings.HasPrefix(addr, UnixSocketPrefix)
+}
+
+// CheckListenAddr 校验监听地址
+func CheckListenAddr(addr string) error {
+	if IsUnixSocketAddr(addr) {
+		if strings.TrimPrefix(addr, UnixSocketPrefix) == "" {
+			return fmt.Errorf("unix socket路径为空: %s", addr)
+		}
+		return nil
+	}
+	_, err := net.ResolveTCPAddr("tcp", addr)
+	return err
+}
+
+// Listen 监听TCP地址或unix socket
+func Listen(addr string) (net.Listener, error) {
+	if !IsUnixSocketAddr(addr) {
+		return net.Listen("tcp", addr)
+	}
+
+	path := strings.TrimPrefix(addr, UnixSocketPrefix)
+	// 删除上次未正常退出遗留的socket文件, 不删除普通文件
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s 已存在且不是socket文件", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, unixSocketPerm); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"testing"
+)
+
+// TestSplitListenAddrs 测试拆分监听地址
+func TestSplitListenAddrs(t *testing.T) {
+	addrs := SplitListenAddrs(" 127.0.0.1:9876, ,unix:///tmp/ddns-go.sock,")
+	if len(addrs) != 2 || addrs[0] != "127.0.0.1:9876" || addrs[1] != "unix:///tmp/ddns-go.sock" {
+		t.Errorf("拆分监听地址失败: %v", addrs)
+	}
+}
+
+// TestCheckListenAddr 测试校验监听地址
+func TestCheckListenAddr(t *testing.T) {
+	data := map[string]bool{
+		":9876":                    true,
+		"127.0.0.1:9876":           true,
+		"unix:///tmp/ddns-go.sock": true,
+		"unix://":                  false,
+		"127.0.0.1:abc":            false,
+	}
+
+	for key, value := range data {
+		if (CheckListenAddr(key) == nil) != value {
+			t.Errorf("%s 校验失败\n", key)
+		}
+	}
+}
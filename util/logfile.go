@@ -0,0 +1,162 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 轮转后的文件名中的时间格式
+const rotateTimeFormat = "20060102-150405.000"
+
+// RotateFile 日志文件, 超过MaxSize时轮转, 并按MaxAge及MaxBackups清理旧文件
+type RotateFile struct {
+	Path string
+	// MaxSize 单个文件的最大字节数, 0为不轮转
+	MaxSize int64
+	// MaxAge 旧文件保留的时长, 0为不限
+	MaxAge time.Duration
+	// MaxBackups 旧文件保留的数量, 0为不限
+	MaxBackups int
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenRotateFile 打开日志文件, 不存在时创建
+func OpenRotateFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotateFile, error) {
+	r := &RotateFile{Path: filepath.Clean(path), MaxSize: maxSize, MaxAge: maxAge, MaxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	r.cleanup()
+	return r, nil
+}
+
+// Write 写入日志, 超过MaxSize时先轮转
+func (r *RotateFile) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.MaxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Reopen 重新打开日志文件, 用于logrotate等外部工具移走文件后
+func (r *RotateFile) Reopen() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.close()
+	return r.open()
+}
+
+// Close 关闭日志文件
+func (r *RotateFile) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.close()
+}
+
+func (r *RotateFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(r.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *RotateFile) close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// rotate 将当前文件重命名为 name-时间.ext 后重新打开
+func (r *RotateFile) rotate() error {
+	r.close()
+	prefix, ext := r.backupName()
+	backup := prefix + time.Now().Format(rotateTimeFormat) + ext
+	if err := os.Rename(r.Path, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	r.cleanup()
+	return nil
+}
+
+// backupName 旧文件名的前缀及扩展名
+func (r *RotateFile) backupName() (prefix string, ext string) {
+	ext = filepath.Ext(r.Path)
+	return strings.TrimSuffix(r.Path, ext) + "-", ext
+}
+
+// cleanup 删除超过保留时长或数量的旧文件
+func (r *RotateFile) cleanup() {
+	if r.MaxAge <= 0 && r.MaxBackups <= 0 {
+		return
+	}
+	prefix, ext := r.backupName()
+	files, err := ioutil.ReadDir(filepath.Dir(r.Path))
+	if err != nil {
+		return
+	}
+
+	backups := []string{}
+	for _, file := range files {
+		name := filepath.Join(filepath.Dir(r.Path), file.Name())
+		if file.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		t := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if _, err := time.Parse(rotateTimeFormat, t); err == nil {
+			backups = append(backups, name)
+		}
+	}
+	// 时间格式可按文件名排序, 新的在前
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	for i, backup := range backups {
+		if r.MaxBackups > 0 && i >= r.MaxBackups {
+			os.Remove(backup)
+			continue
+		}
+		if r.MaxAge > 0 {
+			if info, err := os.Stat(backup); err == nil && time.Since(info.ModTime()) > r.MaxAge {
+				os.Remove(backup)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRotateFile 测试日志文件的轮转及清理
+func TestRotateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddns-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ddns-go.log")
+	r, err := OpenRotateFile(path, 10, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, err = r.Write([]byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 3 {
+		t.Errorf("应保留当前文件及2个旧文件: %d", len(files))
+	}
+	if byt, _ := ioutil.ReadFile(path); string(byt) != "0123456789" {
+		t.Errorf("当前文件内容不正确: %s", byt)
+	}
+
+	// 外部移走文件后重新打开
+	os.Rename(path, path+".1")
+	if err = r.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	r.Write([]byte("a"))
+	if byt, _ := ioutil.ReadFile(path); string(byt) != "a" {
+		t.Errorf("重新打开后内容不正确: %s", byt)
+	}
+}
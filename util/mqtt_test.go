@@ -0,0 +1,67 @@
+package util
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestSubscribeMQTT 登录、订阅并收到QoS 1的消息后确认, 保留的消息标记为Retained
+func TestSubscribeMQTT(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	puback := make(chan []byte, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		c := &mqttConn{conn: conn, reader: bufio.NewReader(conn)}
+		if packetType, _, body, err := c.read(time.Second); err != nil || packetType != mqttConnect || body[7]&0xc0 != 0xc0 {
+			t.Errorf("CONNECT不正确: %v %v", body, err)
+			return
+		}
+		c.write(mqttConnack<<4, []byte{0, 0})
+		if packetType, _, _, err := c.read(time.Second); err != nil || packetType != mqttSubscribe {
+			t.Errorf("SUBSCRIBE不正确: %v", err)
+			return
+		}
+		c.write(mqttSuback<<4, []byte{0, 1, 1})
+		c.write(mqttPublish<<4|0x01, append(mqttString("ddns-go/command"), "update"...))
+		c.write(mqttPublish<<4|0x02, append(append(mqttString("ddns-go/command"), 0, 7), "pause"...))
+		if packetType, _, body, err := c.read(time.Second); err == nil && packetType == mqttPuback {
+			puback <- body
+		}
+		c.read(time.Second)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var msgs []MQTTMessage
+	SubscribeMQTT(ctx, "mqtt://user:pass@"+l.Addr().String(), "ddns-go/command", func(msg MQTTMessage) {
+		msgs = append(msgs, msg)
+		if len(msgs) == 2 {
+			cancel()
+		}
+	})
+
+	if len(msgs) != 2 || !msgs[0].Retained || string(msgs[0].Payload) != "update" ||
+		msgs[1].Retained || string(msgs[1].Payload) != "pause" || msgs[1].Topic != "ddns-go/command" {
+		t.Fatalf("收到的消息不正确: %v", msgs)
+	}
+	select {
+	case body := <-puback:
+		if len(body) != 2 || body[1] != 7 {
+			t.Errorf("PUBACK的报文ID不正确: %v", body)
+		}
+	case <-time.After(time.Second):
+		t.Error("QoS 1的消息未确认")
+	}
+}
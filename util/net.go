@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"net"
 	"strings"
 )
@@ -59,3 +60,60 @@ func IsPrivateNetwork(remoteAddr string) bool {
 
 	return false
 }
+
+// 运营商级NAT的共享地址, https://datatracker.ietf.org/doc/html/rfc6598
+var _, cgnatNet, _ = net.ParseCIDR("100.64.0.0/10")
+
+// IsCGNAT 是否为运营商级NAT(CGNAT)的地址, 外网无法访问
+func IsCGNAT(ipAddr string) bool {
+	ip := net.ParseIP(ipAddr)
+	return ip != nil && cgnatNet.Contains(ip)
+}
+
+// ParseIPRanges 解析IP段, 一行一个, 如 1.2.3.0/24, 2409:8a00::/24, 只填写IP时为单个IP
+func ParseIPRanges(lines []string) (ranges []*net.IPNet, err error) {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.Contains(line, "/") {
+			ip := net.ParseIP(line)
+			if ip == nil {
+				return nil, fmt.Errorf("IP段 %s 不正确, 如: 1.2.3.0/24", line)
+			}
+			if ip.To4() != nil {
+				line += "/32"
+			} else {
+				line += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(line)
+		if err != nil {
+			return nil, fmt.Errorf("IP段 %s 不正确, 如: 1.2.3.0/24", line)
+		}
+		ranges = append(ranges, ipNet)
+	}
+	return ranges, nil
+}
+
+// ReverseAddr IP对应的反向解析(PTR)域名, 如 1.2.3.4 为 4.3.2.1.in-addr.arpa
+func ReverseAddr(ipAddr string) (string, error) {
+	ip := net.ParseIP(ipAddr)
+	if ip == nil {
+		return "", fmt.Errorf("IP %s 不正确", ipAddr)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa", ip4[3], ip4[2], ip4[1], ip4[0]), nil
+	}
+	const hexDigit = "0123456789abcdef"
+	var sb strings.Builder
+	for i := len(ip) - 1; i >= 0; i-- {
+		sb.WriteByte(hexDigit[ip[i]&0xf])
+		sb.WriteByte('.')
+		sb.WriteByte(hexDigit[ip[i]>>4])
+		sb.WriteByte('.')
+	}
+	sb.WriteString("ip6.arpa")
+	return sb.String(), nil
+}
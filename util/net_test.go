@@ -25,3 +25,54 @@ func TestIsPrivateNetwork(t *testing.T) {
 
 	}
 }
+
+// TestIsCGNAT 测试是否为运营商级NAT的地址
+func TestIsCGNAT(t *testing.T) {
+	data := map[string]bool{
+		"100.64.0.1":      true,
+		"100.127.255.254": true,
+		"100.63.255.255":  false,
+		"100.128.0.1":     false,
+		"223.5.5.5":       false,
+		"2409::1":         false,
+		"":                false,
+	}
+
+	for key, value := range data {
+		if IsCGNAT(key) != value {
+			t.Errorf("%s 校验失败\n", key)
+		}
+	}
+}
+
+// TestReverseAddr 测试IP对应的反向解析域名
+func TestReverseAddr(t *testing.T) {
+	data := map[string]string{
+		"1.2.3.4":      "4.3.2.1.in-addr.arpa",
+		"2409:8a00::1": "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.a.8.9.0.4.2.ip6.arpa",
+	}
+	for ip, want := range data {
+		if got, err := ReverseAddr(ip); err != nil || got != want {
+			t.Errorf("%s 的反向解析域名为 %s, 应为 %s: %v", ip, got, want, err)
+		}
+	}
+	if _, err := ReverseAddr("abc"); err == nil {
+		t.Error("不正确的IP应返回错误")
+	}
+}
+
+// TestParseIPRanges 测试解析IP段
+func TestParseIPRanges(t *testing.T) {
+	ranges, err := ParseIPRanges([]string{"1.2.3.0/24", " 5.6.7.8 ", "", "2409:8a00::/24"})
+	if err != nil || len(ranges) != 3 {
+		t.Fatalf("解析IP段失败: %v %v", ranges, err)
+	}
+	if ranges[1].String() != "5.6.7.8/32" {
+		t.Errorf("单个IP应为 /32, 实际为 %s", ranges[1])
+	}
+	for _, line := range []string{"1.2.3.0/33", "example.com"} {
+		if _, err := ParseIPRanges([]string{line}); err == nil {
+			t.Errorf("%s 应校验失败", line)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package util
+
+import (
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// 收到网络变化后等待地址稳定的时间, 期间的多个事件合并为一次
+	netSettleDelay = 3 * time.Second
+	// 不支持网络变化通知时检查网卡地址的间隔
+	netPollInterval = 10 * time.Second
+)
+
+// networkEvents 监听系统的网络变化通知, 网卡状态或地址变化时调用notify. 不支持的系统为nil
+var networkEvents func(notify func()) error
+
+// WatchNetworkChange 网络重连(网卡启用、DHCP续租、PPPoE重拨等)导致网卡地址变化时调用onChange
+func WatchNetworkChange(onChange func()) {
+	events := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case events <- struct{}{}:
+		default:
+		}
+	}
+
+	go func() {
+		if networkEvents != nil {
+			err := networkEvents(notify)
+			log.Println("监听网络变化失败, 改为定时检查网卡地址", err)
+		}
+		for {
+			time.Sleep(netPollInterval)
+			notify()
+		}
+	}()
+
+	last := interfaceAddrs()
+	for range events {
+		time.Sleep(netSettleDelay)
+		select {
+		case <-events:
+		default:
+		}
+
+		current := interfaceAddrs()
+		if current == last {
+			continue
+		}
+		last = current
+		log.Println("检测到网络变化, 立即更新")
+		onChange()
+	}
+}
+
+// interfaceAddrs 获得所有网卡的地址, 排序后用逗号连接
+func interfaceAddrs() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	result := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		result = append(result, addr.String())
+	}
+	sort.Strings(result)
+	return strings.Join(result, ",")
+}
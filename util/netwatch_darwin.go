@@ -0,0 +1,35 @@
+package util
+
+import "syscall"
+
+func init() {
+	networkEvents = routeSocketEvents
+}
+
+// routeSocketEvents 通过路由套接字监听网卡状态及地址的变化
+func routeSocketEvents(notify func()) error {
+	fd, err := syscall.Socket(syscall.AF_ROUTE, syscall.SOCK_RAW, syscall.AF_UNSPEC)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	buf := make([]byte, 2048)
+	for {
+		n, err := syscall.Read(fd, buf)
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			return err
+		}
+		// rt_msghdr: 长度(2字节), 版本(1字节), 类型(1字节)
+		if n < 4 {
+			continue
+		}
+		switch buf[3] {
+		case syscall.RTM_IFINFO, syscall.RTM_NEWADDR, syscall.RTM_DELADDR:
+			notify()
+		}
+	}
+}
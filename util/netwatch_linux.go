@@ -0,0 +1,52 @@
+package util
+
+import "syscall"
+
+// rtnetlink的多播组, syscall中未定义
+const (
+	rtmgrpLink       = 0x1
+	rtmgrpIpv4Ifaddr = 0x10
+	rtmgrpIpv6Ifaddr = 0x100
+)
+
+func init() {
+	networkEvents = netlinkEvents
+}
+
+// netlinkEvents 通过netlink监听网卡状态及地址的变化
+func netlinkEvents(notify func()) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	sa := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: rtmgrpLink | rtmgrpIpv4Ifaddr | rtmgrpIpv6Ifaddr,
+	}
+	if err := syscall.Bind(fd, sa); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 8192)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, msg := range msgs {
+			switch msg.Header.Type {
+			case syscall.RTM_NEWLINK, syscall.RTM_DELLINK, syscall.RTM_NEWADDR, syscall.RTM_DELADDR:
+				notify()
+			}
+		}
+	}
+}
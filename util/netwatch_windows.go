@@ -0,0 +1,24 @@
+package util
+
+import "syscall"
+
+var procNotifyAddrChange = syscall.NewLazyDLL("iphlpapi.dll").NewProc("NotifyAddrChange")
+
+func init() {
+	networkEvents = notifyAddrChangeEvents
+}
+
+// notifyAddrChangeEvents 通过NotifyAddrChange监听网卡地址的变化
+func notifyAddrChangeEvents(notify func()) error {
+	if err := procNotifyAddrChange.Find(); err != nil {
+		return err
+	}
+	for {
+		// 两个参数都为NULL时阻塞到地址变化
+		r, _, _ := procNotifyAddrChange.Call(0, 0)
+		if r != 0 {
+			return syscall.Errno(r)
+		}
+		notify()
+	}
+}
@@ -0,0 +1,165 @@
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProfileDefault 默认配置方案, 即 -c 指定的配置文件
+const ProfileDefault = "default"
+
+// profileFilePrefix 其它配置方案的配置文件前缀, 如 ~/.ddns_go_config.profile-vps.yaml
+const profileFilePrefix = ".profile-"
+
+var profileNameReg = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// IsValidProfileName 配置方案名称只能包含字母、数字、_、-
+func IsValidProfileName(name string) bool {
+	return profileNameReg.MatchString(name)
+}
+
+// GetProfileConfigFilePath 获得配置方案的配置文件路径
+func GetProfileConfigFilePath(name string) string {
+	base := getBaseConfigFilePath()
+	if name == "" || name == ProfileDefault {
+		return base
+	}
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + profileFilePrefix + name + ext
+}
+
+// getActiveProfileFilePath 保存当前配置方案名称的文件路径
+func getActiveProfileFilePath() string {
+	base := getBaseConfigFilePath()
+	return strings.TrimSuffix(base, filepath.Ext(base)) + ".profile"
+}
+
+// activeProfile 当前的配置方案, 第一次使用时读取, 之后在切换及RefreshProfile时更新
+var activeProfile struct {
+	sync.Mutex
+	base string
+	name string
+}
+
+// GetProfile 获得当前的配置方案, 配置方案的配置文件不存在时为默认配置方案
+// 远程配置不支持配置方案
+func GetProfile() string {
+	base := getBaseConfigFilePath()
+	activeProfile.Lock()
+	defer activeProfile.Unlock()
+	if activeProfile.base != base {
+		activeProfile.base = base
+		activeProfile.name = readProfile()
+	}
+	return activeProfile.name
+}
+
+// RefreshProfile 重新读取当前的配置方案, 用于发现在其它进程中的切换, 如 ddns-go profile use
+func RefreshProfile() {
+	setActiveProfile(readProfile())
+}
+
+// setActiveProfile 更新当前的配置方案
+func setActiveProfile(name string) {
+	activeProfile.Lock()
+	defer activeProfile.Unlock()
+	activeProfile.base = getBaseConfigFilePath()
+	activeProfile.name = name
+}
+
+// readProfile 从文件中读取当前的配置方案
+func readProfile() string {
+	if IsRemoteConfigPath(getBaseConfigFilePath()) {
+		return ProfileDefault
+	}
+	byt, err := ioutil.ReadFile(getActiveProfileFilePath())
+	if err != nil {
+		return ProfileDefault
+	}
+	name := strings.TrimSpace(string(byt))
+	if !IsValidProfileName(name) {
+		return ProfileDefault
+	}
+	if _, err := os.Stat(GetProfileConfigFilePath(name)); err != nil {
+		return ProfileDefault
+	}
+	return name
+}
+
+// SetProfile 切换配置方案
+func SetProfile(name string) error {
+	if name == ProfileDefault {
+		err := os.Remove(getActiveProfileFilePath())
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		setActiveProfile(ProfileDefault)
+		return nil
+	}
+	if !IsValidProfileName(name) {
+		return fmt.Errorf("配置方案名称 %s 不正确, 只能包含字母、数字、_、-", name)
+	}
+	if _, err := os.Stat(GetProfileConfigFilePath(name)); err != nil {
+		return fmt.Errorf("配置方案 %s 不存在", name)
+	}
+	if err := ioutil.WriteFile(getActiveProfileFilePath(), []byte(name), 0600); err != nil {
+		return err
+	}
+	setActiveProfile(name)
+	return nil
+}
+
+// GetProfiles 获得所有的配置方案, 默认配置方案在前
+func GetProfiles() (names []string) {
+	base := getBaseConfigFilePath()
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + profileFilePrefix
+	paths, _ := filepath.Glob(prefix + "*" + ext)
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimPrefix(path, prefix), ext)
+		if IsValidProfileName(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{ProfileDefault}, names...)
+}
+
+// CreateProfile 复制当前配置方案的配置文件, 创建新的配置方案
+func CreateProfile(name string) error {
+	if !IsValidProfileName(name) || name == ProfileDefault {
+		return fmt.Errorf("配置方案名称 %s 不正确, 只能包含字母、数字、_、-", name)
+	}
+	path := GetProfileConfigFilePath(name)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("配置方案 %s 已存在", name)
+	}
+	byt, err := ioutil.ReadFile(GetConfigFilePath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return ioutil.WriteFile(path, byt, 0600)
+}
+
+// DeleteProfile 删除配置方案, 不能删除默认及当前的配置方案
+func DeleteProfile(name string) error {
+	if name == ProfileDefault || !IsValidProfileName(name) {
+		return fmt.Errorf("不能删除配置方案 %s", name)
+	}
+	if name == GetProfile() {
+		return fmt.Errorf("不能删除当前的配置方案 %s", name)
+	}
+	if err := os.Remove(GetProfileConfigFilePath(name)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("配置方案 %s 不存在", name)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestProfile 测试配置方案的创建、切换及删除
+func TestProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddns-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "config.yaml")
+	os.Setenv(ConfigFilePathENV, base)
+	defer os.Unsetenv(ConfigFilePathENV)
+	if err = ioutil.WriteFile(base, []byte("ttl: \"600\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = CreateProfile("vps"); err != nil {
+		t.Fatal(err)
+	}
+	if CreateProfile("vps") == nil || CreateProfile("../x") == nil {
+		t.Error("已存在或名称不正确时应返回错误")
+	}
+	if profiles := GetProfiles(); !reflect.DeepEqual(profiles, []string{ProfileDefault, "vps"}) {
+		t.Errorf("配置方案不正确: %v", profiles)
+	}
+
+	if err = SetProfile("vps"); err != nil {
+		t.Fatal(err)
+	}
+	if GetConfigFilePath() != filepath.Join(dir, "config.profile-vps.yaml") {
+		t.Errorf("配置文件路径不正确: %s", GetConfigFilePath())
+	}
+	if DeleteProfile("vps") == nil {
+		t.Error("不能删除当前的配置方案")
+	}
+
+	// 其它进程切换的配置方案, 重新读取后生效
+	if err = ioutil.WriteFile(getActiveProfileFilePath(), []byte(ProfileDefault), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if GetProfile() != "vps" {
+		t.Error("应使用已读取的配置方案")
+	}
+	RefreshProfile()
+	if GetProfile() != ProfileDefault {
+		t.Errorf("重新读取后配置方案不正确: %s", GetProfile())
+	}
+
+	if err = SetProfile(ProfileDefault); err != nil {
+		t.Fatal(err)
+	}
+	if err = DeleteProfile("vps"); err != nil || GetConfigFilePath() != base {
+		t.Error("删除配置方案失败", err)
+	}
+}
@@ -0,0 +1,87 @@
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ProxyDirect 不使用代理, 也不使用环境变量中的代理
+const ProxyDirect = "direct"
+
+// 请求DNS服务商及获取IP使用的代理, 为空时使用环境变量HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+var globalProxy string
+
+// 每个代理的Transport, 相同代理的请求复用连接
+var proxyTransports = struct {
+	sync.Mutex
+	m map[string]*http.Transport
+}{m: map[string]*http.Transport{}}
+
+// ParseProxy 解析代理地址, 支持 http://, https://, socks5://, 可带用户名和密码
+func ParseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("代理 %s 不正确, 如: http://127.0.0.1:7890, socks5://127.0.0.1:1080", proxy)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "socks5":
+		return u, nil
+	}
+	return nil, fmt.Errorf("代理 %s 不正确, 只支持 http, https, socks5", proxy)
+}
+
+// CheckProxy 校验代理的配置, 可为空、direct或代理地址
+func CheckProxy(proxy string) error {
+	if proxy == "" || proxy == ProxyDirect {
+		return nil
+	}
+	_, err := ParseProxy(proxy)
+	return err
+}
+
+// SetProxy 设置请求DNS服务商、获取IP及Webhook使用的代理, 为空时使用环境变量
+func SetProxy(proxy string) error {
+	proxy = strings.TrimSpace(proxy)
+	if err := CheckProxy(proxy); err != nil {
+		return err
+	}
+	proxyTransports.Lock()
+	defer proxyTransports.Unlock()
+	globalProxy = proxy
+	return nil
+}
+
+// proxyTransport 获得使用代理的Transport, proxy为空时使用设置的代理, 未设置时使用环境变量. 相同的配置复用Transport
+// provider为true时用于请求DNS服务商, 接口的域名使用设置的固定IP或DNS服务器解析, ipVersion为使用的IP版本, bind为绑定的网卡或源IP
+func proxyTransport(proxy string, provider bool, ipVersion string, bind string) http.RoundTripper {
+	proxyTransports.Lock()
+	defer proxyTransports.Unlock()
+
+	if proxy == "" {
+		proxy = globalProxy
+	}
+	key := proxy
+	if provider {
+		key = "provider " + ipVersion + " " + bind + " " + proxy
+	}
+	if t, ok := proxyTransports.m[key]; ok {
+		return t
+	}
+	t := newTransport()
+	if proxy == ProxyDirect {
+		t.Proxy = nil
+	} else if proxy != "" {
+		// 代理不正确时使用环境变量
+		if u, err := ParseProxy(proxy); err == nil {
+			t.Proxy = http.ProxyURL(u)
+		}
+	}
+	if provider {
+		t.DialContext = bootstrapDial(ipVersion, bind, httpOptions.ConnectTimeout)
+	}
+	proxyTransports.m[key] = t
+	return t
+}
@@ -0,0 +1,140 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 未设置限速时, 每秒最多请求DNS服务商的次数及可连续请求的次数
+const (
+	DefaultRateLimit = 5
+	DefaultRateBurst = 10
+)
+
+// ParseRateLimit 解析限速, 格式为 每秒请求数 或 每秒请求数/连续请求数, 如 2/5.
+// 为空时使用默认值, 0为不限速
+func ParseRateLimit(s string) (rate float64, burst int, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return DefaultRateLimit, DefaultRateBurst, nil
+	}
+	rateStr, burstStr := s, ""
+	if i := strings.Index(s, "/"); i >= 0 {
+		rateStr, burstStr = s[:i], s[i+1:]
+	}
+	rate, err = strconv.ParseFloat(strings.TrimSpace(rateStr), 64)
+	if err != nil || rate < 0 {
+		return 0, 0, fmt.Errorf("限速 %s 不正确, 格式为 每秒请求数/连续请求数, 如 2/5", s)
+	}
+	burst = int(rate)
+	if burstStr != "" {
+		burst, err = strconv.Atoi(strings.TrimSpace(burstStr))
+		if err != nil || burst < 1 {
+			return 0, 0, fmt.Errorf("限速 %s 的连续请求数不正确", s)
+		}
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return rate, burst, nil
+}
+
+// RateLimiter 令牌桶限速, 每秒生成rate个令牌, 最多累积burst个
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter 创建限速, 开始时可连续请求burst次
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{rate: rate, burst: burst, tokens: float64(burst)}
+}
+
+// reserve 取一个令牌, 返回需等待的时间. 令牌不足时预支, 之后的请求等待更久
+func (l *RateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+	if !l.last.IsZero() && now.After(l.last) {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > float64(l.burst) {
+			l.tokens = float64(l.burst)
+		}
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel 等待时ctx被取消, 归还预支的令牌
+func (l *RateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+}
+
+// Wait 等待到可以请求, ctx取消时返回错误
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	wait := l.reserve(time.Now())
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// 每个DNS服务商帐号的限速, 同时更新的域名共享
+var rateLimiters = struct {
+	sync.Mutex
+	m map[string]*RateLimiter
+}{m: make(map[string]*RateLimiter)}
+
+// SharedRateLimiter key相同的请求共享限速, 修改了限速时重新创建
+func SharedRateLimiter(key string, rate float64, burst int) *RateLimiter {
+	rateLimiters.Lock()
+	defer rateLimiters.Unlock()
+	l := rateLimiters.m[key]
+	if l == nil || l.rate != rate || l.burst != burst {
+		l = NewRateLimiter(rate, burst)
+		rateLimiters.m[key] = l
+	}
+	return l
+}
+
+// rateLimitTransport 请求前等待限速
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *RateLimiter
+}
+
+// RateLimitTransport 请求前等待限速的Transport
+func RateLimitTransport(base http.RoundTripper, limiter *RateLimiter) http.RoundTripper {
+	return &rateLimitTransport{base: base, limiter: limiter}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
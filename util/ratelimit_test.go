@@ -0,0 +1,68 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestParseRateLimit 为空时默认值, 只有每秒请求数时连续请求数与之相同
+func TestParseRateLimit(t *testing.T) {
+	cases := []struct {
+		s     string
+		rate  float64
+		burst int
+		ok    bool
+	}{
+		{"", DefaultRateLimit, DefaultRateBurst, true},
+		{"0", 0, 1, true},
+		{"2/5", 2, 5, true},
+		{"0.5", 0.5, 1, true},
+		{" 3 ", 3, 3, true},
+		{"-1", 0, 0, false},
+		{"2/0", 0, 0, false},
+		{"abc", 0, 0, false},
+	}
+	for _, c := range cases {
+		rate, burst, err := ParseRateLimit(c.s)
+		if (err == nil) != c.ok || rate != c.rate || burst != c.burst {
+			t.Errorf("ParseRateLimit(%q) = %v %v %v", c.s, rate, burst, err)
+		}
+	}
+}
+
+// TestRateLimiter 连续请求burst次后按rate等待
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(2, 3)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if wait := l.reserve(now); wait != 0 {
+			t.Fatalf("第%d次请求不应等待: %s", i+1, wait)
+		}
+	}
+	if wait := l.reserve(now); wait != 500*time.Millisecond {
+		t.Errorf("令牌不足时应等待500ms: %s", wait)
+	}
+	if wait := l.reserve(now); wait != time.Second {
+		t.Errorf("预支后应等待更久: %s", wait)
+	}
+	// 令牌最多累积burst个
+	if wait := l.reserve(now.Add(time.Hour)); wait != 0 {
+		t.Errorf("一段时间后不应等待: %s", wait)
+	}
+	if l.tokens != 2 {
+		t.Errorf("令牌数 %v 不正确", l.tokens)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	waiting := NewRateLimiter(0.001, 1)
+	waiting.reserve(time.Now())
+	if err := waiting.Wait(ctx); err == nil {
+		t.Error("ctx取消时应返回错误")
+	}
+
+	if SharedRateLimiter("a", 1, 1) != SharedRateLimiter("a", 1, 1) || SharedRateLimiter("a", 1, 1) == SharedRateLimiter("a", 2, 2) {
+		t.Error("限速相同时应共享, 修改后重新创建")
+	}
+}
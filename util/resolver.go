@@ -0,0 +1,270 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 查询的超时时间
+const resolverTimeout = 5 * time.Second
+
+// DNS记录类型
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+)
+
+// Resolver DNS服务器, 支持DNS(UDP)、DNS over TLS及DNS over HTTPS
+type Resolver struct {
+	// udp/tls/https
+	network string
+	// udp/tls时为 host:port, https时为URL
+	addr string
+}
+
+// ParseResolver 解析DNS服务器, 如 223.5.5.5, udp://223.5.5.5:53, tls://1.1.1.1, https://1.1.1.1/dns-query
+func ParseResolver(resolver string) (*Resolver, error) {
+	resolver = strings.TrimSpace(resolver)
+	if !strings.Contains(resolver, "://") {
+		resolver = "udp://" + resolver
+	}
+	u, err := url.Parse(resolver)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("DNS服务器 %s 不正确, 如: 223.5.5.5, tls://1.1.1.1, https://1.1.1.1/dns-query", resolver)
+	}
+	switch u.Scheme {
+	case "udp", "tls":
+		port := u.Port()
+		if port == "" {
+			port = map[string]string{"udp": "53", "tls": "853"}[u.Scheme]
+		}
+		return &Resolver{network: u.Scheme, addr: net.JoinHostPort(u.Hostname(), port)}, nil
+	case "https":
+		if u.Path == "" {
+			u.Path = "/dns-query"
+		}
+		return &Resolver{network: u.Scheme, addr: u.String()}, nil
+	}
+	return nil, fmt.Errorf("DNS服务器 %s 不正确, 只支持 udp, tls, https", resolver)
+}
+
+// String DNS服务器地址
+func (r *Resolver) String() string {
+	if r.network == "https" {
+		return r.addr
+	}
+	return r.network + "://" + r.addr
+}
+
+// LookupIP 查询域名的A/AAAA记录
+func (r *Resolver) LookupIP(ctx context.Context, domain string, recordType string) ([]string, error) {
+	qtype := uint16(dnsTypeA)
+	if recordType == "AAAA" {
+		qtype = dnsTypeAAAA
+	}
+	query, id, err := buildDNSQuery(domain, qtype, r.network != "https")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolverTimeout)
+	defer cancel()
+	var resp []byte
+	switch r.network {
+	case "https":
+		resp, err = r.exchangeHTTPS(ctx, query)
+	case "tls":
+		resp, err = r.exchangeStream(ctx, query, true)
+	default:
+		resp, err = r.exchangeUDP(ctx, query)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询 %s 失败: %s", r, err)
+	}
+	return parseDNSResponse(resp, id, qtype)
+}
+
+// exchangeUDP 通过UDP查询, 结果被截断时使用TCP
+func (r *Resolver) exchangeUDP(ctx context.Context, query []byte) ([]byte, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", r.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err = conn.Write(query); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, 4096)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return nil, err
+	}
+	// TC
+	if n > 2 && resp[2]&0x02 != 0 {
+		return r.exchangeStream(ctx, query, false)
+	}
+	return resp[:n], nil
+}
+
+// exchangeStream 通过TCP或TLS查询, 前2字节为长度
+func (r *Resolver) exchangeStream(ctx context.Context, query []byte, useTLS bool) ([]byte, error) {
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if useTLS {
+		host, _, _ := net.SplitHostPort(r.addr)
+		conn, err = dialer.DialContext(ctx, "tcp", r.addr)
+		if err == nil {
+			tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+			if deadline, ok := ctx.Deadline(); ok {
+				tlsConn.SetDeadline(deadline)
+			}
+			if err = tlsConn.Handshake(); err != nil {
+				conn.Close()
+			}
+			conn = tlsConn
+		}
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", r.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	if _, err = conn.Write(msg); err != nil {
+		return nil, err
+	}
+	length := make([]byte, 2)
+	if _, err = io.ReadFull(conn, length); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length))
+	if _, err = io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// exchangeHTTPS 按RFC8484使用POST查询
+func (r *Resolver) exchangeHTTPS(ctx context.Context, query []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", r.addr, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("返回状态码: %d", resp.StatusCode)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, 65535))
+}
+
+// buildDNSQuery 生成查询报文, DoH时ID为0以便缓存
+func buildDNSQuery(domain string, qtype uint16, randomID bool) (query []byte, id uint16, err error) {
+	if randomID {
+		b := make([]byte, 2)
+		rand.Read(b)
+		id = binary.BigEndian.Uint16(b)
+	}
+	// ID, RD=1, QDCOUNT=1
+	query = make([]byte, 12)
+	binary.BigEndian.PutUint16(query, id)
+	binary.BigEndian.PutUint16(query[2:], 0x0100)
+	binary.BigEndian.PutUint16(query[4:], 1)
+
+	for _, label := range strings.Split(strings.TrimSuffix(domain, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return nil, 0, fmt.Errorf("域名 %s 不正确", domain)
+		}
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	query = append(query, 0, byte(qtype>>8), byte(qtype), 0, 1)
+	return
+}
+
+// parseDNSResponse 解析返回报文中类型为qtype的记录
+func parseDNSResponse(resp []byte, id uint16, qtype uint16) (ips []string, err error) {
+	if len(resp) < 12 || binary.BigEndian.Uint16(resp) != id {
+		return nil, errors.New("返回的报文不正确")
+	}
+	switch rcode := resp[3] & 0x0f; rcode {
+	case 0:
+	case 3:
+		// NXDOMAIN
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("返回错误码: %d", rcode)
+	}
+
+	qdcount := int(binary.BigEndian.Uint16(resp[4:]))
+	ancount := int(binary.BigEndian.Uint16(resp[6:]))
+	off := 12
+	for i := 0; i < qdcount; i++ {
+		if off, err = skipDNSName(resp, off); err != nil {
+			return nil, err
+		}
+		off += 4
+	}
+	for i := 0; i < ancount; i++ {
+		if off, err = skipDNSName(resp, off); err != nil {
+			return nil, err
+		}
+		if off+10 > len(resp) {
+			return nil, errors.New("返回的报文不正确")
+		}
+		rtype := binary.BigEndian.Uint16(resp[off:])
+		rdlen := int(binary.BigEndian.Uint16(resp[off+8:]))
+		off += 10
+		if off+rdlen > len(resp) {
+			return nil, errors.New("返回的报文不正确")
+		}
+		if rtype == qtype && (rdlen == net.IPv4len || rdlen == net.IPv6len) {
+			ips = append(ips, net.IP(resp[off:off+rdlen]).String())
+		}
+		off += rdlen
+	}
+	return ips, nil
+}
+
+// skipDNSName 跳过报文中的域名, 支持压缩指针
+func skipDNSName(msg []byte, off int) (int, error) {
+	for off < len(msg) {
+		l := int(msg[off])
+		switch {
+		case l == 0:
+			return off + 1, nil
+		case l&0xc0 == 0xc0:
+			return off + 2, nil
+		}
+		off += l + 1
+	}
+	return 0, errors.New("返回的报文不正确")
+}
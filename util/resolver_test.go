@@ -0,0 +1,81 @@
+package util
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// fakeDNSAnswer 生成返回报文, 回答一条A记录
+func fakeDNSAnswer(query []byte, ip string) []byte {
+	resp := append([]byte{}, query...)
+	binary.BigEndian.PutUint16(resp[2:], 0x8180)
+	binary.BigEndian.PutUint16(resp[6:], 1)
+	resp = append(resp, 0xc0, 0x0c, 0, dnsTypeA, 0, 1, 0, 0, 0, 60, 0, 4)
+	return append(resp, net.ParseIP(ip).To4()...)
+}
+
+// TestParseResolver 解析DNS服务器
+func TestParseResolver(t *testing.T) {
+	tests := map[string]string{
+		"223.5.5.5":            "udp://223.5.5.5:53",
+		"udp://[2400:3200::1]": "udp://[2400:3200::1]:53",
+		"tls://1.1.1.1":        "tls://1.1.1.1:853",
+		"https://1.1.1.1":      "https://1.1.1.1/dns-query",
+	}
+	for resolver, expected := range tests {
+		r, err := ParseResolver(resolver)
+		if err != nil || r.String() != expected {
+			t.Errorf("%s 解析为 %v, 应为 %s", resolver, r, expected)
+		}
+	}
+	if _, err := ParseResolver("ftp://1.1.1.1"); err == nil {
+		t.Error("不支持的协议应解析失败")
+	}
+}
+
+// TestLookupIPUDP 通过UDP查询
+func TestLookupIPUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 512)
+		n, addr, err := conn.ReadFrom(buf)
+		if err == nil {
+			conn.WriteTo(fakeDNSAnswer(buf[:n], "192.0.2.10"), addr)
+		}
+	}()
+
+	r, _ := ParseResolver(conn.LocalAddr().String())
+	ips, err := r.LookupIP(context.Background(), "ddns.example.com", "A")
+	if err != nil || !reflect.DeepEqual(ips, []string{"192.0.2.10"}) {
+		t.Errorf("查询失败: %v %v", ips, err)
+	}
+}
+
+// TestLookupIPHTTPS 通过DNS over HTTPS查询
+func TestLookupIPHTTPS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query, _ := ioutil.ReadAll(req.Body)
+		if req.Header.Get("Content-Type") != "application/dns-message" || binary.BigEndian.Uint16(query) != 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write(fakeDNSAnswer(query, "192.0.2.20"))
+	}))
+	defer server.Close()
+
+	r := &Resolver{network: "https", addr: server.URL + "/dns-query"}
+	ips, err := r.LookupIP(context.Background(), "ddns.example.com", "A")
+	if err != nil || !reflect.DeepEqual(ips, []string{"192.0.2.20"}) {
+		t.Errorf("查询失败: %v %v", ips, err)
+	}
+}
@@ -0,0 +1,120 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Store 嵌入式存储, 基于bbolt, 每个bucket中按时间顺序保存JSON记录
+// 每次操作时打开文件, 不长期占用文件锁, 运行中也可使用子命令读取
+type Store struct {
+	Path string
+}
+
+// 打开文件的超时时间, 其它进程正在使用时等待
+const storeOpenTimeout = 3 * time.Second
+
+// update 在写事务中执行
+func (s Store) update(fn func(tx *bolt.Tx) error) error {
+	db, err := bolt.Open(s.Path, 0600, &bolt.Options{Timeout: storeOpenTimeout})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(fn)
+}
+
+// view 在读事务中执行, 文件不存在时不创建
+func (s Store) view(fn func(tx *bolt.Tx) error) error {
+	if _, err := os.Stat(s.Path); os.IsNotExist(err) {
+		return nil
+	}
+	db, err := bolt.Open(s.Path, 0600, &bolt.Options{Timeout: storeOpenTimeout})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+// storeKey 时间+序号, 按时间排序且不重复
+func storeKey(t time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+// StoreRecord 一条记录
+type StoreRecord struct {
+	Time  time.Time
+	Value interface{}
+}
+
+// Add 添加记录
+func (s Store) Add(bucket string, records ...StoreRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return s.update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			byt, err := json.Marshal(record.Value)
+			if err != nil {
+				return err
+			}
+			seq, _ := b.NextSequence()
+			if err = b.Put(storeKey(record.Time, seq), byt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List 从新到旧遍历记录, fn返回false时停止
+func (s Store) List(bucket string, fn func(value []byte) bool) error {
+	return s.view(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if !fn(v) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// Prune 删除各bucket中早于before的记录
+func (s Store) Prune(before time.Time, buckets ...string) (deleted int, err error) {
+	end := storeKey(before, 0)
+	err = s.update(func(tx *bolt.Tx) error {
+		for _, bucket := range buckets {
+			b := tx.Bucket([]byte(bucket))
+			if b == nil {
+				continue
+			}
+			c := b.Cursor()
+			for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.First() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				deleted++
+			}
+		}
+		return nil
+	})
+	return
+}
@@ -0,0 +1,52 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestStore 测试记录的添加、倒序读取及清理
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddns-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := Store{Path: filepath.Join(dir, "history.db")}
+	// 文件不存在时读取为空且不创建
+	store.List("updates", func(value []byte) bool {
+		t.Error("文件不存在时不应有记录")
+		return true
+	})
+	if _, err = os.Stat(store.Path); !os.IsNotExist(err) {
+		t.Error("读取时不应创建文件")
+	}
+
+	now := time.Now()
+	err = store.Add("updates",
+		StoreRecord{Time: now.AddDate(0, 0, -10), Value: "a"},
+		StoreRecord{Time: now, Value: "b"},
+		StoreRecord{Time: now, Value: "c"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []string{}
+	store.List("updates", func(value []byte) bool {
+		values = append(values, string(value))
+		return true
+	})
+	if len(values) != 3 || values[0] != `"c"` || values[2] != `"a"` {
+		t.Errorf("读取的顺序不正确: %v", values)
+	}
+
+	deleted, err := store.Prune(now.AddDate(0, 0, -1), "updates", "detections")
+	if err != nil || deleted != 1 {
+		t.Errorf("清理的条数不正确: %d, %v", deleted, err)
+	}
+}
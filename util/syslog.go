@@ -0,0 +1,112 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syslog的级别
+const (
+	SyslogError   = 3
+	SyslogWarning = 4
+	SyslogInfo    = 6
+	SyslogDebug   = 7
+)
+
+// syslog的facility, daemon
+const syslogFacility = 3
+
+// Syslog 按RFC5424发送日志到远程的syslog服务器
+type Syslog struct {
+	network  string
+	addr     string
+	tag      string
+	hostname string
+
+	lock sync.Mutex
+	conn net.Conn
+}
+
+// DialSyslog 连接syslog服务器, 支持 udp://host:514, tcp://host:514, 未填写端口时为514
+func DialSyslog(rawURL string, tag string) (*Syslog, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Hostname() == "" {
+		return nil, fmt.Errorf("syslog地址 %s 不正确, 如: udp://192.168.1.2:514", rawURL)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "514")
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	s := &Syslog{network: u.Scheme, addr: addr, tag: tag, hostname: hostname}
+	if err = s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Send 发送一条日志, 连接断开时重新连接
+func (s *Syslog) Send(severity int, msg string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	line := s.format(severity, msg, time.Now())
+	var err error
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			if err = s.connect(); err != nil {
+				return err
+			}
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err = s.conn.Write([]byte(line)); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+// Close 关闭连接
+func (s *Syslog) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *Syslog) connect() error {
+	conn, err := net.DialTimeout(s.network, s.addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// format RFC5424格式, TCP时按RFC6587在前面加上长度
+func (s *Syslog) format(severity int, msg string, t time.Time) string {
+	msg = strings.TrimRight(msg, "\n")
+	line := fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+		syslogFacility*8+severity, t.Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname, s.tag, os.Getpid(), msg)
+	if s.network == "tcp" {
+		return fmt.Sprintf("%d %s", len(line), line)
+	}
+	return line
+}
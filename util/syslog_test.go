@@ -0,0 +1,41 @@
+package util
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSyslog 测试按RFC5424发送日志
+func TestSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s, err := DialSyslog("udp://"+conn.LocalAddr().String(), "ddns-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err = s.Send(SyslogError, "更新失败\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := string(buf[:n])
+	if !strings.HasPrefix(line, "<27>1 ") || !strings.HasSuffix(line, " ddns-go "+strings.Fields(line)[4]+" - - 更新失败") {
+		t.Errorf("格式不正确: %s", line)
+	}
+
+	if _, err = DialSyslog("http://127.0.0.1", "ddns-go"); err == nil {
+		t.Error("不支持的地址应返回错误")
+	}
+}
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestSdNotify 发送到NOTIFY_SOCKET, 没有时不发送
+func TestSdNotify(t *testing.T) {
+	if err := SdNotify("READY=1"); err != nil {
+		t.Errorf("没有NOTIFY_SOCKET时不应失败: %s", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+	os.Setenv("NOTIFY_SOCKET", path)
+	defer os.Unsetenv("NOTIFY_SOCKET")
+
+	if err := SdNotify("READY=1"); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Errorf("收到的状态不正确: %q %v", buf[:n], err)
+	}
+}
+
+// TestSdWatchdogInterval WATCHDOG_PID不是本进程时不启用
+func TestSdWatchdogInterval(t *testing.T) {
+	os.Setenv("WATCHDOG_USEC", "20000000")
+	defer os.Unsetenv("WATCHDOG_USEC")
+	defer os.Unsetenv("WATCHDOG_PID")
+
+	if interval := SdWatchdogInterval(); interval != 20*time.Second {
+		t.Errorf("WatchdogSec不正确: %s", interval)
+	}
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	if interval := SdWatchdogInterval(); interval != 20*time.Second {
+		t.Errorf("WatchdogSec不正确: %s", interval)
+	}
+	os.Setenv("WATCHDOG_PID", "1")
+	if interval := SdWatchdogInterval(); interval != 0 {
+		t.Errorf("不是发给本进程的应为0: %s", interval)
+	}
+}
+
+// TestSdListeners 使用systemd传递的socket, LISTEN_PID不是本进程时不使用
+func TestSdListeners(t *testing.T) {
+	if addr := os.Getenv("DDNS_GO_TEST_LISTEN_ADDR"); addr != "" {
+		// 子进程中获取传递的socket
+		listeners, err := SdListeners()
+		if err != nil || len(listeners) != 1 || listeners[0].Addr().String() != addr {
+			t.Fatalf("获取systemd传递的socket失败: %v %v", listeners, err)
+		}
+		if os.Getenv("LISTEN_FDS") != "" {
+			t.Error("获取后应清除环境变量")
+		}
+		return
+	}
+
+	os.Setenv("LISTEN_PID", "1")
+	os.Setenv("LISTEN_FDS", "1")
+	if listeners, _ := SdListeners(); len(listeners) != 0 {
+		t.Error("LISTEN_PID不是本进程时不应使用")
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	file, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// exec后进程号不变, LISTEN_PID为子进程
+	cmd := exec.Command("sh", "-c", `LISTEN_PID=$$ LISTEN_FDS=1 exec "$0" -test.run=^TestSdListeners$`, os.Args[0])
+	cmd.Env = append(os.Environ(), "DDNS_GO_TEST_LISTEN_ADDR="+l.Addr().String())
+	cmd.ExtraFiles = []*os.File{file}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("子进程失败: %s %s", err, out)
+	}
+}
@@ -0,0 +1,131 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeWindow 时间段, 如 09:00-18:00 或 mon-fri 09:00-18:00. 开始晚于结束时跨过零点
+type TimeWindow struct {
+	days       [7]bool
+	start, end int
+}
+
+// TimeWindows 多个时间段, 满足其一即可
+type TimeWindows []TimeWindow
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ParseTimeWindows 解析时间段, 多个用逗号分隔。如 "mon-fri 09:00-18:00, sat 10:00-12:00"
+func ParseTimeWindows(expr string) (TimeWindows, error) {
+	var windows TimeWindows
+	for _, item := range strings.Split(expr, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		window, err := parseTimeWindow(item)
+		if err != nil {
+			return nil, fmt.Errorf("时间段 %s 不正确, 应为 HH:MM-HH:MM 或 mon-fri HH:MM-HH:MM", item)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+func parseTimeWindow(item string) (window TimeWindow, err error) {
+	fields := strings.Fields(item)
+	switch len(fields) {
+	case 1:
+		for i := range window.days {
+			window.days[i] = true
+		}
+	case 2:
+		if window.days, err = parseWeekdays(fields[0]); err != nil {
+			return
+		}
+	default:
+		return window, fmt.Errorf("格式不正确")
+	}
+
+	times := strings.Split(fields[len(fields)-1], "-")
+	if len(times) != 2 {
+		return window, fmt.Errorf("格式不正确")
+	}
+	if window.start, err = parseClock(times[0]); err != nil {
+		return
+	}
+	if window.end, err = parseClock(times[1]); err != nil {
+		return
+	}
+	if window.start == window.end {
+		return window, fmt.Errorf("开始和结束时间相同")
+	}
+	return
+}
+
+// parseWeekdays 解析星期, 如 mon 或 mon-fri
+func parseWeekdays(field string) (days [7]bool, err error) {
+	parts := strings.Split(strings.ToLower(field), "-")
+	if len(parts) > 2 {
+		return days, fmt.Errorf("格式不正确")
+	}
+	var index []int
+	for _, part := range parts {
+		found := -1
+		for i, day := range weekdays {
+			if day == part {
+				found = i
+			}
+		}
+		if found < 0 {
+			return days, fmt.Errorf("星期 %s 不正确", part)
+		}
+		index = append(index, found)
+	}
+	end := index[len(index)-1]
+	for i := index[0]; ; i = (i + 1) % 7 {
+		days[i] = true
+		if i == end {
+			break
+		}
+	}
+	return
+}
+
+// parseClock 解析 HH:MM, 返回从零点开始的分钟数. 支持 24:00
+func parseClock(clock string) (int, error) {
+	var hour, minute int
+	if n, err := fmt.Sscanf(clock, "%d:%d", &hour, &minute); err != nil || n != 2 {
+		return 0, fmt.Errorf("时间 %s 不正确", clock)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+		return 0, fmt.Errorf("时间 %s 不正确", clock)
+	}
+	return hour*60 + minute, nil
+}
+
+// Contains t是否在时间段内
+func (window TimeWindow) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	weekday := int(t.Weekday())
+	if window.start < window.end {
+		return window.days[weekday] && minute >= window.start && minute < window.end
+	}
+	// 跨过零点, 星期为开始的那天
+	if minute >= window.start {
+		return window.days[weekday]
+	}
+	return minute < window.end && window.days[(weekday+6)%7]
+}
+
+// Contains t是否在任一时间段内
+func (windows TimeWindows) Contains(t time.Time) bool {
+	for _, window := range windows {
+		if window.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
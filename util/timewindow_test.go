@@ -0,0 +1,38 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTimeWindows 测试时间段
+func TestTimeWindows(t *testing.T) {
+	windows, err := ParseTimeWindows("mon-fri 09:00-18:00, sat 22:00-02:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2022-01-03 为周一
+	cases := map[string]bool{
+		"2022-01-03 09:00": true,
+		"2022-01-03 17:59": true,
+		"2022-01-03 18:00": false,
+		"2022-01-02 10:00": false,
+		"2022-01-08 23:00": true,
+		"2022-01-09 01:30": true,
+		"2022-01-09 02:00": false,
+		"2022-01-08 01:30": false,
+	}
+	for value, expected := range cases {
+		tm, _ := time.ParseInLocation("2006-01-02 15:04", value, time.Local)
+		if windows.Contains(tm) != expected {
+			t.Errorf("%s 应为 %v", value, expected)
+		}
+	}
+
+	for _, expr := range []string{"09:00", "9-18", "mon-xyz 09:00-18:00", "09:00-25:00", "10:00-10:00"} {
+		if _, err := ParseTimeWindows(expr); err == nil {
+			t.Errorf("%s 应解析失败", expr)
+		}
+	}
+}
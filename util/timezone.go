@@ -0,0 +1,60 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	// 容器中通常没有时区数据, 内置后TZ环境变量及 -tz 均可使用
+	_ "time/tzdata"
+)
+
+// DefaultTimeFormat 网页、通知中默认的时间格式
+const DefaultTimeFormat = "2006-01-02 15:04:05"
+
+var timeFormat = struct {
+	sync.RWMutex
+	layout string
+}{}
+
+// SetTimezone 设置日志、网页及通知中使用的时区, 如 Asia/Shanghai, 为空时使用TZ环境变量或系统的时区
+func SetTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("时区 %s 不正确, 如: Asia/Shanghai, UTC", name)
+	}
+	time.Local = loc
+	return nil
+}
+
+// SetTimeFormat 设置日志、网页及通知中的时间格式, 使用Go的格式, 如 2006-01-02 15:04:05. 为空时使用默认的
+func SetTimeFormat(layout string) error {
+	layout = strings.TrimSpace(layout)
+	// 不包含年月日等时格式化后不变
+	if layout != "" && time.Date(2021, 11, 22, 10, 30, 40, 0, time.UTC).Format(layout) == layout {
+		return fmt.Errorf("时间格式 %s 不正确, 需使用Go的格式, 如: 2006-01-02 15:04:05", layout)
+	}
+	timeFormat.Lock()
+	defer timeFormat.Unlock()
+	timeFormat.layout = layout
+	return nil
+}
+
+// TimeFormat 设置的时间格式, 未设置时为空
+func TimeFormat() string {
+	timeFormat.RLock()
+	defer timeFormat.RUnlock()
+	return timeFormat.layout
+}
+
+// FormatTime 使用设置的时区及时间格式
+func FormatTime(t time.Time) string {
+	layout := TimeFormat()
+	if layout == "" {
+		layout = DefaultTimeFormat
+	}
+	return t.In(time.Local).Format(layout)
+}
@@ -0,0 +1,35 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// TestFormatTime 测试设置的时区及时间格式
+func TestFormatTime(t *testing.T) {
+	local := time.Local
+	defer func() {
+		time.Local = local
+		SetTimeFormat("")
+	}()
+
+	if err := SetTimezone("Asia/Shanghai"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetTimezone("Mars/Phobos"); err == nil {
+		t.Error("时区不正确时应返回错误")
+	}
+	utc := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	if got := FormatTime(utc); got != "2022-03-04 13:06:07" {
+		t.Errorf("默认格式返回 %s", got)
+	}
+	if err := SetTimeFormat("02.01.2006 15:04 MST"); err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatTime(utc); got != "04.03.2022 13:06 CST" {
+		t.Errorf("设置的格式返回 %s", got)
+	}
+	if err := SetTimeFormat("yyyy-MM-dd"); err == nil {
+		t.Error("不是Go的格式时应返回错误")
+	}
+}
@@ -0,0 +1,237 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 缓存的最大Span数量, 发送失败时丢弃旧的
+const maxPendingSpans = 1000
+
+// OTLP中Span的类型
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+)
+
+// tracer 将追踪数据按OTLP/HTTP JSON格式发送
+type tracer struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+
+	lock  sync.Mutex
+	spans []*Span
+	// 正在发送的请求, 退出前等待完成
+	sending sync.WaitGroup
+}
+
+// 未开启追踪时为nil
+var otlp *tracer
+
+// SetTraceEndpoint 开启追踪, 发送到OTLP/HTTP接口, 如 http://127.0.0.1:4318
+func SetTraceEndpoint(endpoint string, serviceName string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("OTLP地址 %s 不正确, 如: http://127.0.0.1:4318", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, "/v1/traces") {
+		u.Path = strings.TrimRight(u.Path, "/") + "/v1/traces"
+	}
+	otlp = &tracer{
+		endpoint:    u.String(),
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+	return nil
+}
+
+type spanKey struct{}
+
+// Span 追踪中的一段, 未开启追踪时为nil, 方法可在nil上调用
+type Span struct {
+	traceID  string
+	spanID   string
+	parentID string
+	kind     int
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    [][2]string
+	err      string
+}
+
+// StartSpan 开始一段追踪, ctx中有Span时作为其子Span
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	if otlp == nil {
+		return ctx, nil
+	}
+	span := &Span{spanID: randomHex(8), kind: spanKindInternal, name: name, start: time.Now()}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		span.traceID = randomHex(16)
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// startChildSpan ctx中有Span时开始一段子Span, 否则返回nil
+func startChildSpan(ctx context.Context, name string) *Span {
+	if _, ok := ctx.Value(spanKey{}).(*Span); !ok {
+		return nil
+	}
+	_, span := StartSpan(ctx, name)
+	return span
+}
+
+// SetAttribute 设置属性
+func (span *Span) SetAttribute(key string, value string) {
+	if span == nil {
+		return
+	}
+	span.attrs = append(span.attrs, [2]string{key, value})
+}
+
+// SetError 标记为失败
+func (span *Span) SetError(err string) {
+	if span == nil {
+		return
+	}
+	span.err = err
+}
+
+// End 结束, 根Span结束时发送本次的追踪数据
+func (span *Span) End() {
+	if span == nil {
+		return
+	}
+	span.end = time.Now()
+
+	otlp.lock.Lock()
+	otlp.spans = append(otlp.spans, span)
+	if len(otlp.spans) > maxPendingSpans {
+		otlp.spans = otlp.spans[len(otlp.spans)-maxPendingSpans:]
+	}
+	otlp.lock.Unlock()
+
+	if span.parentID == "" {
+		otlp.sending.Add(1)
+		go func() {
+			defer otlp.sending.Done()
+			otlp.flush()
+		}()
+	}
+}
+
+// FlushTrace 发送缓存的追踪数据并等待发送完成, 用于退出前. ctx取消时不再等待
+func FlushTrace(ctx context.Context) {
+	if otlp == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		otlp.sending.Wait()
+		otlp.flush()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// flush 发送缓存的Span
+func (t *tracer) flush() {
+	t.lock.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.lock.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(t.encode(spans)))
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			err = fmt.Errorf("返回状态码: %d", resp.StatusCode)
+		}
+	}
+	if err != nil {
+		log.Printf("发送追踪数据到 %s 失败: %s\n", t.endpoint, err)
+	}
+}
+
+// OTLP JSON格式
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string                 `json:"traceId"`
+	SpanID            string                 `json:"spanId"`
+	ParentSpanID      string                 `json:"parentSpanId,omitempty"`
+	Name              string                 `json:"name"`
+	Kind              int                    `json:"kind"`
+	StartTimeUnixNano string                 `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string                 `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute        `json:"attributes,omitempty"`
+	Status            map[string]interface{} `json:"status"`
+}
+
+// encode 转换为OTLP/HTTP JSON的请求内容
+func (t *tracer) encode(spans []*Span) []byte {
+	result := []otlpSpan{}
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.traceID,
+			SpanID:            span.spanID,
+			ParentSpanID:      span.parentID,
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Status:            map[string]interface{}{"code": 1},
+		}
+		for _, attr := range span.attrs {
+			s.Attributes = append(s.Attributes, otlpAttribute{Key: attr[0], Value: map[string]string{"stringValue": attr[1]}})
+		}
+		if span.err != "" {
+			s.Status = map[string]interface{}{"code": 2, "message": span.err}
+		}
+		result = append(result, s)
+	}
+
+	byt, _ := json.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []otlpAttribute{{Key: "service.name", Value: map[string]string{"stringValue": t.serviceName}}},
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": "ddns-go"},
+				"spans": result,
+			}},
+		}},
+	})
+	return byt
+}
+
+// randomHex n字节的随机数, 转为16进制
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestTrace 测试Span的父子关系及OTLP格式
+func TestTrace(t *testing.T) {
+	if _, span := StartSpan(context.Background(), "update"); span != nil {
+		t.Fatal("未开启追踪时应返回nil")
+	}
+	if err := SetTraceEndpoint("127.0.0.1:4318", "ddns-go"); err == nil {
+		t.Error("地址不正确时应返回错误")
+	}
+	if err := SetTraceEndpoint("http://127.0.0.1:1", "ddns-go"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { otlp = nil }()
+	if otlp.endpoint != "http://127.0.0.1:1/v1/traces" {
+		t.Errorf("地址不正确: %s", otlp.endpoint)
+	}
+
+	ctx, root := StartSpan(context.Background(), "update")
+	_, child := StartSpan(ctx, "detect IPv4")
+	child.SetError("未能获取IPv4地址")
+	if child.traceID != root.traceID || child.parentID != root.spanID {
+		t.Error("子Span的traceID或parentID不正确")
+	}
+	if startChildSpan(context.Background(), "HTTP GET") != nil {
+		t.Error("没有父Span时不应记录请求")
+	}
+
+	var result struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					ParentSpanID string
+					Status       struct{ Code int }
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(otlp.encode([]*Span{root, child}), &result); err != nil {
+		t.Fatal(err)
+	}
+	spans := result.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 || spans[1].ParentSpanID != root.spanID || spans[1].Status.Code != 2 {
+		t.Errorf("OTLP格式不正确: %+v", spans)
+	}
+}
+
+// TestFlushTrace 退出前发送全部追踪数据
+func TestFlushTrace(t *testing.T) {
+	received := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+	}))
+	defer server.Close()
+	if err := SetTraceEndpoint(server.URL, "ddns-go"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { otlp = nil }()
+
+	ctx, root := StartSpan(context.Background(), "update")
+	_, child := StartSpan(ctx, "detect IPv4")
+	child.End()
+	root.End()
+	FlushTrace(context.Background())
+	if received != 1 || len(otlp.spans) != 0 {
+		t.Errorf("退出前应发送追踪数据, 请求了%d次, 剩余%d", received, len(otlp.spans))
+	}
+}
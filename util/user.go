@@ -3,14 +3,21 @@ package util
 import (
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/mitchellh/go-homedir"
 )
 
 const ConfigFilePathENV = "DDNS_CONFIG_FILE_PATH"
 
-// GetConfigFilePath 获得配置文件路径
+// GetConfigFilePath 获得当前配置方案的配置文件路径
 func GetConfigFilePath() string {
+	return GetProfileConfigFilePath(GetProfile())
+}
+
+// getBaseConfigFilePath 获得 -c 指定的配置文件路径, 即默认配置方案的配置文件路径
+func getBaseConfigFilePath() string {
 	configFilePath := os.Getenv(ConfigFilePathENV)
 	if configFilePath != "" {
 		return configFilePath
@@ -27,3 +34,24 @@ func GetConfigFilePathDefault() string {
 	}
 	return dir + string(os.PathSeparator) + ".ddns_go_config.yaml"
 }
+
+// IsRemoteConfigPath 是否为远程配置, 如 https://, consul://, etcd://
+func IsRemoteConfigPath(path string) bool {
+	for _, prefix := range []string{"http://", "https://", "consul://", "consuls://", "etcd://", "etcds://"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDataFilePath 获得与配置文件同目录的数据文件路径
+// 如配置文件为 ~/.ddns_go_config.yaml, name为history.json, 返回 ~/.ddns_go_config.history.json
+// 使用远程配置时保存在用户目录
+func GetDataFilePath(name string) string {
+	configFilePath := GetConfigFilePath()
+	if IsRemoteConfigPath(configFilePath) {
+		configFilePath = GetConfigFilePathDefault()
+	}
+	return strings.TrimSuffix(configFilePath, filepath.Ext(configFilePath)) + "." + name
+}
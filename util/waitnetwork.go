@@ -0,0 +1,91 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WaitForNetwork 等待网络就绪: 有默认路由, 能解析并访问任一获取IP的接口. 超时或ctx取消时返回未就绪的原因
+func WaitForNetwork(ctx context.Context, timeout time.Duration, urls []string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	logged := false
+	for {
+		err := checkNetwork(ctx, urls)
+		if err == nil {
+			if logged {
+				log.Printf("网络已就绪, 等待了%s\n", time.Since(start).Round(time.Second))
+			}
+			return nil
+		}
+		if !logged {
+			log.Println("等待网络就绪:", err)
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// checkNetwork 检查网络是否就绪, 未就绪时返回原因
+func checkNetwork(ctx context.Context, urls []string) error {
+	if !hasDefaultRoute() {
+		return errors.New("没有默认路由")
+	}
+
+	var err error
+	for _, u := range urls {
+		if err = checkURL(ctx, u); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// hasDefaultRoute 是否有IPv4或IPv6的默认路由. 连接UDP时不会发送数据
+func hasDefaultRoute() bool {
+	for _, addr := range []string{"223.5.5.5:53", "[2400:3200::1]:53"} {
+		if conn, err := net.Dial("udp", addr); err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// checkURL 能否解析域名并访问URL, 有响应即可
+func checkURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("URL %s 不正确", rawURL)
+	}
+	if net.ParseIP(u.Hostname()) == nil {
+		if _, err := net.DefaultResolver.LookupHost(ctx, u.Hostname()); err != nil {
+			return fmt.Errorf("无法解析 %s", u.Hostname())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "HEAD", rawURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("无法访问 %s", rawURL)
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"ddns-go/config"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIKeys API密钥管理页面
+func APIKeys(writer http.ResponseWriter, request *http.Request) {
+	tmpl, err := parseTemplate(apiKeysEmbedFile, "apiKeys.html", request)
+	if err != nil {
+		fmt.Println("Error happened..")
+		fmt.Println(err)
+		return
+	}
+
+	conf, _ := config.GetConfigCache()
+	tmpl.Execute(writer, conf.APIKeys)
+}
+
+// CreateAPIKey 创建API密钥, 返回的密钥只显示一次
+func CreateAPIKey(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimSpace(request.FormValue("Name"))
+	scope := request.FormValue("Scope")
+	if name == "" {
+		writer.WriteHeader(http.StatusBadRequest)
+		writer.Write([]byte("名称不能为空"))
+		return
+	}
+	if !config.IsValidAPIKeyScope(scope) {
+		writer.WriteHeader(http.StatusBadRequest)
+		writer.Write([]byte("权限不正确"))
+		return
+	}
+
+	conf, _ := config.GetConfigCache()
+	old := conf
+	apiKey, key, err := config.NewAPIKey(name, scope)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		writer.Write([]byte(err.Error()))
+		return
+	}
+	conf.APIKeys = append(conf.APIKeys, apiKey)
+	if err = conf.SaveConfig(); err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		writer.Write([]byte(err.Error()))
+		return
+	}
+	auditConfig(request, old, conf)
+
+	byt, _ := json.Marshal(struct {
+		ID  string
+		Key string
+	}{ID: apiKey.ID, Key: key})
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Write(byt)
+}
+
+// RevokeAPIKey 撤销API密钥
+func RevokeAPIKey(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	conf, _ := config.GetConfigCache()
+	old := conf
+	if !conf.RemoveAPIKey(request.FormValue("ID")) {
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte("未找到API密钥"))
+		return
+	}
+	if err := conf.SaveConfig(); err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		writer.Write([]byte(err.Error()))
+		return
+	}
+	auditConfig(request, old, conf)
+	writer.Write([]byte("ok"))
+}
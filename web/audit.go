@@ -0,0 +1,96 @@
+package web
+
+import (
+	"context"
+	"ddns-go/config"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 页面中显示的审计日志条数
+const auditRecordNum int = 200
+
+// 同一IP及帐号在此时间内再次登录成功时不重复记录
+const auditLoginInterval = 30 * time.Minute
+
+type auditUserKey struct{}
+
+// 最后一次记录登录成功的时间, key为IP及帐号
+var auditLogins = struct {
+	sync.Mutex
+	last map[string]time.Time
+}{last: map[string]time.Time{}}
+
+// Audit 审计日志
+func Audit(writer http.ResponseWriter, request *http.Request) {
+	tmpl, err := parseTemplate(auditEmbedFile, "audit.html", request)
+	if err != nil {
+		fmt.Println("Error happened..")
+		fmt.Println(err)
+		return
+	}
+	tmpl.Execute(writer, config.GetAuditRecords(auditRecordNum))
+}
+
+// withAuditUser 在请求中保存登录帐号或API密钥名称
+func withAuditUser(r *http.Request, user string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), auditUserKey{}, user))
+}
+
+// auditConfig 记录配置的修改
+func auditConfig(r *http.Request, old config.Config, new config.Config) {
+	auditChanges(r, config.DiffConfig(old, new))
+}
+
+// auditChanges 记录修改的内容, 没有修改时不记录
+func auditChanges(r *http.Request, changes []config.AuditChange) {
+	if len(changes) == 0 {
+		return
+	}
+	user, _ := r.Context().Value(auditUserKey{}).(string)
+	config.AddAuditRecord(config.AuditRecord{
+		Type:    config.AuditTypeConfig,
+		User:    user,
+		IP:      clientIP(r),
+		Action:  r.URL.Path,
+		Success: true,
+		Changes: changes,
+	})
+}
+
+// auditLogin 记录登录, 登录成功时每个IP及帐号在auditLoginInterval内只记录一次
+func auditLogin(r *http.Request, user string, success bool) {
+	ip := clientIP(r)
+	if success {
+		auditLogins.Lock()
+		key := ip + " " + user
+		last, ok := auditLogins.last[key]
+		now := time.Now()
+		auditLogins.last[key] = now
+		auditLogins.Unlock()
+		if ok && now.Sub(last) < auditLoginInterval {
+			return
+		}
+	}
+	config.AddAuditRecord(config.AuditRecord{
+		Type:    config.AuditTypeLogin,
+		User:    user,
+		IP:      ip,
+		Action:  r.URL.Path,
+		Success: success,
+	})
+}
+
+// clientIP 请求的来源IP
+func clientIP(r *http.Request) string {
+	if isUnixSocketRequest(r) {
+		return "unix"
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -6,6 +6,7 @@ import (
 	"ddns-go/util"
 	"encoding/base64"
 	"log"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -20,19 +21,97 @@ type loginDetect struct {
 
 var ld = &loginDetect{}
 
-// BasicAuth basic auth
-func BasicAuth(f ViewFunc) ViewFunc {
+// Auth 认证, 支持API密钥和登录帐号
+// 使用API密钥时需拥有scope权限; 使用登录帐号时, 非GET请求需校验CSRF Token
+func Auth(scope string, f ViewFunc) ViewFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		conf, _ := config.GetConfigCache()
 
-		// 禁止公网访问
-		if conf.NotAllowWanAccess {
-			if !util.IsPrivateNetwork(r.RemoteAddr) || !util.IsPrivateNetwork(r.Host) {
+		if isWanAccessDenied(&conf, r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// Home Assistant的ingress, 已在Home Assistant中登录
+		if isIngressRequest(r) {
+			CSRF(f)(w, withAuditUser(r, ingressUser(r)))
+			return
+		}
+
+		// API密钥
+		bearerPrefix := "Bearer "
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, bearerPrefix) {
+			apiKey, ok := conf.FindAPIKey(auth[len(bearerPrefix):])
+			if !ok {
+				log.Printf("%s API密钥不正确!\n", r.RemoteAddr)
+				auditLogin(r, "", false)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if !apiKey.Allow(scope) {
 				w.WriteHeader(http.StatusForbidden)
 				return
 			}
+			f(w, withAuditUser(r, "API密钥 "+apiKey.Name))
+			return
+		}
+
+		// 路由器等只支持帐号密码时, 可使用API密钥作为密码, 帐号任意
+		if _, password, ok := r.BasicAuth(); ok && password != "" {
+			if apiKey, ok := conf.FindAPIKey(password); ok {
+				if !apiKey.Allow(scope) {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				f(w, withAuditUser(r, "API密钥 "+apiKey.Name))
+				return
+			}
+		}
+
+		BasicAuth(CSRF(f))(w, r)
+	}
+}
+
+// APIKeyAuth 只接受API密钥的认证, 通过Bearer或帐号密码中的密码传递, 用于路由器推送IP.
+// 不使用登录帐号, 未设置帐号或浏览器缓存了帐号时其它网页也无法通过GET请求修改解析记录
+func APIKeyAuth(scope string, f ViewFunc) ViewFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conf, _ := config.GetConfigCache()
+
+		if isWanAccessDenied(&conf, r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
 		}
 
+		key := ""
+		bearerPrefix := "Bearer "
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, bearerPrefix) {
+			key = auth[len(bearerPrefix):]
+		} else if _, password, ok := r.BasicAuth(); ok {
+			key = password
+		}
+		apiKey, ok := conf.FindAPIKey(key)
+		if key == "" || !ok {
+			log.Printf("%s API密钥不正确!\n", r.RemoteAddr)
+			auditLogin(r, "", false)
+			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("badauth"))
+			return
+		}
+		if !apiKey.Allow(scope) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		f(w, withAuditUser(r, "API密钥 "+apiKey.Name))
+	}
+}
+
+// BasicAuth basic auth
+func BasicAuth(f ViewFunc) ViewFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conf, _ := config.GetConfigCache()
+
 		// 帐号或密码为空。跳过
 		if conf.Username == "" && conf.Password == "" {
 			// 执行被装饰的函数
@@ -57,12 +136,19 @@ func BasicAuth(f ViewFunc) ViewFunc {
 					bytes.Equal(pair[0], []byte(conf.Username)) &&
 					bytes.Equal(pair[1], []byte(conf.Password)) {
 					ld.FailTimes = 0
+					auditLogin(r, conf.Username, true)
 					// 执行被装饰的函数
-					f(w, r)
+					f(w, withAuditUser(r, conf.Username))
 					return
 				}
 			}
 
+			user := ""
+			if err == nil {
+				user = string(bytes.SplitN(payload, []byte(":"), 2)[0])
+			}
+			auditLogin(r, user, false)
+
 			ld.FailTimes = ld.FailTimes + 1
 			if ld.FailTimes > 5 {
 				log.Printf("%s 登陆失败超过5次! 并延时60s响应\n", r.RemoteAddr)
@@ -80,3 +166,30 @@ func BasicAuth(f ViewFunc) ViewFunc {
 		log.Printf("%s 请求登陆!\n", r.RemoteAddr)
 	}
 }
+
+// isWanAccessDenied 是否为禁止的公网访问. 通过unix socket访问时检查反向代理转发的来源地址, 未转发的视为本机访问
+func isWanAccessDenied(conf *config.Config, r *http.Request) bool {
+	if !conf.NotAllowWanAccess {
+		return false
+	}
+	if isUnixSocketRequest(r) {
+		addr := forwardedAddr(r)
+		return addr != "" && !util.IsPrivateNetwork(net.JoinHostPort(addr, "0"))
+	}
+	return !util.IsPrivateNetwork(r.RemoteAddr) || !util.IsPrivateNetwork(r.Host)
+}
+
+// forwardedAddr 反向代理转发的来源地址, 使用X-Forwarded-For中最后一个(反向代理添加的)或X-Real-IP
+func forwardedAddr(r *http.Request) string {
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		addrs := strings.Split(values[len(values)-1], ",")
+		return strings.TrimSpace(addrs[len(addrs)-1])
+	}
+	return strings.TrimSpace(r.Header.Get("X-Real-IP"))
+}
+
+// isUnixSocketRequest 是否通过unix socket访问
+func isUnixSocketRequest(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && addr.Network() == "unix"
+}
@@ -0,0 +1,153 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"ddns-go/config"
+	"ddns-go/util"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAPIKeyAuth 只接受API密钥, 未设置帐号时也不允许访问
+func TestAPIKeyAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddns-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv(util.ConfigFilePathENV, filepath.Join(dir, "config.yaml"))
+	defer os.Unsetenv(util.ConfigFilePathENV)
+	defer config.ClearConfigCache()
+
+	updateKey, updateSecret, _ := config.NewAPIKey("router", config.APIKeyScopeUpdate)
+	readKey, readSecret, _ := config.NewAPIKey("monitor", config.APIKeyScopeRead)
+	conf := &config.Config{APIKeys: []config.APIKey{updateKey, readKey}}
+	if err := conf.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := APIKeyAuth(config.APIKeyScopeUpdate, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	request := func(setAuth func(r *http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/nic/update?hostname=a.example.com&myip=1.2.3.4", nil)
+		setAuth(r)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	if w := request(func(r *http.Request) {}); w.Code != http.StatusUnauthorized {
+		t.Errorf("未设置帐号且没有API密钥时应返回401, 返回 %d", w.Code)
+	}
+	if w := request(func(r *http.Request) { r.SetBasicAuth("admin", "wrong") }); w.Code != http.StatusUnauthorized {
+		t.Errorf("API密钥不正确时应返回401, 返回 %d", w.Code)
+	}
+	if w := request(func(r *http.Request) { r.SetBasicAuth("any", readSecret) }); w.Code != http.StatusForbidden {
+		t.Errorf("只读的API密钥应返回403, 返回 %d", w.Code)
+	}
+	if w := request(func(r *http.Request) { r.SetBasicAuth("any", updateSecret) }); w.Code != http.StatusOK {
+		t.Errorf("密码为API密钥时应允许, 返回 %d", w.Code)
+	}
+	if w := request(func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+updateSecret) }); w.Code != http.StatusOK {
+		t.Errorf("Bearer API密钥应允许, 返回 %d", w.Code)
+	}
+}
+
+// TestWanAccessUnixSocket 通过unix socket访问时检查反向代理转发的来源地址
+func TestWanAccessUnixSocket(t *testing.T) {
+	conf := &config.Config{NotAllowWanAccess: true}
+	request := func(header string, value string) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+		r = r.WithContext(context.WithValue(r.Context(), http.LocalAddrContextKey, &net.UnixAddr{Name: "/run/ddns-go.sock", Net: "unix"}))
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		return r
+	}
+	if isWanAccessDenied(conf, request("", "")) {
+		t.Error("未经反向代理时应视为本机访问")
+	}
+	if isWanAccessDenied(conf, request("X-Forwarded-For", "8.8.8.8, 192.168.1.2")) {
+		t.Error("反向代理转发的内网地址应允许")
+	}
+	if !isWanAccessDenied(conf, request("X-Forwarded-For", "192.168.1.2, 8.8.8.8")) {
+		t.Error("反向代理转发的公网地址应禁止")
+	}
+	if !isWanAccessDenied(conf, request("X-Real-IP", "2001:db8::1")) {
+		t.Error("X-Real-IP为公网地址时应禁止")
+	}
+}
+
+// TestSaveCSRF 保存及测试通知需POST并带有正确的CSRF Token
+func TestSaveCSRF(t *testing.T) {
+	os.Setenv(util.ConfigFilePathENV, filepath.Join(t.TempDir(), "config.yaml"))
+	defer os.Unsetenv(util.ConfigFilePathENV)
+	defer config.ClearConfigCache()
+	conf := &config.Config{User: config.User{Username: "admin", Password: "pass"}}
+	if err := conf.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(f ViewFunc, method string, target string, token string) int {
+		r := httptest.NewRequest(method, target, strings.NewReader("DnsName=alidns"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.SetBasicAuth("admin", "pass")
+		if token != "" {
+			r.Header.Set(csrfHeaderName, token)
+		}
+		w := httptest.NewRecorder()
+		Auth(config.APIKeyScopeFull, f)(w, r)
+		return w.Code
+	}
+
+	if code := request(Save, "POST", "/save", ""); code != http.StatusForbidden {
+		t.Errorf("没有CSRF Token时应返回403, 返回 %d", code)
+	}
+	if code := request(Save, "POST", "/save", "wrong"); code != http.StatusForbidden {
+		t.Errorf("CSRF Token不正确时应返回403, 返回 %d", code)
+	}
+	if code := request(Save, "GET", "/save?DnsName=alidns&Ipv4Domains=a.example.com", ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET保存应返回405, 返回 %d", code)
+	}
+	if code := request(NotifyTest, "GET", "/notifyTest?Channel=webhook&WebhookURL=http://127.0.0.1/", ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET测试通知应返回405, 返回 %d", code)
+	}
+	if saved, _ := config.GetConfigCache(); saved.DNS.Name != "" {
+		t.Errorf("配置不应被修改: %s", saved.DNS.Name)
+	}
+}
+
+// TestImportConfigValidate 导入的配置校验不通过时返回400, 不保存
+func TestImportConfigValidate(t *testing.T) {
+	os.Setenv(util.ConfigFilePathENV, filepath.Join(t.TempDir(), "config.yaml"))
+	defer os.Unsetenv(util.ConfigFilePathENV)
+	defer config.ClearConfigCache()
+	if err := (&config.Config{TTL: "600"}).SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("ConfigFile", "config.yaml")
+	part.Write([]byte("dns:\n  name: not-a-provider\nttl: \"60\"\n"))
+	form.Close()
+	r := httptest.NewRequest("POST", "/importConfig", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	ImportConfig(w, r)
+	if w.Code != http.StatusBadRequest || w.Body.Len() == 0 {
+		t.Errorf("校验不通过时应返回400及原因, 返回 %d %s", w.Code, w.Body.String())
+	}
+	if conf, _ := config.GetConfigCache(); conf.TTL != "600" {
+		t.Errorf("校验不通过时不应保存: %s", conf.TTL)
+	}
+}
@@ -0,0 +1,114 @@
+package web
+
+import (
+	"ddns-go/config"
+	"ddns-go/dns"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// 导入的配置文件最大1MB
+const maxImportConfigSize = 1 << 20
+
+// ExportConfig 导出配置, redact=true时隐藏密钥和密码
+func ExportConfig(writer http.ResponseWriter, request *http.Request) {
+	conf, err := config.GetConfigCache()
+	if err != nil {
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte("未找到配置文件"))
+		return
+	}
+
+	if request.FormValue("redact") == "true" {
+		conf = conf.Redacted()
+	}
+
+	byt, err := yaml.Marshal(conf)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		writer.Write([]byte(err.Error()))
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/x-yaml")
+	writer.Header().Set("Content-Disposition", `attachment; filename="ddns_go_config.yaml"`)
+	writer.Write(byt)
+}
+
+// ImportConfig 导入配置, 覆盖当前配置. 导入的配置中隐藏了的密钥保留当前的
+func ImportConfig(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	request.Body = http.MaxBytesReader(writer, request.Body, maxImportConfigSize)
+	file, header, err := request.FormFile("ConfigFile")
+	if err != nil {
+		writer.Write([]byte("请选择配置文件"))
+		return
+	}
+	defer file.Close()
+
+	byt, err := ioutil.ReadAll(file)
+	if err != nil {
+		writer.Write([]byte(err.Error()))
+		return
+	}
+
+	old, _ := config.GetConfigCache()
+	var conf config.Config
+	if config.IsDdclientFile(header.Filename) {
+		// 从ddclient迁移
+		var replaced, warnings []string
+		conf, replaced, warnings, err = config.ImportDdclient(byt)
+		for _, warning := range warnings {
+			log.Println("导入ddclient配置:", warning)
+		}
+		// 需替换当前的配置时, 确认后再次导入
+		if err == nil && len(replaced) > 0 && request.FormValue("Overwrite") != "true" {
+			writer.WriteHeader(http.StatusConflict)
+			writer.Write([]byte(strings.Join(replaced, "\n")))
+			return
+		}
+	} else {
+		conf, err = config.ParseConfig(header.Filename, byt)
+	}
+	if err != nil {
+		writer.Write([]byte("配置文件不正确: " + err.Error()))
+		return
+	}
+	// 更新前后的命令只能在配置文件中修改
+	conf.Hooks = old.Hooks
+	// 导入隐藏密钥后的配置时保留当前的密钥及密码
+	conf.KeepRedacted(old)
+	if err = conf.KeepExecPath(old); err != nil {
+		writer.Write([]byte(err.Error()))
+		return
+	}
+	// 与gRPC的SetConfig相同, 校验不通过时不保存
+	if errs := conf.Validate(); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		writer.WriteHeader(http.StatusBadRequest)
+		writer.Write([]byte(strings.Join(msgs, "; ")))
+		return
+	}
+
+	if err = conf.SaveConfig(); err != nil {
+		writer.Write([]byte(err.Error()))
+		return
+	}
+	auditConfig(request, old, conf)
+
+	// 只运行一次
+	go dns.RunOnce()
+
+	writer.Write([]byte("ok"))
+}
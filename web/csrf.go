@@ -0,0 +1,48 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"net/http"
+)
+
+// CSRF Token的请求头及表单字段
+const (
+	csrfHeaderName = "X-CSRF-Token"
+	csrfFormName   = "csrfToken"
+)
+
+// 每次启动生成新的CSRF Token, 页面中获取
+var csrfToken = newCSRFToken()
+
+func newCSRFToken() string {
+	byt := make([]byte, 32)
+	if _, err := rand.Read(byt); err != nil {
+		log.Fatalln("生成CSRF Token失败", err)
+	}
+	return hex.EncodeToString(byt)
+}
+
+// CSRF 非GET请求需校验CSRF Token
+func CSRF(f ViewFunc) ViewFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			f(w, r)
+			return
+		}
+
+		token := r.Header.Get(csrfHeaderName)
+		if token == "" {
+			token = r.FormValue(csrfFormName)
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(csrfToken)) != 1 {
+			log.Printf("%s CSRF Token校验失败!\n", r.RemoteAddr)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("CSRF Token校验失败, 请刷新页面"))
+			return
+		}
+		f(w, r)
+	}
+}
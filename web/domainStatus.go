@@ -0,0 +1,18 @@
+package web
+
+import (
+	"ddns-go/dns"
+	"encoding/json"
+	"net/http"
+)
+
+// DomainStatus 获得域名状态
+func DomainStatus(writer http.ResponseWriter, request *http.Request) {
+	byt, err := json.Marshal(dns.GetStatus())
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Write(byt)
+}
@@ -0,0 +1,122 @@
+package web
+
+import (
+	"ddns-go/config"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 图表显示的天数
+const historyChartDays int = 30
+
+// 页面中显示的更新记录条数
+const historyUpdateNum int = 100
+
+// 接口默认返回的记录条数
+const defaultHistoryLimit int = 100
+
+// historyChartBar 每天IP变化次数
+type historyChartBar struct {
+	Date   string
+	Count  int
+	Height int // 柱状图高度百分比
+}
+
+// History IP变化记录
+func History(writer http.ResponseWriter, request *http.Request) {
+	tmpl, err := parseTemplate(historyEmbedFile, "history.html", request)
+	if err != nil {
+		fmt.Println("Error happened..")
+		fmt.Println(err)
+		return
+	}
+
+	histories := config.GetIPHistory()
+	chart := getHistoryChart(histories, time.Now())
+	tmpl.Execute(writer, struct {
+		Histories  []config.IPHistory
+		Updates    []config.UpdateRecord
+		Chart      []historyChartBar
+		ChartStart string
+		ChartEnd   string
+	}{
+		Histories:  histories,
+		Updates:    config.GetUpdateRecords(historyUpdateNum, ""),
+		Chart:      chart,
+		ChartStart: chart[0].Date,
+		ChartEnd:   chart[len(chart)-1].Date,
+	})
+}
+
+// HistoryRecords 历史记录接口, type为ip(IP变化)、detect(获取IP)、update(更新域名), 默认为update
+// limit为返回的条数, type为update时可使用domain过滤
+func HistoryRecords(writer http.ResponseWriter, request *http.Request) {
+	limit := defaultHistoryLimit
+	if l := request.FormValue("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte("limit不正确"))
+			return
+		}
+	}
+
+	var records interface{}
+	switch request.FormValue("type") {
+	case "ip":
+		histories := config.GetIPHistory()
+		if len(histories) > limit {
+			histories = histories[:limit]
+		}
+		records = histories
+	case "detect":
+		records = config.GetDetectRecords(limit)
+	case "", "update":
+		records = config.GetUpdateRecords(limit, request.FormValue("domain"))
+	default:
+		writer.WriteHeader(http.StatusBadRequest)
+		writer.Write([]byte("type不正确, 支持: ip, detect, update"))
+		return
+	}
+
+	byt, err := json.Marshal(records)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Write(byt)
+}
+
+// getHistoryChart 统计最近每天的IP变化次数
+func getHistoryChart(histories []config.IPHistory, now time.Time) []historyChartBar {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	bars := make([]historyChartBar, historyChartDays)
+	for i := range bars {
+		bars[i].Date = today.AddDate(0, 0, i-historyChartDays+1).Format("01-02")
+	}
+
+	maxCount := 0
+	for _, h := range histories {
+		t := h.Time.In(now.Location())
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+		index := historyChartDays - 1 - int(today.Sub(day).Hours()/24)
+		if index < 0 || index >= historyChartDays {
+			continue
+		}
+		bars[index].Count++
+		if bars[index].Count > maxCount {
+			maxCount = bars[index].Count
+		}
+	}
+
+	if maxCount > 0 {
+		for i := range bars {
+			bars[i].Height = bars[i].Count * 100 / maxCount
+		}
+	}
+	return bars
+}
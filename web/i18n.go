@@ -0,0 +1,49 @@
+package web
+
+import (
+	"ddns-go/util"
+	"embed"
+	"html/template"
+	"net/http"
+)
+
+// 保存语言的cookie名称
+const langCookieName = "lang"
+
+// getLanguage 获得请求的语言, 优先使用页面中选择的语言, 其次为Accept-Language
+func getLanguage(request *http.Request) string {
+	if cookie, err := request.Cookie(langCookieName); err == nil && util.IsSupportedLanguage(cookie.Value) {
+		return cookie.Value
+	}
+	return util.MatchLanguage(request.Header.Get("Accept-Language"))
+}
+
+// parseTemplate 解析模板, 并添加翻译、CSRF Token、路径前缀及插件函数
+func parseTemplate(fs embed.FS, name string, request *http.Request) (*template.Template, error) {
+	if !UIEnabled() {
+		return nil, errUIDisabled
+	}
+	lang := getLanguage(request)
+	return template.New(name).Funcs(template.FuncMap{
+		"t": func(key string, args ...interface{}) string {
+			return util.Translate(lang, key, args...)
+		},
+		"lang": func() string {
+			return lang
+		},
+		"languages": func() []util.Language {
+			return util.Languages
+		},
+		"csrfToken": func() string {
+			return csrfToken
+		},
+		"base": func() string {
+			return basePath(request)
+		},
+		"static": func(name string) string {
+			return staticURL(basePath(request), name)
+		},
+		"plugins":  pluginList,
+		"datetime": util.FormatTime,
+	}).ParseFS(fs, name)
+}
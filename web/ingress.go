@@ -0,0 +1,34 @@
+package web
+
+import (
+	"ddns-go/util"
+	"net"
+	"net/http"
+	"regexp"
+)
+
+// Home Assistant ingress的路径前缀, 如 /api/hassio_ingress/xxx
+var ingressPathRegexp = regexp.MustCompile(`^/api/hassio_ingress/[a-zA-Z0-9_-]+$`)
+
+// isIngressRequest 作为Home Assistant加载项运行时, 是否为Supervisor的ingress代理的请求
+// 只有在Home Assistant中登录后才可通过ingress访问
+func isIngressRequest(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	return err == nil && host == util.HomeAssistantIngressIP && util.IsHomeAssistant()
+}
+
+// ingressUser 通过ingress访问时Home Assistant中登录的用户
+func ingressUser(r *http.Request) string {
+	if user := r.Header.Get("X-Remote-User-Name"); user != "" {
+		return "Home Assistant " + user
+	}
+	return "Home Assistant"
+}
+
+// basePath 页面中链接的路径前缀, 通过ingress访问时为 X-Ingress-Path, 否则为空
+func basePath(r *http.Request) string {
+	if path := r.Header.Get("X-Ingress-Path"); ingressPathRegexp.MatchString(path) && isIngressRequest(r) {
+		return path
+	}
+	return ""
+}
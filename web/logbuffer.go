@@ -0,0 +1,100 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+)
+
+// 网页中的日志保存到文件的间隔
+const logPersistInterval = 30 * time.Second
+
+// SetLogBufferSize 设置网页中保存的日志条数
+func SetLogBufferSize(size int) error {
+	if size < 1 {
+		return fmt.Errorf("网页中保存的日志条数 %d 不正确, 需大于0", size)
+	}
+	mlogs.Lock.Lock()
+	defer mlogs.Lock.Unlock()
+
+	mlogs.MaxNum = size
+	if len(mlogs.Logs) > size {
+		mlogs.Logs = mlogs.Logs[len(mlogs.Logs)-size:]
+	}
+	return nil
+}
+
+// PersistLogs 网页中的日志保存到path, 启动时加载之前保存的日志, 之后定时保存
+func PersistLogs(path string) {
+	mlogs.Lock.Lock()
+	mlogs.persistPath = path
+	err := mlogs.load()
+	mlogs.Lock.Unlock()
+	if err != nil {
+		log.Printf("读取保存的日志 %s 失败: %s\n", path, err)
+	}
+
+	go func() {
+		for range time.Tick(logPersistInterval) {
+			SaveLogs()
+		}
+	}()
+}
+
+// SaveLogs 有新日志时保存网页中的日志, 退出前调用
+func SaveLogs() {
+	mlogs.Lock.Lock()
+	defer mlogs.Lock.Unlock()
+
+	if mlogs.persistPath == "" || !mlogs.dirty {
+		return
+	}
+	// 保存失败时不能再输出日志, 输出到标准错误
+	if err := mlogs.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "保存日志到 %s 失败: %s\n", mlogs.persistPath, err)
+		return
+	}
+	mlogs.dirty = false
+}
+
+// load 加载保存的日志, 放在当前日志之前
+func (mlogs *MemoryLogs) load() error {
+	byt, err := ioutil.ReadFile(mlogs.persistPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var logs []LogEntry
+	if err = json.Unmarshal(byt, &logs); err != nil {
+		return err
+	}
+
+	for i := len(logs) - 1; i >= 0 && mlogs.lastError.Time.IsZero(); i-- {
+		if logs[i].Level == LogLevelError {
+			mlogs.lastError = logs[i]
+		}
+	}
+	mlogs.Logs = append(logs, mlogs.Logs...)
+	if len(mlogs.Logs) > mlogs.MaxNum {
+		mlogs.Logs = mlogs.Logs[len(mlogs.Logs)-mlogs.MaxNum:]
+	}
+	return nil
+}
+
+// save 先写入临时文件再重命名, 防止保存中退出时文件不完整
+func (mlogs *MemoryLogs) save() error {
+	byt, err := json.Marshal(mlogs.Logs)
+	if err != nil {
+		return err
+	}
+	tmpPath := mlogs.persistPath + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, byt, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, mlogs.persistPath)
+}
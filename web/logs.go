@@ -1,44 +1,419 @@
 package web
 
 import (
+	"ddns-go/config"
+	"ddns-go/dns"
+	"ddns-go/util"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// 日志格式
+const (
+	// LogFormatText 文本
+	LogFormatText = "text"
+	// LogFormatJSON 每行一个JSON, 便于Loki/ELK等采集
+	LogFormatJSON = "json"
+)
+
+// 标准输出及日志文件的格式
+var logFormat = LogFormatText
+
+// 日志的输出, 除网页中的日志外
+var logOutputs = []io.Writer{os.Stdout}
+
+// 按级别处理日志, 如syslog、Windows事件日志
+var logHandlers []func(level string, msg string)
+
+// SetLogFormat 设置标准输出及日志文件的格式
+func SetLogFormat(format string) error {
+	switch format {
+	case LogFormatText, LogFormatJSON:
+		logFormat = format
+		return nil
+	}
+	return fmt.Errorf("不支持的日志格式 %s, 支持: text, json", format)
+}
+
+// jsonLog JSON格式的日志, 域名的更新结果包含domain等字段
+type jsonLog struct {
+	Time       time.Time `json:"time"`
+	Level      string    `json:"level"`
+	Provider   string    `json:"provider,omitempty"`
+	Domain     string    `json:"domain,omitempty"`
+	RecordType string    `json:"recordType,omitempty"`
+	OldIP      string    `json:"oldIP,omitempty"`
+	NewIP      string    `json:"newIP,omitempty"`
+	Result     string    `json:"result,omitempty"`
+	Message    string    `json:"msg"`
+}
+
+// JSON日志中的更新结果
+var jsonLogResults = map[string]string{
+	config.UpdatedSuccess:         "success",
+	config.UpdatedFailed:          "failed",
+	string(config.UpdatedNothing): "unchanged",
+}
+
+// 日志级别
+const (
+	// LogLevelDebug 调试, 包含请求及返回的内容
+	LogLevelDebug = "debug"
+	// LogLevelInfo 信息
+	LogLevelInfo = "info"
+	// LogLevelWarn 警告
+	LogLevelWarn = "warn"
+	// LogLevelError 错误
+	LogLevelError = "error"
+)
+
+// 日志级别的顺序
+var logLevelOrder = map[string]int{LogLevelDebug: 0, LogLevelInfo: 1, LogLevelWarn: 2, LogLevelError: 3}
+
+// 低于该级别的日志不输出
+var minLogLevel = LogLevelInfo
+
+// SetLogLevel 设置输出的最低日志级别, 为debug时开启调试模式
+func SetLogLevel(level string) error {
+	if _, ok := logLevelOrder[level]; !ok {
+		return fmt.Errorf("不支持的日志级别 %s, 支持: debug, info, warn, error", level)
+	}
+	minLogLevel = level
+	util.SetDebug(level == LogLevelDebug)
+	return nil
+}
+
+// 每页默认显示的日志条数
+const defaultLogPageSize = 50
+
+// 每页最多的日志条数
+const maxLogPageSize = 1000
+
+// 包含这些关键字的日志视为错误日志
+var errorLogKeywords = []string{"失败", "异常", "error", "err:", "err："}
+
+// 包含这些关键字的日志视为警告日志
+var warnLogKeywords = []string{"超时", "暂停", "跳过", "尚未生效", "warn"}
+
+// LogEntry 一条日志
+type LogEntry struct {
+	Time     time.Time
+	Level    string
+	Provider string // 产生日志时正在更新的DNS服务商
+	Message  string
+}
+
 // MemoryLogs 内存中的日志
 type MemoryLogs struct {
-	MaxNum int      // 保存最大条数
-	Logs   []string // 日志
+	MaxNum int        // 保存最大条数
+	Logs   []LogEntry // 日志
+	Lock   sync.Mutex
+	// 订阅新日志
+	subscribers map[chan string]bool
+	// 最后一条错误日志, 清空日志后保留
+	lastError LogEntry
+	// 保存日志的文件, 为空时不保存
+	persistPath string
+	// 有未保存的日志
+	dirty bool
 }
 
 func (mlogs *MemoryLogs) Write(p []byte) (n int, err error) {
-	mlogs.Logs = append(mlogs.Logs, string(p))
+	mlogs.Lock.Lock()
+	defer mlogs.Lock.Unlock()
+
+	now := time.Now()
+	msg := formatLogPrefix(string(p), now)
+	entry := LogEntry{
+		Time:     now,
+		Level:    getLogLevel(msg),
+		Provider: dns.RunningProvider(),
+		Message:  msg,
+	}
+	if logLevelOrder[entry.Level] < logLevelOrder[minLogLevel] {
+		return len(p), nil
+	}
+	mlogs.Logs = append(mlogs.Logs, entry)
+	mlogs.dirty = true
+	if entry.Level == LogLevelError {
+		mlogs.lastError = entry
+	}
+	writeStdout(entry)
+	for _, handler := range logHandlers {
+		handler(entry.Level, trimLogPrefix(msg))
+	}
 	// 处理日志数量
 	if len(mlogs.Logs) > mlogs.MaxNum {
 		mlogs.Logs = mlogs.Logs[len(mlogs.Logs)-mlogs.MaxNum:]
 	}
+	for ch := range mlogs.subscribers {
+		// 订阅者处理不过来时丢弃, 不阻塞日志输出
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
 	return len(p), nil
 }
 
-var mlogs = &MemoryLogs{MaxNum: 50}
+// getLastError 最后一条错误日志
+func (mlogs *MemoryLogs) getLastError() LogEntry {
+	mlogs.Lock.Lock()
+	defer mlogs.Lock.Unlock()
+	return mlogs.lastError
+}
+
+// subscribe 订阅新日志
+func (mlogs *MemoryLogs) subscribe() chan string {
+	mlogs.Lock.Lock()
+	defer mlogs.Lock.Unlock()
+
+	if mlogs.subscribers == nil {
+		mlogs.subscribers = make(map[chan string]bool)
+	}
+	ch := make(chan string, 100)
+	mlogs.subscribers[ch] = true
+	return ch
+}
+
+// unsubscribe 取消订阅
+func (mlogs *MemoryLogs) unsubscribe(ch chan string) {
+	mlogs.Lock.Lock()
+	defer mlogs.Lock.Unlock()
+
+	delete(mlogs.subscribers, ch)
+}
+
+// filter 按关键字/级别/DNS服务商过滤日志, 按时间先后排序
+func (mlogs *MemoryLogs) filter(keyword string, level string, provider string) (logs []LogEntry) {
+	mlogs.Lock.Lock()
+	defer mlogs.Lock.Unlock()
+
+	keyword = strings.ToLower(keyword)
+	for _, entry := range mlogs.Logs {
+		if level != "" && entry.Level != level {
+			continue
+		}
+		if provider != "" && entry.Provider != provider {
+			continue
+		}
+		if keyword != "" && !strings.Contains(strings.ToLower(entry.Message), keyword) {
+			continue
+		}
+		logs = append(logs, entry)
+	}
+	return
+}
+
+var mlogs = &MemoryLogs{MaxNum: 1000}
 
 // 初始化日志
 func init() {
-	log.SetOutput(io.MultiWriter(mlogs, os.Stdout))
+	log.SetOutput(mlogs)
 	// log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+	dns.OnResult(logDomainResult)
 }
 
-// Logs web
+// AddLogOutput 增加日志的输出, 如日志文件
+func AddLogOutput(w io.Writer) {
+	mlogs.Lock.Lock()
+	defer mlogs.Lock.Unlock()
+	logOutputs = append(logOutputs, w)
+}
+
+// AddLogHandler 增加按级别处理的日志输出, msg不含时间. handler中不能再输出日志
+func AddLogHandler(handler func(level string, msg string)) {
+	mlogs.Lock.Lock()
+	defer mlogs.Lock.Unlock()
+	logHandlers = append(logHandlers, handler)
+}
+
+// writeOutput 按日志格式写入各输出, 某个输出失败时不影响其它输出
+func writeOutput(p []byte) {
+	for _, w := range logOutputs {
+		w.Write(p)
+	}
+}
+
+// writeStdout 按日志格式输出到标准输出及日志文件
+func writeStdout(entry LogEntry) {
+	if logFormat != LogFormatJSON {
+		writeOutput([]byte(entry.Message))
+		return
+	}
+	writeJSONLog(jsonLog{
+		Time:     entry.Time,
+		Level:    entry.Level,
+		Provider: entry.Provider,
+		Message:  trimLogPrefix(entry.Message),
+	})
+}
+
+// logDomainResult JSON格式时输出域名的更新结果
+func logDomainResult(result dns.DomainResult) {
+	if logFormat != LogFormatJSON {
+		return
+	}
+	level := LogLevelInfo
+	if result.Result == config.UpdatedFailed {
+		level = LogLevelError
+	}
+
+	mlogs.Lock.Lock()
+	defer mlogs.Lock.Unlock()
+	writeJSONLog(jsonLog{
+		Time:       time.Now(),
+		Level:      level,
+		Provider:   result.Provider,
+		Domain:     result.Domain,
+		RecordType: result.RecordType,
+		OldIP:      result.OldIP,
+		NewIP:      result.NewIP,
+		Result:     jsonLogResults[result.Result],
+		Message:    fmt.Sprintf("%s %s %s", result.RecordType, result.Domain, result.Result),
+	})
+}
+
+func writeJSONLog(line jsonLog) {
+	byt, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+	writeOutput(append(byt, '\n'))
+}
+
+// log输出的时间前缀
+const logPrefix = "2006/01/02 15:04:05 "
+
+// formatLogPrefix 设置了时间格式时替换log输出的时间前缀
+func formatLogPrefix(msg string, now time.Time) string {
+	layout := util.TimeFormat()
+	if layout == "" || len(msg) < len(logPrefix) {
+		return msg
+	}
+	if _, err := time.ParseInLocation(logPrefix, msg[:len(logPrefix)], time.Local); err != nil {
+		return msg
+	}
+	return now.Format(layout) + " " + msg[len(logPrefix):]
+}
+
+// trimLogPrefix 去掉日志中的时间前缀及换行
+func trimLogPrefix(msg string) string {
+	layouts := []string{logPrefix}
+	if layout := util.TimeFormat(); layout != "" {
+		layouts = append(layouts, layout+" ")
+	}
+	for _, prefix := range layouts {
+		// 格式中有月份名称等时长度可能不同, 按当前时间的长度判断
+		n := len(time.Now().Format(prefix))
+		if len(msg) >= n {
+			if _, err := time.ParseInLocation(prefix, msg[:n], time.Local); err == nil {
+				msg = msg[n:]
+				break
+			}
+		}
+	}
+	return strings.TrimRight(msg, "\n")
+}
+
+// getLogLevel 根据日志内容判断级别
+func getLogLevel(msg string) string {
+	if strings.HasPrefix(trimLogPrefix(msg), util.DebugPrefix) {
+		return LogLevelDebug
+	}
+	lower := strings.ToLower(msg)
+	for _, keyword := range errorLogKeywords {
+		if strings.Contains(lower, keyword) {
+			return LogLevelError
+		}
+	}
+	for _, keyword := range warnLogKeywords {
+		if strings.Contains(lower, keyword) {
+			return LogLevelWarn
+		}
+	}
+	return LogLevelInfo
+}
+
+// filterLogs 使用请求中的q/level/provider参数过滤日志
+func filterLogs(request *http.Request) []LogEntry {
+	return mlogs.filter(
+		strings.TrimSpace(request.FormValue("q")),
+		request.FormValue("level"),
+		request.FormValue("provider"),
+	)
+}
+
+// Logs web. 分页返回过滤后的日志, 最新的在前
 func Logs(writer http.ResponseWriter, request *http.Request) {
-	for _, log := range mlogs.Logs {
-		writer.Write([]byte(log))
-		writer.Write([]byte("<br/>"))
+	logs := filterLogs(request)
+
+	page, err := strconv.Atoi(request.FormValue("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	size, err := strconv.Atoi(request.FormValue("size"))
+	if err != nil || size < 1 {
+		size = defaultLogPageSize
+	}
+	if size > maxLogPageSize {
+		size = maxLogPageSize
+	}
+	// 超过最后一页时返回最后一页
+	if pages := (len(logs) + size - 1) / size; page > pages {
+		page = pages
+		if page < 1 {
+			page = 1
+		}
+	}
+
+	result := struct {
+		Total int
+		Page  int
+		Size  int
+		Logs  []LogEntry
+	}{Total: len(logs), Page: page, Size: size, Logs: []LogEntry{}}
+
+	for i := len(logs) - 1 - (page-1)*size; i >= 0 && len(result.Logs) < size; i-- {
+		result.Logs = append(result.Logs, logs[i])
+	}
+
+	byt, err := json.Marshal(result)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Write(byt)
+}
+
+// DownloadLogs 下载过滤后的日志
+func DownloadLogs(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.Header().Set("Content-Disposition", `attachment; filename="ddns-go.log"`)
+	for _, entry := range filterLogs(request) {
+		writer.Write([]byte(entry.Message))
 	}
 }
 
 // ClearLog
 func ClearLog(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	mlogs.Lock.Lock()
+	defer mlogs.Lock.Unlock()
+
 	mlogs.Logs = mlogs.Logs[:0]
+	mlogs.dirty = true
 }
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"ddns-go/config"
+	"ddns-go/dns"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// NicUpdate DynDNS2协议的更新接口, 路由器推送IP后更新获取IP方式为DynDNS2的域名
+// 如 /nic/update?hostname=ddns.example.com&myip=1.2.3.4, myip可用逗号分隔IPv4和IPv6, 为空时使用请求的来源IP
+func NicUpdate(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	conf, err := config.GetConfigCache()
+	if err != nil {
+		writer.Write([]byte("911"))
+		return
+	}
+
+	hostnames := strings.Split(request.FormValue("hostname"), ",")
+	for _, hostname := range hostnames {
+		if !conf.IsPushDomain(hostname) {
+			log.Printf("DynDNS2: %s 推送的域名 %s 未配置或获取IP方式不是DynDNS2\n", clientIP(request), hostname)
+			if strings.TrimSpace(hostname) == "" {
+				writer.Write([]byte("notfqdn"))
+			} else {
+				writer.Write([]byte("nohost"))
+			}
+			return
+		}
+	}
+
+	var ipv4, ipv6 string
+	for _, ip := range strings.Split(request.FormValue("myip")+","+request.FormValue("myipv6"), ",") {
+		if ip = strings.TrimSpace(ip); ip == "" {
+			continue
+		}
+		parsed := net.ParseIP(ip)
+		switch {
+		case parsed == nil:
+			writer.Write([]byte("911"))
+			return
+		case parsed.To4() != nil:
+			ipv4 = parsed.String()
+		default:
+			ipv6 = parsed.String()
+		}
+	}
+	if ipv4 == "" && ipv6 == "" {
+		if parsed := net.ParseIP(clientIP(request)); parsed != nil && parsed.To4() != nil {
+			ipv4 = parsed.String()
+		} else if parsed != nil {
+			ipv6 = parsed.String()
+		}
+	}
+
+	changed := false
+	ips := []string{}
+	if ipv4 != "" && conf.Ipv4.GetType == config.GetTypeDynDNS2 {
+		changed = config.SetPushedIP("IPv4", ipv4) || changed
+		ips = append(ips, ipv4)
+	}
+	if ipv6 != "" && conf.Ipv6.GetType == config.GetTypeDynDNS2 {
+		changed = config.SetPushedIP("IPv6", ipv6) || changed
+		ips = append(ips, ipv6)
+	}
+	if len(ips) == 0 {
+		writer.Write([]byte("911"))
+		return
+	}
+
+	code := "nochg"
+	if changed {
+		log.Printf("DynDNS2: %s 推送了新的IP %s\n", clientIP(request), strings.Join(ips, ","))
+		code = "good"
+		go dns.RunOnce()
+	}
+	// 每个域名返回一行
+	lines := make([]string, len(hostnames))
+	for i := range hostnames {
+		lines[i] = code + " " + strings.Join(ips, ",")
+	}
+	writer.Write([]byte(strings.Join(lines, "\n")))
+}
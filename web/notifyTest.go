@@ -0,0 +1,33 @@
+package web
+
+import (
+	"ddns-go/config"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// NotifyTest 使用模拟数据测试通知渠道, 返回原始的响应
+// 使用页面中填写的(可能未保存的)配置, 填写了DnsWebhookURL时测试DNS服务商的Webhook
+func NotifyTest(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	conf, _ := config.GetConfigCache()
+	conf.WebhookURL = strings.TrimSpace(request.FormValue("WebhookURL"))
+	conf.WebhookRequestBody = strings.TrimSpace(request.FormValue("WebhookRequestBody"))
+	conf.DNS.WebhookURL = strings.TrimSpace(request.FormValue("DnsWebhookURL"))
+	conf.DNS.WebhookRequestBody = strings.TrimSpace(request.FormValue("DnsWebhookRequestBody"))
+
+	result, err := config.TestNotify(request.FormValue("Channel"), &conf)
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		writer.Write([]byte(err.Error()))
+		return
+	}
+
+	byt, _ := json.Marshal(result)
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Write(byt)
+}
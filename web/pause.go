@@ -0,0 +1,40 @@
+package web
+
+import (
+	"ddns-go/config"
+	"ddns-go/dns"
+	"net/http"
+	"strings"
+)
+
+// Pause 暂停或恢复更新
+// 参数paused为true时暂停, 可选参数provider只暂停或恢复指定的DNS服务商
+func Pause(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	conf, err := config.GetConfigCache()
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		writer.Write([]byte(err.Error()))
+		return
+	}
+
+	old := conf
+	paused := request.FormValue("paused") == "true"
+	conf.SetPaused(strings.TrimSpace(request.FormValue("provider")), paused)
+	if err := conf.SaveConfig(); err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		writer.Write([]byte(err.Error()))
+		return
+	}
+	auditConfig(request, old, conf)
+
+	// 恢复后立即更新
+	if !conf.IsPaused(conf.DNS.Name) {
+		go dns.RunOnce()
+	}
+	writer.Write([]byte("ok"))
+}
@@ -0,0 +1,9 @@
+//go:build !noweb
+// +build !noweb
+
+package web
+
+import "ddns-go/plugin"
+
+// pluginList 已加载的插件, 在网页中选择
+var pluginList = plugin.List
@@ -0,0 +1,9 @@
+//go:build noweb
+// +build noweb
+
+package web
+
+// pluginList 使用 noweb 构建时不支持插件
+func pluginList() []struct{} {
+	return nil
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"ddns-go/config"
+	"ddns-go/dns"
+	"ddns-go/util"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Profiles 配置方案管理页面
+func Profiles(writer http.ResponseWriter, request *http.Request) {
+	tmpl, err := parseTemplate(profilesEmbedFile, "profiles.html", request)
+	if err != nil {
+		fmt.Println("Error happened..")
+		fmt.Println(err)
+		return
+	}
+
+	tmpl.Execute(writer, struct {
+		Profile  string
+		Profiles []string
+	}{
+		Profile:  util.GetProfile(),
+		Profiles: util.GetProfiles(),
+	})
+}
+
+// SwitchProfile 切换配置方案并立即更新
+func SwitchProfile(writer http.ResponseWriter, request *http.Request) {
+	profileAction(writer, request, util.SetProfile)
+}
+
+// CreateProfile 复制当前配置, 创建配置方案
+func CreateProfile(writer http.ResponseWriter, request *http.Request) {
+	profileAction(writer, request, util.CreateProfile)
+}
+
+// DeleteProfile 删除配置方案
+func DeleteProfile(writer http.ResponseWriter, request *http.Request) {
+	profileAction(writer, request, util.DeleteProfile)
+}
+
+// profileAction 执行配置方案的操作, 当前配置方案变化时重新加载配置
+func profileAction(writer http.ResponseWriter, request *http.Request, action func(name string) error) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	before := util.GetProfile()
+	beforeProfiles := strings.Join(util.GetProfiles(), ",")
+	if err := action(strings.TrimSpace(request.FormValue("Name"))); err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		writer.Write([]byte(err.Error()))
+		return
+	}
+
+	var changes []config.AuditChange
+	if profiles := strings.Join(util.GetProfiles(), ","); profiles != beforeProfiles {
+		changes = append(changes, config.AuditChange{Field: "profiles", Old: beforeProfiles, New: profiles})
+	}
+	if profile := util.GetProfile(); profile != before {
+		changes = append(changes, config.AuditChange{Field: "profile", Old: before, New: profile})
+	}
+	auditChanges(request, changes)
+
+	if util.GetProfile() != before {
+		config.ClearConfigCache()
+		go dns.RunOnce()
+	}
+	writer.Write([]byte("ok"))
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"ddns-go/config"
+	"ddns-go/dns"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// publicDomainStatus 公开的域名状态, 不包含IP
+type publicDomainStatus struct {
+	Domain     string
+	RecordType string
+	LastUpdate time.Time
+	OK         bool
+	// 是否检查过
+	Checked bool
+}
+
+// PublicStatus 公开状态页, 需在配置中启用, 无需登录
+func PublicStatus(writer http.ResponseWriter, request *http.Request) {
+	conf, err := config.GetConfigCache()
+	if err != nil || !conf.PublicStatusPage {
+		http.NotFound(writer, request)
+		return
+	}
+	if isWanAccessDenied(&conf, request) {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	tmpl, err := parseTemplate(publicStatusEmbedFile, "publicStatus.html", request)
+	if err != nil {
+		fmt.Println("Error happened..")
+		fmt.Println(err)
+		return
+	}
+
+	status := dns.GetStatus()
+	var domains []publicDomainStatus
+	for _, ds := range status.Domains {
+		domains = append(domains, publicDomainStatus{
+			Domain:     ds.Domain,
+			RecordType: ds.RecordType,
+			LastUpdate: ds.LastUpdate,
+			OK:         ds.LastResult != config.UpdatedFailed,
+			Checked:    !ds.LastCheck.IsZero(),
+		})
+	}
+	tmpl.Execute(writer, struct {
+		LastRun time.Time
+		Domains []publicDomainStatus
+	}{LastRun: status.LastRun, Domains: domains})
+}
@@ -3,14 +3,28 @@ package web
 import (
 	"ddns-go/config"
 	"ddns-go/dns"
+	"ddns-go/util"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 )
 
 // Save 保存
 func Save(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	conf, err := config.GetConfigCache()
+	if err != nil && !os.IsNotExist(err) {
+		// 配置文件不正确时不保存, 防止丢失原有的配置
+		writer.Write([]byte("配置文件解析失败, 请先修复: " + err.Error()))
+		return
+	}
 
-	conf, _ := config.GetConfigCache()
+	old := conf
 
 	idNew := request.FormValue("DnsID")
 	secretNew := request.FormValue("DnsSecret")
@@ -26,39 +40,192 @@ func Save(writer http.ResponseWriter, request *http.Request) {
 
 	// 覆盖以前的配置
 	conf.DNS.Name = request.FormValue("DnsName")
+	conf.DNS.Proxy = strings.TrimSpace(request.FormValue("DnsProxy"))
+	conf.DNS.IPVersion = request.FormValue("DnsIPVersion")
+	if err := util.CheckIPVersion(conf.DNS.IPVersion); err != nil {
+		writer.Write([]byte(err.Error()))
+		return
+	}
+	conf.DNS.Bind = strings.TrimSpace(request.FormValue("DnsBind"))
+	conf.DNS.WebhookURL = strings.TrimSpace(request.FormValue("DnsWebhookURL"))
+	conf.DNS.WebhookRequestBody = strings.TrimSpace(request.FormValue("DnsWebhookRequestBody"))
+	if err := util.CheckBind(conf.DNS.Bind); err != nil {
+		writer.Write([]byte(err.Error()))
+		return
+	}
+	conf.DNS.RateLimit = strings.TrimSpace(request.FormValue("DnsRateLimit"))
+	if _, _, err := util.ParseRateLimit(conf.DNS.RateLimit); err != nil {
+		writer.Write([]byte(err.Error()))
+		return
+	}
+	if conf.DNS.Name == "exec" {
+		if err := conf.KeepExecPath(old); err != nil {
+			writer.Write([]byte(err.Error()))
+			return
+		}
+		if err := config.CheckExecPath(conf.DNS.ID); err != nil {
+			writer.Write([]byte(err.Error()))
+			return
+		}
+	}
 
 	conf.Ipv4.Enable = request.FormValue("Ipv4Enable") == "on"
 	conf.Ipv4.URL = strings.TrimSpace(request.FormValue("Ipv4Url"))
 	conf.Ipv4.GetType = request.FormValue("Ipv4GetType")
 	conf.Ipv4.NetInterface = request.FormValue("Ipv4NetInterface")
+	if conf.Ipv4.GetType == config.GetTypeWireGuard {
+		conf.Ipv4.NetInterface = strings.TrimSpace(request.FormValue("Ipv4WireGuard"))
+	}
 	conf.Ipv4.Domains = strings.Split(request.FormValue("Ipv4Domains"), "\r\n")
+	conf.Ipv4.Expression = strings.TrimSpace(request.FormValue("Ipv4Expression"))
+	conf.Ipv4.CGNAT = request.FormValue("Ipv4CGNAT")
+	conf.Ipv4.CGNATCheckPublic = request.FormValue("Ipv4CGNATCheckPublic") == "on"
+	conf.Ipv4.PTR = strings.TrimSpace(request.FormValue("Ipv4PTR"))
 
 	conf.Ipv6.Enable = request.FormValue("Ipv6Enable") == "on"
 	conf.Ipv6.GetType = request.FormValue("Ipv6GetType")
 	conf.Ipv6.NetInterface = request.FormValue("Ipv6NetInterface")
+	if conf.Ipv6.GetType == config.GetTypeWireGuard {
+		conf.Ipv6.NetInterface = strings.TrimSpace(request.FormValue("Ipv6WireGuard"))
+	}
 	conf.Ipv6.URL = strings.TrimSpace(request.FormValue("Ipv6Url"))
 	conf.Ipv6.Domains = strings.Split(request.FormValue("Ipv6Domains"), "\r\n")
+	conf.Ipv6.Expression = strings.TrimSpace(request.FormValue("Ipv6Expression"))
+	conf.Ipv6.PTR = strings.TrimSpace(request.FormValue("Ipv6PTR"))
+	for _, source := range []string{conf.Ipv4.Expression, conf.Ipv6.Expression} {
+		if source == "" {
+			continue
+		}
+		if _, err := config.CompileIPExpr(source); err != nil {
+			writer.Write([]byte(err.Error()))
+			return
+		}
+	}
 
 	conf.Username = strings.TrimSpace(request.FormValue("Username"))
 	conf.Password = request.FormValue("Password")
 
 	conf.WebhookURL = strings.TrimSpace(request.FormValue("WebhookURL"))
 	conf.WebhookRequestBody = strings.TrimSpace(request.FormValue("WebhookRequestBody"))
+	conf.WebhookEvents = request.Form["WebhookEvents"]
 
 	conf.NotAllowWanAccess = request.FormValue("NotAllowWanAccess") == "on"
+	conf.PublicStatusPage = request.FormValue("PublicStatusPage") == "on"
 	conf.TTL = request.FormValue("TTL")
+	conf.Cron = strings.TrimSpace(request.FormValue("Cron"))
+	conf.DryRun = request.FormValue("DryRun") == "on"
+	conf.UpdateWindows = strings.TrimSpace(request.FormValue("UpdateWindows"))
+	conf.NoUpdateWindows = strings.TrimSpace(request.FormValue("NoUpdateWindows"))
+	for _, windows := range []string{conf.UpdateWindows, conf.NoUpdateWindows} {
+		if _, err := util.ParseTimeWindows(windows); err != nil {
+			writer.Write([]byte(err.Error()))
+			return
+		}
+	}
+	conf.FlapDetection = request.FormValue("FlapDetection") == "on"
+	conf.MinUpdateInterval = 0
+	if interval := strings.TrimSpace(request.FormValue("MinUpdateInterval")); interval != "" {
+		conf.MinUpdateInterval, err = strconv.Atoi(interval)
+		if err != nil || conf.MinUpdateInterval < 0 {
+			writer.Write([]byte("最小更新间隔不正确"))
+			return
+		}
+	}
+	conf.Concurrency = 0
+	if concurrency := strings.TrimSpace(request.FormValue("Concurrency")); concurrency != "" {
+		conf.Concurrency, err = strconv.Atoi(concurrency)
+		if err != nil || conf.Concurrency < 0 || conf.Concurrency > config.MaxConcurrency {
+			writer.Write([]byte("同时更新的域名数不正确"))
+			return
+		}
+	}
+	conf.HistoryDays = 0
+	if days := strings.TrimSpace(request.FormValue("HistoryDays")); days != "" {
+		conf.HistoryDays, err = strconv.Atoi(days)
+		if err != nil || conf.HistoryDays < 0 {
+			writer.Write([]byte("历史记录保留天数不正确"))
+			return
+		}
+	}
+	conf.Proxy = strings.TrimSpace(request.FormValue("Proxy"))
+	for _, proxy := range []string{conf.Proxy, conf.DNS.Proxy} {
+		if err := util.CheckProxy(proxy); err != nil {
+			writer.Write([]byte(err.Error()))
+			return
+		}
+	}
+	conf.Resolver = strings.TrimSpace(request.FormValue("Resolver"))
+	conf.BootstrapResolver = strings.TrimSpace(request.FormValue("BootstrapResolver"))
+	for _, resolver := range []string{conf.Resolver, conf.BootstrapResolver} {
+		if resolver == "" {
+			continue
+		}
+		if _, err := util.ParseResolver(resolver); err != nil {
+			writer.Write([]byte(err.Error()))
+			return
+		}
+	}
+	conf.Hosts = splitLines(request.FormValue("Hosts"))
+	if _, err := util.ParseHosts(conf.Hosts); err != nil {
+		writer.Write([]byte(err.Error()))
+		return
+	}
+	conf.AllowIPRanges = splitLines(request.FormValue("AllowIPRanges"))
+	conf.DenyIPRanges = splitLines(request.FormValue("DenyIPRanges"))
+	for _, ranges := range [][]string{conf.AllowIPRanges, conf.DenyIPRanges} {
+		if _, err := util.ParseIPRanges(ranges); err != nil {
+			writer.Write([]byte(err.Error()))
+			return
+		}
+	}
+	conf.GeoIPURL = strings.TrimSpace(request.FormValue("GeoIPURL"))
+	if conf.GeoIPURL != "" {
+		if err := config.CheckGeoIPURL(conf.GeoIPURL); err != nil {
+			writer.Write([]byte(err.Error()))
+			return
+		}
+	}
+	conf.MQTTURL = strings.TrimSpace(request.FormValue("MQTTURL"))
+	conf.MQTTTopic = strings.TrimSpace(request.FormValue("MQTTTopic"))
+	if conf.MQTTURL != "" {
+		if _, err := util.ParseMQTTURL(conf.MQTTURL); err != nil {
+			writer.Write([]byte(err.Error()))
+			return
+		}
+		if conf.MQTTTopic == "" {
+			writer.Write([]byte("MQTT主题不能为空"))
+			return
+		}
+	}
+	if conf.Cron != "" {
+		if _, err := util.ParseCron(conf.Cron); err != nil {
+			writer.Write([]byte(err.Error()))
+			return
+		}
+	}
 
 	// 保存到用户目录
-	err := conf.SaveConfig()
+	err = conf.SaveConfig()
 
 	// 只运行一次
 	go dns.RunOnce()
 
 	// 回写错误信息
 	if err == nil {
+		auditConfig(request, old, conf)
 		writer.Write([]byte("ok"))
 	} else {
 		writer.Write([]byte(err.Error()))
 	}
 
 }
+
+// splitLines 多行文本框的内容, 一行一个, 忽略空行
+func splitLines(s string) (lines []string) {
+	for _, line := range strings.Split(s, "\r\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return
+}
@@ -0,0 +1,155 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// staticFile 启动时读取并压缩的静态文件
+type staticFile struct {
+	content []byte
+	gzip    []byte
+	// 构建时生成的同名 .br 文件, 没有时为空
+	brotli      []byte
+	contentType string
+	// 内容的hash, 用于ETag及链接中的版本
+	hash string
+}
+
+// 已加载的静态文件, key为文件路径, 如 static/common.js
+var staticFiles = struct {
+	sync.RWMutex
+	m map[string]*staticFile
+}{m: make(map[string]*staticFile)}
+
+// StaticHandler 静态文件服务, 按Accept-Encoding返回brotli/gzip压缩的内容, 并设置ETag及Cache-Control.
+// 链接中带有正确的版本(?v=hash)时可长期缓存, 否则每次验证ETag
+func StaticHandler(fsys fs.FS) (http.Handler, error) {
+	files := make(map[string]*staticFile)
+	compressed := make(map[string][]byte)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(name, ".br") || strings.HasSuffix(name, ".gz") {
+			compressed[name] = content
+			return nil
+		}
+		sum := sha256.Sum256(content)
+		file := &staticFile{content: content, hash: hex.EncodeToString(sum[:8])}
+		file.contentType = mime.TypeByExtension(path.Ext(name))
+		if file.contentType == "" {
+			file.contentType = http.DetectContentType(content)
+		}
+		files[name] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for name, file := range files {
+		if br, ok := compressed[name+".br"]; ok {
+			file.brotli = br
+		}
+		if gz, ok := compressed[name+".gz"]; ok {
+			file.gzip = gz
+		} else {
+			file.gzip = gzipContent(file.content)
+		}
+	}
+
+	staticFiles.Lock()
+	for name, file := range files {
+		staticFiles.m[name] = file
+	}
+	staticFiles.Unlock()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if file == nil {
+			http.NotFound(w, r)
+			return
+		}
+		serveStaticFile(w, r, file)
+	}), nil
+}
+
+// gzipContent 压缩后没有变小时返回nil, 如图片
+func gzipContent(content []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(content)
+	zw.Close()
+	if buf.Len() >= len(content) {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func serveStaticFile(w http.ResponseWriter, r *http.Request, file *staticFile) {
+	body, etag := file.content, file.hash
+	acceptEncoding := r.Header.Get("Accept-Encoding")
+	switch {
+	case file.brotli != nil && acceptsEncoding(acceptEncoding, "br"):
+		body, etag = file.brotli, file.hash+"-br"
+		w.Header().Set("Content-Encoding", "br")
+	case file.gzip != nil && acceptsEncoding(acceptEncoding, "gzip"):
+		body, etag = file.gzip, file.hash+"-gz"
+		w.Header().Set("Content-Encoding", "gzip")
+	}
+
+	w.Header().Set("Content-Type", file.contentType)
+	w.Header().Set("Vary", "Accept-Encoding")
+	w.Header().Set("ETag", `"`+etag+`"`)
+	if r.URL.Query().Get("v") == file.hash {
+		// 内容变化后链接也会变化
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
+
+// acceptsEncoding Accept-Encoding中是否包含coding, q=0时为不接受
+func acceptsEncoding(header string, coding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(fields[0]), coding) {
+			continue
+		}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); strings.HasPrefix(param, "q=") && err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// staticURL 静态文件的链接, 带有内容的版本. name如 bootstrap.min.css
+func staticURL(base string, name string) string {
+	staticFiles.RLock()
+	defer staticFiles.RUnlock()
+	u := base + "/static/" + name
+	if file := staticFiles.m["static/"+name]; file != nil {
+		u += "?v=" + file.hash
+	}
+	return u
+}
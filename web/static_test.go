@@ -0,0 +1,69 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestStaticHandler 按Accept-Encoding压缩, 带正确版本的链接长期缓存, ETag相同时返回304
+func TestStaticHandler(t *testing.T) {
+	js := strings.Repeat("console.log('ddns-go');\n", 100)
+	handler, err := StaticHandler(fstest.MapFS{
+		"static/common.js":     {Data: []byte(js)},
+		"static/common.css":    {Data: []byte(strings.Repeat("body{}", 100))},
+		"static/common.css.br": {Data: []byte("brotli")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(url string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	url := staticURL("/ingress", "common.js")
+	if !strings.HasPrefix(url, "/ingress/static/common.js?v=") {
+		t.Fatalf("链接 %s 应带有版本", url)
+	}
+	w := get(strings.TrimPrefix(url, "/ingress"), map[string]string{"Accept-Encoding": "gzip, deflate"})
+	if w.Header().Get("Content-Encoding") != "gzip" || !strings.Contains(w.Header().Get("Cache-Control"), "immutable") {
+		t.Fatalf("应返回gzip并长期缓存: %v", w.Header())
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadAll(zr); string(body) != js {
+		t.Error("解压后的内容不正确")
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/javascript") && !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") {
+		t.Errorf("Content-Type %s 不正确", w.Header().Get("Content-Type"))
+	}
+
+	w = get("/static/common.js", map[string]string{"Accept-Encoding": "gzip;q=0"})
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != js || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("不接受gzip时应返回原内容, 没有版本时每次验证: %v", w.Header())
+	}
+	if w = get("/static/common.js", map[string]string{"If-None-Match": w.Header().Get("ETag")}); w.Code != http.StatusNotModified {
+		t.Errorf("ETag相同时应返回304: %d", w.Code)
+	}
+
+	w = get("/static/common.css", map[string]string{"Accept-Encoding": "gzip, br"})
+	if w.Header().Get("Content-Encoding") != "br" || w.Body.String() != "brotli" {
+		t.Errorf("有.br文件时应返回brotli: %v", w.Header())
+	}
+	if w = get("/static/common.css.br", nil); w.Code != http.StatusNotFound {
+		t.Error(".br文件不应单独访问")
+	}
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"ddns-go/config"
+	"ddns-go/dns"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Version 版本, 由main设置
+var Version = "dev"
+
+// 启动时间
+var startTime = time.Now()
+
+// StatusResponse 运行状态
+type StatusResponse struct {
+	Version   string
+	StartTime time.Time
+	// 运行的秒数
+	Uptime int64
+	// 所有域名最后一次检查都未失败
+	OK          bool
+	Provider    string
+	Ipv4Addr    string
+	Ipv6Addr    string
+	Ipv4Info    config.IPInfo
+	Ipv6Info    config.IPInfo
+	LastRun     time.Time
+	NextRun     time.Time
+	PausedUntil time.Time
+	// DNS服务商连续失败的次数, 暂停定时更新时有值
+	PausedFailures int
+	LastError      string
+	LastErrorTime  time.Time
+	Domains        []dns.DomainStatus
+}
+
+// GetStatus 运行状态, 所有域名最后一次检查都未失败时OK为true
+func GetStatus() StatusResponse {
+	status := dns.GetStatus()
+	lastError := mlogs.getLastError()
+	result := StatusResponse{
+		Version:        Version,
+		StartTime:      startTime,
+		Uptime:         int64(time.Since(startTime).Seconds()),
+		OK:             true,
+		Provider:       status.Provider,
+		Ipv4Addr:       status.Ipv4Addr,
+		Ipv6Addr:       status.Ipv6Addr,
+		Ipv4Info:       status.Ipv4Info,
+		Ipv6Info:       status.Ipv6Info,
+		LastRun:        status.LastRun,
+		NextRun:        status.NextRun,
+		PausedUntil:    status.PausedUntil,
+		PausedFailures: status.PausedFailures,
+		LastError:      trimLogPrefix(lastError.Message),
+		LastErrorTime:  lastError.Time,
+		Domains:        status.Domains,
+	}
+	for _, ds := range status.Domains {
+		if ds.LastResult == config.UpdatedFailed {
+			result.OK = false
+		}
+	}
+	return result
+}
+
+// Status 运行状态, 用于外部监控. 有域名更新失败时返回503
+func Status(writer http.ResponseWriter, request *http.Request) {
+	result := GetStatus()
+	byt, err := json.Marshal(result)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	if !result.OK {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writer.Write(byt)
+}
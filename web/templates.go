@@ -0,0 +1,27 @@
+//go:build !noweb
+// +build !noweb
+
+package web
+
+import "embed"
+
+// 使用 noweb 构建时不包含网页
+const uiBuilt = true
+
+//go:embed writing.html
+var writingEmbedFile embed.FS
+
+//go:embed publicStatus.html
+var publicStatusEmbedFile embed.FS
+
+//go:embed profiles.html
+var profilesEmbedFile embed.FS
+
+//go:embed history.html
+var historyEmbedFile embed.FS
+
+//go:embed audit.html
+var auditEmbedFile embed.FS
+
+//go:embed apiKeys.html
+var apiKeysEmbedFile embed.FS
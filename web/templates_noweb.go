@@ -0,0 +1,18 @@
+//go:build noweb
+// +build noweb
+
+package web
+
+import "embed"
+
+// 使用 noweb 构建, 不包含网页, 只能通过配置文件及API使用
+const uiBuilt = false
+
+var (
+	writingEmbedFile      embed.FS
+	publicStatusEmbedFile embed.FS
+	profilesEmbedFile     embed.FS
+	historyEmbedFile      embed.FS
+	auditEmbedFile        embed.FS
+	apiKeysEmbedFile      embed.FS
+)
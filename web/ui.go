@@ -0,0 +1,31 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+)
+
+// 运行时禁用了网页
+var uiDisabled bool
+
+var errUIDisabled = errors.New("网页已禁用, 请使用配置文件及API")
+
+// DisableUI 不提供网页, 只能通过配置文件及API使用. 需在启动服务前调用
+func DisableUI() {
+	uiDisabled = true
+}
+
+// UIEnabled 是否提供网页, 使用 noweb 构建或运行时禁用时为false
+func UIEnabled() bool {
+	return uiBuilt && !uiDisabled
+}
+
+// Page 网页的处理, 网页已禁用时返回404及提示
+func Page(f ViewFunc) ViewFunc {
+	if UIEnabled() {
+		return f
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, errUIDisabled.Error(), http.StatusNotFound)
+	}
+}
@@ -0,0 +1,58 @@
+package web
+
+import (
+	"ddns-go/dns"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// UpdateNow 立即更新, 并实时返回更新过程中的日志
+// 可选参数provider/domain只更新指定的DNS服务商/域名
+func UpdateNow(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	provider := strings.TrimSpace(request.FormValue("provider"))
+	domain := strings.TrimSpace(request.FormValue("domain"))
+
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+	flusher, _ := writer.(http.Flusher)
+
+	logCh := mlogs.subscribe()
+	defer mlogs.unsubscribe(logCh)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- dns.RunManual(provider, domain)
+	}()
+
+	for {
+		select {
+		case msg := <-logCh:
+			writer.Write([]byte(msg))
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case err := <-done:
+			// 输出剩余的日志
+			for len(logCh) > 0 {
+				writer.Write([]byte(<-logCh))
+			}
+			if err != nil {
+				fmt.Fprintf(writer, "更新失败: %s\n", err)
+				return
+			}
+			for _, ds := range dns.GetStatus().Domains {
+				if domain == "" || ds.Domain == domain {
+					fmt.Fprintf(writer, "%s %s: %s\n", ds.RecordType, ds.Domain, ds.LastResult)
+				}
+			}
+			fmt.Fprintln(writer, "更新完成")
+			return
+		}
+	}
+}
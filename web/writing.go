@@ -2,20 +2,15 @@ package web
 
 import (
 	"ddns-go/config"
-	"embed"
 	"strings"
 
 	"fmt"
-	"html/template"
 	"net/http"
 )
 
-//go:embed writing.html
-var writingEmbedFile embed.FS
-
 // Writing 填写信息
 func Writing(writer http.ResponseWriter, request *http.Request) {
-	tmpl, err := template.ParseFS(writingEmbedFile, "writing.html")
+	tmpl, err := parseTemplate(writingEmbedFile, "writing.html", request)
 	if err != nil {
 		fmt.Println("Error happened..")
 		fmt.Println(err)
@@ -56,12 +51,13 @@ const displayCount int = 3
 
 // hideIDSecret 隐藏真实的ID、Secret
 func getHideIDSecret(conf *config.Config) (idHide string, secretHide string) {
-	if len(conf.DNS.ID) > displayCount && conf.DNS.Name != "callback" {
+	// 密钥文件的引用不需要隐藏
+	if len(conf.DNS.ID) > displayCount && conf.DNS.Name != "callback" && conf.DNS.Name != "exec" && !config.IsSecretFile(conf.DNS.ID) {
 		idHide = conf.DNS.ID[:displayCount] + strings.Repeat("*", len(conf.DNS.ID)-displayCount)
 	} else {
 		idHide = conf.DNS.ID
 	}
-	if len(conf.DNS.Secret) > displayCount && conf.DNS.Name != "callback"  {
+	if len(conf.DNS.Secret) > displayCount && conf.DNS.Name != "callback" && !config.IsSecretFile(conf.DNS.Secret) {
 		secretHide = conf.DNS.Secret[:displayCount] + strings.Repeat("*", len(conf.DNS.Secret)-displayCount)
 	} else {
 		secretHide = conf.DNS.Secret